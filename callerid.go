@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+)
+
+const (
+	// Longest caller name most PBXs and carriers will display
+	cnamMaxLength = 15
+
+	// How long a lookup may keep running in the background after a caller has given up waiting
+	// on it, so the result is cached for the next call
+	callerIDBackgroundTimeout = 30 * time.Second
+
+	// How long a FastAGI session may take on top of the lookup timeout before it's dropped
+	agiSessionTimeout = 10 * time.Second
+)
+
+// callerID resolves the caller names of incoming calls. Names are never waited on for longer than
+// timeout, so calls aren't delayed by slow lookups.
+type callerID struct {
+	api       *whatphone.API
+	timeout   time.Duration
+	maxLength int
+
+	// When set, key must be passed with every query, and only clients in allow are answered
+	key   string
+	allow []netip.Prefix
+}
+
+// name returns the caller name for a phone number, preferring CNAM over the name data point. An
+// empty string is returned if no name is known or the lookup doesn't finish in time.
+func (c *callerID) name(number string) string {
+	if !callable(number) {
+		return ""
+	}
+	// caller IDs come in whatever form the PBX or carrier sends them, so they're normalized to
+	// share cached lookups
+	number, err := whatphone.Normalize(number)
+	if err != nil {
+		return ""
+	}
+
+	done := make(chan string, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), callerIDBackgroundTimeout)
+		defer cancel()
+
+		result, err := c.api.LookupContext(ctx, number, whatphone.WithCNAM(), whatphone.WithName())
+		if err != nil {
+			failureLogger(c.api).Error("caller ID lookup failed", "number", c.api.Redaction.Number(number), "error", err)
+			done <- ""
+			return
+		}
+		done <- c.pick(result)
+	}()
+
+	select {
+	case name := <-done:
+		return name
+	case <-time.After(c.timeout):
+		return ""
+	}
+}
+
+// pick chooses the caller name to display from a lookup result
+func (c *callerID) pick(result *whatphone.Result) string {
+	name := result.Data.GetCNAM()
+	if strings.TrimSpace(name) == "" {
+		name = result.Data.GetName()
+	}
+
+	name = strings.Join(strings.Fields(name), " ")
+	if r := []rune(name); c.maxLength > 0 && len(r) > c.maxLength {
+		name = strings.TrimSpace(string(r[:c.maxLength]))
+	}
+	return name
+}
+
+// callable reports whether a caller ID number looks like a phone number worth looking up, rather
+// than an extension, "anonymous", or an empty caller ID
+func callable(number string) bool {
+	var digits int
+	for _, r := range number {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case strings.ContainsRune("+-(). ", r):
+		default:
+			return false
+		}
+	}
+	return digits >= 10
+}
+
+// allowed reports whether a client at addr, in host:port form, may query caller names
+func (c *callerID) allowed(addr string) bool {
+	if len(c.allow) == 0 {
+		return true
+	}
+
+	ap, err := netip.ParseAddrPort(addr)
+	if err != nil {
+		return false
+	}
+	ip := ap.Addr().Unmap()
+	for _, p := range c.allow {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorized reports whether key is the key queries must pass, if there is one
+func (c *callerID) authorized(key string) bool {
+	return c.key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(c.key)) == 1
+}
+
+// httpHandler returns a handler answering caller ID queries in the plain text form expected by PBX
+// CNAM modules: GET /cnam/<number> or GET /cnam?number=<number>. The key, if any, is passed as
+// the "key" query parameter.
+func (c *callerID) httpHandler() http.Handler {
+	mux := http.NewServeMux()
+	handle := func(w http.ResponseWriter, r *http.Request) {
+		if !c.allowed(r.RemoteAddr) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if !c.authorized(r.URL.Query().Get("key")) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		number := r.PathValue("number")
+		if number == "" {
+			number = r.URL.Query().Get("number")
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, c.name(number))
+	}
+	mux.HandleFunc("GET /cnam/{number}", handle)
+	mux.HandleFunc("GET /cnam", handle)
+	return mux
+}
+
+// serveAGI accepts FastAGI connections from Asterisk until the listener is closed
+func (c *callerID) serveAGI(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		if !c.allowed(conn.RemoteAddr().String()) {
+			conn.Close()
+			continue
+		}
+		go func() {
+			if err := c.handleAGI(conn); err != nil {
+				failureLogger(c.api).Error("FastAGI session failed", "remote", conn.RemoteAddr().String(), "error", err)
+			}
+		}()
+	}
+}
+
+// handleAGI runs a single FastAGI session, setting CALLERID(name) to the name of the caller. The
+// key, if any, is passed as the "key" query parameter of the AGI URL.
+func (c *callerID) handleAGI(conn net.Conn) error {
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(c.timeout + agiSessionTimeout)); err != nil {
+		return err
+	}
+	r := bufio.NewReader(conn)
+
+	// the session starts with "agi_<name>: <value>" variables, terminated by an empty line
+	env := make(map[string]string)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if k, v, ok := strings.Cut(line, ": "); ok {
+			env[k] = v
+		}
+	}
+
+	req, err := url.Parse(env["agi_request"])
+	if err != nil || !c.authorized(req.Query().Get("key")) {
+		return fmt.Errorf("unauthorized FastAGI request from %s", conn.RemoteAddr())
+	}
+
+	name := c.name(env["agi_callerid"])
+	if name == "" {
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(conn, "SET VARIABLE CALLERID(name) %s\n", agiQuote(name)); err != nil {
+		return err
+	}
+	reply, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(reply, "200") {
+		return fmt.Errorf("unexpected reply to SET VARIABLE: %s", strings.TrimSpace(reply))
+	}
+	return nil
+}
+
+// agiQuote quotes a value for use as an AGI command argument
+func agiQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+	return `"` + s + `"`
+}
+
+// parseAllowlist parses client addresses given as IP addresses or CIDR prefixes
+func parseAllowlist(addrs []string) ([]netip.Prefix, error) {
+	var allow []netip.Prefix
+	for _, a := range addrs {
+		if !strings.Contains(a, "/") {
+			ip, err := netip.ParseAddr(a)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowed address %q", a)
+			}
+			allow = append(allow, netip.PrefixFrom(ip.Unmap(), ip.Unmap().BitLen()))
+			continue
+		}
+
+		p, err := netip.ParsePrefix(a)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed address %q", a)
+		}
+		allow = append(allow, p.Masked())
+	}
+	return allow, nil
+}
+
+func cmdCallerID(c *cli.Context) error {
+	config, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+
+	httpAddr, agiAddr := c.String("http"), c.String("agi")
+	if httpAddr == "" && agiAddr == "" {
+		return fmt.Errorf("nothing to serve; use --http and/or --agi")
+	}
+
+	allow, err := parseAllowlist(c.StringSlice("allow"))
+	if err != nil {
+		return err
+	}
+	if c.String("key") == "" && len(allow) == 0 {
+		return fmt.Errorf("refusing to answer anyone who asks; use --key and/or --allow")
+	}
+
+	config.Cache = whatphone.NewMemoryCache(c.Duration("cache-ttl"), c.Int("cache-size"))
+	cid := &callerID{
+		api:       &config.API,
+		timeout:   c.Duration("timeout"),
+		maxLength: c.Int("max-length"),
+		key:       c.String("key"),
+		allow:     allow,
+	}
+
+	metricsHandler := instrument(&config.API)
+	if addr := c.String("metrics"); addr != "" {
+		msrv, err := serveMetrics(addr, metricsHandler)
+		if err != nil {
+			return err
+		}
+		defer msrv.Close()
+		fmt.Fprintf(c.App.Writer, "Serving metrics on %s\n", addr)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 2)
+	var srv *http.Server
+	if httpAddr != "" {
+		srv = &http.Server{
+			Addr:              httpAddr,
+			Handler:           cid.httpHandler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			errc <- srv.ListenAndServe()
+		}()
+		fmt.Fprintf(c.App.Writer, "Serving caller ID over HTTP on %s\n", httpAddr)
+	}
+
+	var l net.Listener
+	if agiAddr != "" {
+		if l, err = net.Listen("tcp", agiAddr); err != nil {
+			return err
+		}
+		go func() {
+			errc <- cid.serveAGI(l)
+		}()
+		fmt.Fprintf(c.App.Writer, "Serving FastAGI on %s\n", agiAddr)
+	}
+
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintf(c.App.Writer, "Shutting down\n")
+	if l != nil {
+		l.Close()
+	}
+	if srv != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func TestCallerIDHTTP(t *testing.T) {
+	name := "Jane Doe"
+	srv := apitest.NewServer(apitest.WithNumber("+15557654321", whatphone.Data{Name: &name}))
+	defer srv.Close()
+
+	cid := &callerID{api: srv.API(), timeout: time.Second, maxLength: cnamMaxLength, key: "secret"}
+	h := httptest.NewServer(cid.httpHandler())
+	defer h.Close()
+
+	queries := []struct {
+		path     string
+		status   int
+		expected string
+	}{
+		{"/cnam/+15551234567?key=secret", http.StatusOK, "MICHAEL SEAVER"},
+		{"/cnam?number=5557654321&key=secret", http.StatusOK, "Jane Doe"},
+		{"/cnam/5559999999?key=secret", http.StatusOK, ""},
+		{"/cnam/anonymous?key=secret", http.StatusOK, ""},
+		{"/cnam/+15551234567", http.StatusUnauthorized, "unauthorized\n"},
+	}
+
+	for _, q := range queries {
+		resp, err := http.Get(h.URL + q.path)
+		if err != nil {
+			t.Fatalf("%s returned error: %v", q.path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != q.status {
+			t.Errorf("%s returned unexpected status. Got: %d, Want: %d", q.path, resp.StatusCode, q.status)
+		}
+		if string(body) != q.expected {
+			t.Errorf("%s returned unexpected name. Got: %q, Want: %q", q.path, body, q.expected)
+		}
+	}
+}
+
+func TestCallerIDAllowlist(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	allow, err := parseAllowlist([]string{"10.0.0.0/8", "192.168.1.10"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	cid := &callerID{api: srv.API(), timeout: time.Second, allow: allow}
+	h := cid.httpHandler()
+
+	addrs := map[string]int{
+		"10.1.2.3:5060":     http.StatusOK,
+		"192.168.1.10:5060": http.StatusOK,
+		"192.168.1.11:5060": http.StatusForbidden,
+		"[::1]:5060":        http.StatusForbidden,
+	}
+	for addr, status := range addrs {
+		req := httptest.NewRequest(http.MethodGet, "/cnam/+15551234567", nil)
+		req.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Errorf("Unexpected status for a query from %s. Got: %d, Want: %d", addr, w.Code, status)
+		}
+	}
+
+	if _, err := parseAllowlist([]string{"10.0.0.0/33"}); err == nil {
+		t.Errorf("invalid prefix should have returned an error but didn't")
+	}
+}
+
+func TestCallerIDNormalized(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	api := srv.API()
+	api.Cache = whatphone.NewMemoryCache(time.Minute, 0)
+	cid := &callerID{api: api, timeout: time.Second}
+
+	for _, number := range []string{"5551234567", "+1 (555) 123-4567", "+15551234567"} {
+		if name := cid.name(number); name != "MICHAEL SEAVER" {
+			t.Errorf("Unexpected name for %s. Got: %q, Want: %q", number, name, "MICHAEL SEAVER")
+		}
+	}
+	if srv.Requests() != 1 {
+		t.Errorf("Caller IDs in different forms weren't answered from the cache. Got: %d requests, Want: 1", srv.Requests())
+	}
+}
+
+func TestCallerIDTimeout(t *testing.T) {
+	srv := apitest.NewServer(apitest.WithLatency(200 * time.Millisecond))
+	defer srv.Close()
+
+	api := srv.API()
+	api.Cache = whatphone.NewMemoryCache(time.Minute, 0)
+	cid := &callerID{api: api, timeout: 20 * time.Millisecond}
+
+	start := time.Now()
+	if name := cid.name(apitest.SampleNumber); name != "" {
+		t.Errorf("Unexpected name for slow lookup. Got: %q, Want: %q", name, "")
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Slow lookup delayed the answer by %v", elapsed)
+	}
+
+	// the lookup keeps running in the background, so the next call is answered from the cache
+	time.Sleep(300 * time.Millisecond)
+	if name := cid.name(apitest.SampleNumber); name != "MICHAEL SEAVER" {
+		t.Errorf("Unexpected name for cached lookup. Got: %q, Want: %q", name, "MICHAEL SEAVER")
+	}
+}
+
+func TestCallerIDPick(t *testing.T) {
+	cnam, blank, name := "A VERY LONG CALLER NAME", " ", "Jane  Doe"
+	cid := &callerID{maxLength: cnamMaxLength}
+
+	picks := []struct {
+		data     whatphone.Data
+		expected string
+	}{
+		{whatphone.Data{Cnam: &cnam, Name: &name}, "A VERY LONG CAL"},
+		{whatphone.Data{Cnam: &blank, Name: &name}, "Jane Doe"},
+		{whatphone.Data{}, ""},
+	}
+
+	for _, p := range picks {
+		if got := cid.pick(&whatphone.Result{Data: p.data}); got != p.expected {
+			t.Errorf("Unexpected name for %+v. Got: %q, Want: %q", p.data, got, p.expected)
+		}
+	}
+}
+
+func TestCallerIDAGI(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	cid := &callerID{api: srv.API(), timeout: time.Second, maxLength: cnamMaxLength, key: "secret"}
+	asterisk, agi := net.Pipe()
+	defer asterisk.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- cid.handleAGI(agi)
+	}()
+
+	io.WriteString(asterisk, "agi_network: yes\nagi_request: agi://127.0.0.1/?key=secret\nagi_callerid: 5551234567\nagi_calleridname: unknown\n\n")
+	r := bufio.NewReader(asterisk)
+	cmd, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if expected := "SET VARIABLE CALLERID(name) \"MICHAEL SEAVER\"\n"; cmd != expected {
+		t.Errorf("Unexpected AGI command. Got: %q, Want: %q", cmd, expected)
+	}
+	io.WriteString(asterisk, "200 result=1\n")
+
+	if err := <-errc; err != nil {
+		t.Errorf("AGI session returned error: %v", err)
+	}
+}
+
+func TestCallerIDAGIUnauthorized(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	cid := &callerID{api: srv.API(), timeout: time.Second, key: "secret"}
+	asterisk, agi := net.Pipe()
+	defer asterisk.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- cid.handleAGI(agi)
+	}()
+
+	io.WriteString(asterisk, "agi_network: yes\nagi_request: agi://127.0.0.1/?key=wrong\nagi_callerid: 5551234567\n\n")
+	if err := <-errc; err == nil {
+		t.Errorf("AGI session with the wrong key should have returned an error but didn't")
+	}
+	if srv.Requests() != 0 {
+		t.Errorf("Unauthorized AGI session looked up a number")
+	}
+}
+
+func TestAGIQuote(t *testing.T) {
+	if got, expected := agiQuote(`O"Brien \ Co`), `"O\"Brien \\ Co"`; got != expected {
+		t.Errorf("Unexpected quoting. Got: %s, Want: %s", got, expected)
+	}
+}
+
+func TestCallable(t *testing.T) {
+	numbers := map[string]bool{
+		"+1 (555) 123-4567": true,
+		"5551234567":        true,
+		"1234":              false,
+		"anonymous":         false,
+		"":                  false,
+	}
+	for number, expected := range numbers {
+		if got := callable(number); got != expected {
+			t.Errorf("Unexpected result for %q. Got: %v, Want: %v", number, got, expected)
+		}
+	}
+}
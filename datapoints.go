@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+)
+
+// builtinPresets are the data point presets available without defining any in the config.
+// Presets of the same name in the config take precedence.
+var builtinPresets = map[string][]string{
+	"fraud": {"carrier", "carrier_o", "linetype", "line_provider"},
+	"crm":   {"name", "address", "location"},
+}
+
+// dataPointFlags maps the lookup command's per data point flags to the data point they select
+var dataPointFlags = map[string]whatphone.DataPoint{
+	"name":             whatphone.DataPointName,
+	"profile":          whatphone.DataPointProfile,
+	"cnam":             whatphone.DataPointCNAM,
+	"gender":           whatphone.DataPointGender,
+	"image":            whatphone.DataPointImage,
+	"address":          whatphone.DataPointAddress,
+	"location":         whatphone.DataPointLocation,
+	"line-provider":    whatphone.DataPointLineProvider,
+	"carrier":          whatphone.DataPointCarrier,
+	"original-carrier": whatphone.DataPointOriginalCarrier,
+	"linetype":         whatphone.DataPointLineType,
+}
+
+// presets returns every preset available to cfg, which may be nil
+func (cfg *config) presets() map[string][]string {
+	presets := make(map[string][]string, len(builtinPresets))
+	for name, data := range builtinPresets {
+		presets[name] = data
+	}
+	if cfg != nil {
+		for name, data := range cfg.Presets {
+			presets[name] = data
+		}
+	}
+	return presets
+}
+
+// preset returns the data points selected by a named preset
+func (cfg *config) preset(name string) (whatphone.DataPointSet, error) {
+	data, ok := cfg.presets()[name]
+	if !ok {
+		return whatphone.DataPointSet{}, fmt.Errorf("unknown preset: %s", name)
+	}
+
+	set, err := whatphone.ParseDataPoints(strings.Join(data, ","))
+	if err != nil {
+		return whatphone.DataPointSet{}, fmt.Errorf("preset %s: %v", name, err)
+	}
+	return set, nil
+}
+
+// selectDataPoints returns the data points selected with the --data and --preset flags, and the
+// flags for individual data points. Data points selected more than one way are only requested once.
+func selectDataPoints(c *cli.Context, cfg *config) (whatphone.DataPointSet, error) {
+	set, err := whatphone.ParseDataPoints(c.String("data"))
+	if err != nil {
+		return whatphone.DataPointSet{}, err
+	}
+
+	if name := c.String("preset"); name != "" {
+		preset, err := cfg.preset(name)
+		if err != nil {
+			return whatphone.DataPointSet{}, err
+		}
+		set.Add(preset.DataPoints()...)
+	}
+
+	for flag, dp := range dataPointFlags {
+		if c.Bool(flag) {
+			set.Add(dp)
+		}
+	}
+
+	return set, nil
+}
+
+// requestedDataPoints returns the data points selected on the command line, or the config's
+// defaults when none are. An empty set means every data point was requested with --all.
+func requestedDataPoints(c *cli.Context, cfg *config) (whatphone.DataPointSet, error) {
+	set, err := selectDataPoints(c, cfg)
+	if err != nil || set.Len() > 0 || c.Bool("all") {
+		return set, err
+	}
+
+	for _, name := range cfg.Data {
+		if dp, err := whatphone.ParseDataPoint(name); err == nil {
+			set.Add(dp)
+		}
+	}
+	if set.Len() == 0 {
+		return set, fmt.Errorf("no data points selected; use --all to request all data points")
+	}
+	return set, nil
+}
+
+func cmdDataPoints(c *cli.Context) error {
+	format := c.String("format")
+	if format != formatText && format != formatJSON {
+		return fmt.Errorf("unknown output format: %s", format)
+	}
+
+	// presets can be listed before the app is initialized, so a missing config isn't an error
+	cr := c.App.Metadata["configReader"].(configReader)
+	cfg, _ := cr.reader()
+
+	// a broken preset only breaks lookups using it, so it's left out with a warning
+	presets := cfg.presets()
+	sets := make(map[string]whatphone.DataPointSet, len(presets))
+	for _, name := range sortedKeys(presets) {
+		set, err := cfg.preset(name)
+		if err != nil {
+			fmt.Fprintf(c.App.ErrWriter, "warning: %v\n", err)
+			continue
+		}
+		sets[name] = set
+	}
+
+	if format == formatJSON {
+		return writeDataPointsJSON(c.App.Writer, sets)
+	}
+	return writeDataPointsText(c.App.Writer, sets)
+}
+
+// writeDataPointsText lists every data point and preset with its estimated price
+func writeDataPointsText(w io.Writer, presets map[string]whatphone.DataPointSet) error {
+	fmt.Fprintf(w, "Data points:\n")
+	for _, dp := range whatphone.AllDataPoints() {
+		fmt.Fprintf(w, "  %-14s $%.4f\n", dp, dp.Price())
+	}
+
+	fmt.Fprintf(w, "Presets:\n")
+	for _, name := range sortedKeys(presets) {
+		fmt.Fprintf(w, "  %-14s $%.4f  %s\n", name, presets[name].Price(), presets[name])
+	}
+
+	_, err := fmt.Fprintf(w, "Prices are estimates; data points that aren't found aren't charged for\n")
+	return err
+}
+
+// writeDataPointsJSON lists every data point and preset with its estimated price as JSON
+func writeDataPointsJSON(w io.Writer, presets map[string]whatphone.DataPointSet) error {
+	type dataPoint struct {
+		Name  string  `json:"name"`
+		Price float64 `json:"price"`
+	}
+	type preset struct {
+		Name  string   `json:"name"`
+		Data  []string `json:"data"`
+		Price float64  `json:"price"`
+	}
+	out := struct {
+		DataPoints []dataPoint `json:"data_points"`
+		Presets    []preset    `json:"presets"`
+	}{}
+
+	for _, dp := range whatphone.AllDataPoints() {
+		out.DataPoints = append(out.DataPoints, dataPoint{Name: dp.String(), Price: dp.Price()})
+	}
+	for _, name := range sortedKeys(presets) {
+		out.Presets = append(out.Presets, preset{Name: name, Data: presets[name].Strings(), Price: presets[name].Price()})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
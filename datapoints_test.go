@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func TestDataSelection(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	var query string
+	cr := newConfigReader(func() (*config, error) {
+		api := srv.API()
+		api.Hooks.BeforeRequest = func(req *http.Request) {
+			query = req.URL.RawQuery
+		}
+		return &config{
+			API:     *api,
+			Data:    []string{"cnam"},
+			Presets: map[string][]string{"crm": {"name", "cnam"}, "basic": {"linetype"}},
+		}, nil
+	})
+
+	lookups := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"whatphone", "lookup", "15551234567"}, "data=cnam"},
+		{[]string{"whatphone", "lookup", "-d", "carrier, name", "15551234567"}, "data=name,carrier"},
+		{[]string{"whatphone", "lookup", "--data", "name", "-nc", "15551234567"}, "data=name,carrier"},
+		{[]string{"whatphone", "lookup", "--preset", "fraud", "15551234567"}, "data=line_provider,carrier,carrier_o,linetype"},
+		{[]string{"whatphone", "lookup", "--preset", "crm", "15551234567"}, "data=name,cnam"},
+		{[]string{"whatphone", "lookup", "--preset", "basic", "-d", "name", "15551234567"}, "data=name,linetype"},
+		{[]string{"whatphone", "lookup", "--all", "15551234567"}, ""},
+	}
+
+	for _, lookup := range lookups {
+		query = "unset"
+		if err := run(lookup.args, strings.NewReader(""), io.Discard, io.Discard, cr); err != nil {
+			t.Errorf("%v returned error: %v", lookup.args, err)
+			continue
+		}
+		if query != lookup.expected {
+			t.Errorf("%v requested unexpected data points.\nExpected: %s\nGot: %s\n", lookup.args, lookup.expected, query)
+		}
+	}
+}
+
+func TestPresetErrors(t *testing.T) {
+	cfg := &config{Presets: map[string][]string{"broken": {"name", "spam_score"}}}
+
+	if _, err := cfg.preset("broken"); err == nil || err.Error() != "preset broken: unknown data point: spam_score" {
+		t.Errorf("Error: Unexpected error for a broken preset: %v", err)
+	}
+	if _, err := cfg.preset("missing"); err == nil || err.Error() != "unknown preset: missing" {
+		t.Errorf("Error: Unexpected error for a missing preset: %v", err)
+	}
+
+	var none *config
+	if set, err := none.preset("crm"); err != nil || set.String() != "name,address,location" {
+		t.Errorf("Error: built-in presets should be available without a config. Got: %v (%v)", set, err)
+	}
+}
+
+func TestDataPointsCommand(t *testing.T) {
+	var stdout bytes.Buffer
+	args := []string{"whatphone", "datapoints"}
+	cr := newConfigReader(func() (*config, error) {
+		return nil, errors.New("no config")
+	})
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+
+	expected := `Data points:
+  name           $0.0100
+  profile        $0.0050
+  cnam           $0.0100
+  gender         $0.0100
+  image          $0.0200
+  address        $0.0800
+  location       $0.0050
+  line_provider  $0.0120
+  carrier        $0.0030
+  carrier_o      $0.0050
+  linetype       $0.0010
+Presets:
+  crm            $0.0950  name,address,location
+  fraud          $0.0210  line_provider,carrier,carrier_o,linetype
+Prices are estimates; data points that aren't found aren't charged for
+`
+	if stdout.String() != expected {
+		t.Errorf("%v returned unexpected output.\nExpected: %s\nGot: %s\n", args, expected, stdout.String())
+	}
+
+	stdout.Reset()
+	args = []string{"whatphone", "datapoints", "-f", "json"}
+	cr = newConfigReader(func() (*config, error) {
+		return &config{Presets: map[string][]string{"cheap": {"linetype", "carrier"}}}, nil
+	})
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+
+	var out struct {
+		DataPoints []struct {
+			Name  string
+			Price float64
+		} `json:"data_points"`
+		Presets []struct {
+			Name  string
+			Data  []string
+			Price float64
+		}
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		t.Fatalf("%v returned invalid JSON: %v", args, err)
+	}
+	if len(out.DataPoints) != len(whatphone.AllDataPoints()) || out.DataPoints[0].Name != "name" || out.DataPoints[0].Price != 0.01 {
+		t.Errorf("%v returned unexpected data points: %+v", args, out.DataPoints)
+	}
+	if len(out.Presets) != 3 || out.Presets[0].Name != "cheap" || strings.Join(out.Presets[0].Data, ",") != "carrier,linetype" || out.Presets[0].Price != 0.004 {
+		t.Errorf("%v returned unexpected presets: %+v", args, out.Presets)
+	}
+
+	var stderr bytes.Buffer
+	stdout.Reset()
+	args = []string{"whatphone", "datapoints"}
+	cr = newConfigReader(func() (*config, error) {
+		return &config{Presets: map[string][]string{"broken": {"spam_score"}, "cheap": {"linetype"}}}, nil
+	})
+	if err := run(args, strings.NewReader(""), &stdout, &stderr, cr); err != nil {
+		t.Fatalf("%v with a broken preset returned error: %v", args, err)
+	}
+	if !strings.Contains(stdout.String(), "  cheap ") || !strings.Contains(stdout.String(), "  crm ") || strings.Contains(stdout.String(), "broken") {
+		t.Errorf("%v with a broken preset returned unexpected output:\n%s", args, stdout.String())
+	}
+	if expected := "warning: preset broken: unknown data point: spam_score\n"; stderr.String() != expected {
+		t.Errorf("%v with a broken preset returned unexpected warnings. Got: %q, Want: %q", args, stderr.String(), expected)
+	}
+}
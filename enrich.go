@@ -0,0 +1,442 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+)
+
+// File types the enrich command can read and write
+const (
+	enrichCSV   = "csv"
+	enrichVCard = "vcard"
+)
+
+// enrichField is a value added to each enriched row or contact
+type enrichField struct {
+	name  string
+	value func(data *whatphone.Data) string
+}
+
+// enrichFields holds the fields added for each data point
+var enrichFields = map[whatphone.DataPoint][]enrichField{
+	whatphone.DataPointName: {
+		{"name", (*whatphone.Data).GetName},
+	},
+	whatphone.DataPointProfile: {
+		{"job", func(d *whatphone.Data) string { return orZero(d.Profile).Job }},
+		{"education", func(d *whatphone.Data) string { return orZero(d.Profile).Edu }},
+		{"relationship", func(d *whatphone.Data) string { return orZero(d.Profile).Relationship }},
+	},
+	whatphone.DataPointCNAM: {
+		{"cnam", (*whatphone.Data).GetCNAM},
+	},
+	whatphone.DataPointGender: {
+		{"gender", func(d *whatphone.Data) string { return orZero(d.Gender) }},
+	},
+	whatphone.DataPointImage: {
+		{"image", func(d *whatphone.Data) string { return orZero(d.Image).Large }},
+	},
+	whatphone.DataPointAddress: {
+		{"address", (*whatphone.Data).GetAddress},
+	},
+	whatphone.DataPointLocation: {
+		{"city", (*whatphone.Data).City},
+		{"state", (*whatphone.Data).State},
+		{"zip", (*whatphone.Data).Zip},
+		{"latitude", func(d *whatphone.Data) string { return orZero(d.Location).Geo.Latitude }},
+		{"longitude", func(d *whatphone.Data) string { return orZero(d.Location).Geo.Longitude }},
+	},
+	whatphone.DataPointLineProvider: {
+		{"line_provider", (*whatphone.Data).LineProviderName},
+	},
+	whatphone.DataPointCarrier: {
+		{"carrier", (*whatphone.Data).CarrierName},
+	},
+	whatphone.DataPointOriginalCarrier: {
+		{"original_carrier", (*whatphone.Data).OriginalCarrierName},
+	},
+	whatphone.DataPointLineType: {
+		{"linetype", func(d *whatphone.Data) string { return orZero(d.Linetype) }},
+	},
+}
+
+// orZero returns the value p points to, or the zero value when p is nil
+func orZero[T any](p *T) T {
+	if p == nil {
+		var zero T
+		return zero
+	}
+	return *p
+}
+
+// enrichment is the outcome of looking up one row or contact
+type enrichment struct {
+	result *whatphone.Result
+	cost   float64
+	err    error
+
+	// reused is set when the result came from an earlier lookup rather than a new one
+	reused bool
+}
+
+// enricher looks up the numbers in a contacts file, looking up each distinct number only once
+type enricher struct {
+	api     *whatphone.API
+	job     *job
+	prog    *progress
+	opts    []whatphone.Option
+	fields  []enrichField
+	results map[string]*whatphone.Result
+
+	rows       int
+	lookups    int
+	resumed    int
+	duplicates int
+	errors     int
+	cost       float64
+}
+
+// newEnricher returns an enricher adding the fields of the given data points, or of every data
+// point when set is empty
+func newEnricher(api *whatphone.API, set whatphone.DataPointSet) *enricher {
+	e := &enricher{api: api, results: make(map[string]*whatphone.Result)}
+
+	dps := set.DataPoints()
+	if set.Len() == 0 {
+		dps = whatphone.AllDataPoints()
+	} else {
+		e.opts = []whatphone.Option{whatphone.WithDataPoints(dps...)}
+	}
+	for _, dp := range dps {
+		e.fields = append(e.fields, enrichFields[dp]...)
+	}
+	return e
+}
+
+// lookup looks up a number, only charging for the first lookup of each number however it's written
+func (e *enricher) lookup(number string) enrichment {
+	e.rows++
+	en := e.find(strings.TrimSpace(number))
+	if en.err != nil {
+		e.errors++
+	}
+	e.prog.add(en.result, en.err, en.reused)
+	return en
+}
+
+// find returns the result of an earlier lookup of a number, in any form, or looks it up
+func (e *enricher) find(number string) enrichment {
+	if number == "" {
+		return enrichment{err: fmt.Errorf("missing phone number")}
+	}
+	number, err := whatphone.Normalize(number)
+	if err != nil {
+		return enrichment{err: err}
+	}
+
+	if result, ok := e.results[number]; ok {
+		e.duplicates++
+		return enrichment{result: result, reused: true}
+	}
+
+	// numbers looked up by a previous run of the job were already paid for
+	if result, ok := e.job.result(number); ok {
+		// the job may have been checkpointed before redaction was asked for
+		result = e.api.Redaction.Apply(result)
+		e.results[number] = result
+		e.resumed++
+		return enrichment{result: result, reused: true}
+	}
+
+	result, err := e.api.Lookup(number, e.opts...)
+	if err != nil {
+		return enrichment{err: err}
+	}
+	if err := e.job.record(number, result); err != nil {
+		return enrichment{err: err}
+	}
+	e.results[number] = result
+	e.lookups++
+	e.cost += result.Pricing.Total
+	return enrichment{result: result, cost: result.Pricing.Total}
+}
+
+// values returns the fields added for an enrichment, followed by its cost and error
+func (e *enricher) values(en enrichment) []string {
+	values := make([]string, 0, len(e.fields)+2)
+	for _, f := range e.fields {
+		var v string
+		if en.result != nil {
+			v = f.value(&en.result.Data)
+		}
+		values = append(values, v)
+	}
+
+	var errMsg string
+	if en.err != nil {
+		errMsg = en.err.Error()
+	}
+	return append(values, strconv.FormatFloat(en.cost, 'f', 4, 64), errMsg)
+}
+
+// names returns the names of the fields added to each row or contact
+func (e *enricher) names() []string {
+	names := make([]string, 0, len(e.fields)+2)
+	for _, f := range e.fields {
+		names = append(names, f.name)
+	}
+	return append(names, "cost", "error")
+}
+
+// enrichCSVFile adds a column for each field to a CSV file with a header row. The phone number is
+// read from the column named column, or the column at that 1-based position.
+func (e *enricher) enrichCSVFile(r io.Reader, w io.Writer, column string) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return fmt.Errorf("empty CSV file")
+	}
+	if err != nil {
+		return err
+	}
+
+	col := -1
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), column) {
+			col = i
+			break
+		}
+	}
+	if n, err := strconv.Atoi(column); col < 0 && err == nil && n >= 1 && n <= len(header) {
+		col = n - 1
+	}
+	if col < 0 {
+		return fmt.Errorf("no %q column in CSV header", column)
+	}
+
+	cw := csv.NewWriter(w)
+	added := e.names()
+	for i := range added {
+		added[i] = "whatphone_" + added[i]
+	}
+	if err := cw.Write(append(header, added...)); err != nil {
+		return err
+	}
+
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var number string
+		if col < len(row) {
+			number = row[col]
+		}
+		// pad short rows so the added values line up with their columns
+		for len(row) < len(header) {
+			row = append(row, "")
+		}
+		if err := cw.Write(append(row, e.values(e.lookup(number))...)); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// enrichVCardFile adds an X-WHATPHONE- property for each field to every contact in a vCard file,
+// looking up each contact's first TEL. Every original line is written back untouched.
+func (e *enricher) enrichVCardFile(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+
+	var inCard, capturing bool
+	var number string
+	var prev []byte
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) == 0 && err == io.EOF {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		content := bytes.TrimRight(line, "\r\n")
+		folded := len(content) > 0 && (content[0] == ' ' || content[0] == '\t')
+		if folded && prev != nil {
+			prev = append(prev, content[1:]...)
+		} else {
+			prev = append([]byte(nil), content...)
+		}
+
+		name, value, _ := strings.Cut(string(prev), ":")
+		name, _, _ = strings.Cut(strings.ToUpper(name), ";")
+		if !folded {
+			capturing = inCard && name == "TEL" && number == ""
+		}
+		switch {
+		case capturing:
+			number = strings.TrimPrefix(value, "tel:")
+		case !folded && name == "BEGIN" && strings.EqualFold(value, "VCARD"):
+			inCard, number = true, ""
+		case !folded && inCard && name == "END" && strings.EqualFold(value, "VCARD"):
+			eol := line[len(content):]
+			if len(eol) == 0 {
+				eol = []byte("\r\n")
+			}
+			values := e.values(e.lookup(number))
+			for i, name := range e.names() {
+				if values[i] == "" {
+					continue
+				}
+				prop := "X-WHATPHONE-" + strings.ToUpper(strings.ReplaceAll(name, "_", "-")) + ":" + escapeVCard(values[i])
+				bw.WriteString(foldVCard(prop))
+				bw.Write(eol)
+			}
+			inCard = false
+		}
+
+		bw.Write(line)
+		if err == io.EOF {
+			break
+		}
+	}
+
+	return bw.Flush()
+}
+
+// enrichType returns the type of contacts file the enrich command reads, based on the --type flag
+// or the file's extension
+func enrichType(c *cli.Context, file string) (string, error) {
+	if t := c.String("type"); t != "" {
+		if t != enrichCSV && t != enrichVCard {
+			return "", fmt.Errorf("unknown file type: %s", t)
+		}
+		return t, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".vcf", ".vcard":
+		return enrichVCard, nil
+	default:
+		return enrichCSV, nil
+	}
+}
+
+// enrichOutput returns the file the enriched copy of file is written to, which is never file itself
+func enrichOutput(c *cli.Context, file string) (string, error) {
+	out := c.String("output")
+	if out == "" {
+		ext := filepath.Ext(file)
+		out = strings.TrimSuffix(file, ext) + ".enriched" + ext
+	}
+
+	in, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(out)
+	if err != nil {
+		return "", err
+	}
+	if in == abs {
+		return "", fmt.Errorf("refusing to overwrite %s; choose another --output", file)
+	}
+	return out, nil
+}
+
+func cmdEnrich(c *cli.Context) error {
+	config, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() < 1 {
+		return fmt.Errorf("missing contacts file")
+	}
+	file := c.Args().Get(0)
+
+	typ, err := enrichType(c, file)
+	if err != nil {
+		return err
+	}
+	out, err := enrichOutput(c, file)
+	if err != nil {
+		return err
+	}
+	set, err := requestedDataPoints(c, config)
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	f, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	j, err := startJob(c, "enrich", 0, config.Redaction)
+	if err != nil {
+		return err
+	}
+	defer j.close()
+
+	prog, err := newProgress(c, 0)
+	if err != nil {
+		return err
+	}
+
+	e := newEnricher(&config.API, set)
+	e.job = j
+	e.prog = prog
+	if typ == enrichVCard {
+		err = e.enrichVCardFile(in, f)
+	} else {
+		err = e.enrichCSVFile(in, f, c.String("column"))
+	}
+	prog.finish()
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := j.finish(e.rows, e.errors); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.App.Writer, "Enriched %d rows with %d lookups (%d errors), costing %.4f\n", e.rows, e.lookups, e.errors, e.cost)
+	if e.duplicates > 0 {
+		fmt.Fprintf(c.App.Writer, "Answered %d duplicate rows without a paid lookup\n", e.duplicates)
+	}
+	if e.resumed > 0 {
+		fmt.Fprintf(c.App.Writer, "Reused %d lookups from the job's checkpoint\n", e.resumed)
+	}
+	fmt.Fprintf(c.App.Writer, "Wrote %s\n", out)
+	return nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func enrichServer() *apitest.Server {
+	name := "Jane Doe"
+	return apitest.NewServer(apitest.WithNumber("+15557654321", whatphone.Data{Name: &name}))
+}
+
+func TestEnrichCSV(t *testing.T) {
+	srv := enrichServer()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "contacts.csv")
+	contacts := `id,Phone,note
+1,+15551234567,"has, comma"
+2,555-765-4321,
+3,,no number
+4,123,bad number
+5,5557654321,repeat
+`
+	if err := os.WriteFile(in, []byte(contacts), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	args := []string{"whatphone", "enrich", "-d", "name,carrier", in}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, newConfigReader(testServerConfig(srv))); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "contacts.enriched.csv"))
+	if err != nil {
+		t.Fatalf("%v didn't write the enriched file: %v", args, err)
+	}
+	expected := `id,Phone,note,whatphone_name,whatphone_carrier,whatphone_cost,whatphone_error
+1,+15551234567,"has, comma",Michael Seaver,Growing Wireless Inc.,-0.0130,
+2,555-765-4321,,Jane Doe,,0.0100,
+3,,no number,,,0.0000,missing phone number
+4,123,bad number,,,0.0000,invalid phone number
+5,5557654321,repeat,Jane Doe,,0.0000,
+`
+	if string(got) != expected {
+		t.Errorf("%v wrote unexpected output.\nExpected: %s\nGot: %s\n", args, expected, got)
+	}
+
+	summary := "Enriched 5 rows with 2 lookups (2 errors), costing -0.0030\nAnswered 1 duplicate rows without a paid lookup\n"
+	if !strings.HasPrefix(stdout.String(), summary) {
+		t.Errorf("%v returned unexpected summary.\nExpected: %s\nGot: %s\n", args, summary, stdout.String())
+	}
+	if srv.Requests() != 2 {
+		t.Errorf("%v made %d requests; each distinct number should only be looked up once", args, srv.Requests())
+	}
+
+	original, _ := os.ReadFile(in)
+	if string(original) != contacts {
+		t.Errorf("%v modified the original file", args)
+	}
+}
+
+func TestEnrichCSVColumn(t *testing.T) {
+	srv := enrichServer()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "contacts.txt")
+	if err := os.WriteFile(in, []byte("name,number\nJ,5557654321\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out.csv")
+
+	for _, column := range []string{"Number", "2"} {
+		args := []string{"whatphone", "enrich", "--type", "csv", "--column", column, "-d", "linetype", "-o", out, in}
+		if err := run(args, strings.NewReader(""), io.Discard, io.Discard, newConfigReader(testServerConfig(srv))); err != nil {
+			t.Fatalf("%v returned error: %v", args, err)
+		}
+		got, _ := os.ReadFile(out)
+		expected := "name,number,whatphone_linetype,whatphone_cost,whatphone_error\nJ,5557654321,,0.0000,\n"
+		if string(got) != expected {
+			t.Errorf("%v wrote unexpected output.\nExpected: %s\nGot: %s\n", args, expected, got)
+		}
+	}
+}
+
+func TestEnrichCSVShortRows(t *testing.T) {
+	srv := enrichServer()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "contacts.csv")
+	if err := os.WriteFile(in, []byte("name,phone,notes\nJ,5557654321\nK\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out.csv")
+
+	args := []string{"whatphone", "enrich", "--type", "csv", "-d", "linetype", "-o", out, in}
+	if err := run(args, strings.NewReader(""), io.Discard, io.Discard, newConfigReader(testServerConfig(srv))); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	got, _ := os.ReadFile(out)
+	expected := "name,phone,notes,whatphone_linetype,whatphone_cost,whatphone_error\nJ,5557654321,,,0.0000,\nK,,,,0.0000,missing phone number\n"
+	if string(got) != expected {
+		t.Errorf("%v wrote unexpected output.\nExpected: %s\nGot: %s\n", args, expected, got)
+	}
+}
+
+func TestEnrichVCard(t *testing.T) {
+	srv := enrichServer()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "contacts.vcf")
+	contacts := "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Sample\r\nTEL;TYPE=cell:tel:+1555123\r\n 4567\r\nTEL:+15557654321\r\nEND:VCARD\r\n" +
+		"BEGIN:VCARD\r\nVERSION:3.0\r\nFN:No Phone\r\nEND:VCARD\r\n"
+	if err := os.WriteFile(in, []byte(contacts), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{"whatphone", "enrich", "-d", "name,location", in}
+	if err := run(args, strings.NewReader(""), io.Discard, io.Discard, newConfigReader(testServerConfig(srv))); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "contacts.enriched.vcf"))
+	if err != nil {
+		t.Fatalf("%v didn't write the enriched file: %v", args, err)
+	}
+	expected := "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Sample\r\nTEL;TYPE=cell:tel:+1555123\r\n 4567\r\nTEL:+15557654321\r\n" +
+		"X-WHATPHONE-NAME:Michael Seaver\r\nX-WHATPHONE-CITY:Long Island\r\nX-WHATPHONE-STATE:NY\r\nX-WHATPHONE-ZIP:10003\r\n" +
+		"X-WHATPHONE-LATITUDE:40.799787\r\nX-WHATPHONE-LONGITUDE:-73.971421\r\nX-WHATPHONE-COST:-0.0150\r\nEND:VCARD\r\n" +
+		"BEGIN:VCARD\r\nVERSION:3.0\r\nFN:No Phone\r\nX-WHATPHONE-COST:0.0000\r\nX-WHATPHONE-ERROR:missing phone number\r\nEND:VCARD\r\n"
+	if string(got) != expected {
+		t.Errorf("%v wrote unexpected output.\nExpected: %q\nGot: %q\n", args, expected, got)
+	}
+}
+
+func TestEnrichErrors(t *testing.T) {
+	srv := enrichServer()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "contacts.csv")
+	if err := os.WriteFile(in, []byte("id,mobile\n1,5557654321\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args     []string
+		expected error
+	}{
+		{[]string{"whatphone", "enrich", "-d", "name"}, errors.New("missing contacts file")},
+		{[]string{"whatphone", "enrich", "-d", "name", in}, errors.New(`no "phone" column in CSV header`)},
+		{[]string{"whatphone", "enrich", "-d", "name", "-o", in, in}, errors.New("refusing to overwrite " + in + "; choose another --output")},
+		{[]string{"whatphone", "enrich", "-d", "name", "--type", "xlsx", in}, errors.New("unknown file type: xlsx")},
+		{[]string{"whatphone", "enrich", in}, errors.New("no data points selected; use --all to request all data points")},
+	}
+
+	for _, test := range tests {
+		err := run(test.args, strings.NewReader(""), io.Discard, io.Discard, newConfigReader(testServerConfig(srv)))
+		if err == nil || err.Error() != test.expected.Error() {
+			t.Errorf("%v returned unexpected error.\nExpected: %v\nGot: %v\n", test.args, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+
+	"samhofi.us/x/whatphone/pkg/geo"
+)
+
+// feature is a lookup result placed on a map
+type feature struct {
+	point      geo.Point
+	properties featureProperties
+}
+
+// featureProperties describe the phone number a feature was looked up for
+type featureProperties struct {
+	Number   string `json:"number"`
+	Name     string `json:"name,omitempty"`
+	Carrier  string `json:"carrier,omitempty"`
+	LineType string `json:"linetype,omitempty"`
+	City     string `json:"city,omitempty"`
+	State    string `json:"state,omitempty"`
+	Zip      string `json:"zip,omitempty"`
+}
+
+// fields returns the properties as name and value pairs, in the order they're declared
+func (p featureProperties) fields() [][2]string {
+	return [][2]string{
+		{"number", p.Number}, {"name", p.Name}, {"carrier", p.Carrier}, {"linetype", p.LineType},
+		{"city", p.City}, {"state", p.State}, {"zip", p.Zip},
+	}
+}
+
+// features turns lookup results into map features. Results without a location, whether from
+// their coordinates or their ZIP code, can't be placed on a map and are reported to errw instead.
+func features(errw io.Writer, outs []lookupOutput) []feature {
+	fs := make([]feature, 0, len(outs))
+	for _, out := range outs {
+		place := geo.FromLocation(out.Data.Location)
+		if place.Point == nil {
+			fmt.Fprintf(errw, "warning: %s: no location; skipped\n", out.Number)
+			continue
+		}
+
+		props := featureProperties{
+			Number:  out.Number,
+			Name:    out.Data.GetName(),
+			Carrier: out.Data.CarrierName(),
+			City:    out.Data.City(),
+			State:   place.State,
+			Zip:     place.ZIP,
+		}
+		if out.Data.Linetype != nil {
+			props.LineType = out.Data.LineType().String()
+		}
+		fs = append(fs, feature{point: *place.Point, properties: props})
+	}
+	return fs
+}
+
+// writeGeoJSON writes lookup results as a GeoJSON FeatureCollection of points
+func writeGeoJSON(w, errw io.Writer, outs []lookupOutput) error {
+	type geometry struct {
+		Type        string     `json:"type"`
+		Coordinates [2]float64 `json:"coordinates"`
+	}
+	type geoFeature struct {
+		Type       string            `json:"type"`
+		Geometry   geometry          `json:"geometry"`
+		Properties featureProperties `json:"properties"`
+	}
+	collection := struct {
+		Type     string       `json:"type"`
+		Features []geoFeature `json:"features"`
+	}{Type: "FeatureCollection", Features: []geoFeature{}}
+
+	for _, f := range features(errw, outs) {
+		collection.Features = append(collection.Features, geoFeature{
+			Type: "Feature",
+			// GeoJSON positions are longitude first
+			Geometry:   geometry{Type: "Point", Coordinates: [2]float64{f.point.Lon, f.point.Lat}},
+			Properties: f.properties,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(collection)
+}
+
+// writeKML writes lookup results as a KML document with a placemark for each result
+func writeKML(w, errw io.Writer, outs []lookupOutput) error {
+	type data struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value"`
+	}
+	type placemark struct {
+		Name         string `xml:"name"`
+		ExtendedData []data `xml:"ExtendedData>Data"`
+		Coordinates  string `xml:"Point>coordinates"`
+	}
+	doc := struct {
+		XMLName    xml.Name    `xml:"http://www.opengis.net/kml/2.2 kml"`
+		Name       string      `xml:"Document>name"`
+		Placemarks []placemark `xml:"Document>Placemark"`
+	}{Name: "whatphone"}
+
+	for _, f := range features(errw, outs) {
+		pm := placemark{
+			Name:        f.properties.Number,
+			Coordinates: strconv.FormatFloat(f.point.Lon, 'f', -1, 64) + "," + strconv.FormatFloat(f.point.Lat, 'f', -1, 64),
+		}
+		for _, field := range f.properties.fields() {
+			if field[1] != "" {
+				pm.ExtendedData = append(pm.ExtendedData, data{Name: field[0], Value: field[1]})
+			}
+		}
+		doc.Placemarks = append(doc.Placemarks, pm)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+// featureServer returns a fake EveryoneAPI that knows the sample number, a number located only by
+// its ZIP code, and a number with no location at all
+func featureServer() *apitest.Server {
+	carrier := &whatphone.Carrier{ID: "1", Name: "Example Wireless"}
+	return apitest.NewServer(
+		apitest.WithNumber("+15557654321", whatphone.Data{Carrier: carrier, Location: &whatphone.Location{City: "Seattle", State: "WA", Zip: "98101"}}),
+		apitest.WithNumber("+15550001111", whatphone.Data{Carrier: carrier}),
+	)
+}
+
+func TestGeoJSON(t *testing.T) {
+	srv := featureServer()
+	defer srv.Close()
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"whatphone", "lookup", "-f", "geojson", "-nclt", "15551234567", "5557654321", "5550001111"}
+	if err := run(args, strings.NewReader(""), &stdout, &stderr, newConfigReader(testServerConfig(srv))); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+
+	expected := `{
+  "type": "FeatureCollection",
+  "features": [
+    {
+      "type": "Feature",
+      "geometry": {
+        "type": "Point",
+        "coordinates": [
+          -73.971421,
+          40.799787
+        ]
+      },
+      "properties": {
+        "number": "+15551234567",
+        "name": "Michael Seaver",
+        "carrier": "Growing Wireless Inc.",
+        "linetype": "mobile",
+        "city": "Long Island",
+        "state": "NY",
+        "zip": "10003"
+      }
+    },
+    {
+      "type": "Feature",
+      "geometry": {
+        "type": "Point",
+        "coordinates": [
+          -122.3348,
+          47.6105
+        ]
+      },
+      "properties": {
+        "number": "+15557654321",
+        "carrier": "Example Wireless",
+        "city": "Seattle",
+        "state": "WA",
+        "zip": "98101"
+      }
+    }
+  ]
+}
+`
+	if stdout.String() != expected {
+		t.Errorf("%v returned unexpected output.\nExpected: %s\nGot: %s\n", args, expected, stdout.String())
+	}
+	if stderr.String() != "warning: +15550001111: no location; skipped\n" {
+		t.Errorf("%v reported unexpected warnings: %s", args, stderr.String())
+	}
+
+	// a single lookup is still written as a collection
+	stdout.Reset()
+	args = []string{"whatphone", "lookup", "-f", "geojson", "-n", "5550001111"}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, newConfigReader(testServerConfig(srv))); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	var collection struct {
+		Type     string
+		Features []json.RawMessage
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &collection); err != nil || collection.Type != "FeatureCollection" || collection.Features == nil || len(collection.Features) != 0 {
+		t.Errorf("%v returned unexpected output (%v):\n%s", args, err, stdout.String())
+	}
+}
+
+func TestKML(t *testing.T) {
+	srv := featureServer()
+	defer srv.Close()
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"whatphone", "lookup", "-f", "kml", "-nclt", "15551234567", "5550001111"}
+	if err := run(args, strings.NewReader(""), &stdout, &stderr, newConfigReader(testServerConfig(srv))); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+  <Document>
+    <name>whatphone</name>
+    <Placemark>
+      <name>+15551234567</name>
+      <ExtendedData>
+        <Data name="number">
+          <value>+15551234567</value>
+        </Data>
+        <Data name="name">
+          <value>Michael Seaver</value>
+        </Data>
+        <Data name="carrier">
+          <value>Growing Wireless Inc.</value>
+        </Data>
+        <Data name="linetype">
+          <value>mobile</value>
+        </Data>
+        <Data name="city">
+          <value>Long Island</value>
+        </Data>
+        <Data name="state">
+          <value>NY</value>
+        </Data>
+        <Data name="zip">
+          <value>10003</value>
+        </Data>
+      </ExtendedData>
+      <Point>
+        <coordinates>-73.971421,40.799787</coordinates>
+      </Point>
+    </Placemark>
+  </Document>
+</kml>
+`
+	if stdout.String() != expected {
+		t.Errorf("%v returned unexpected output.\nExpected: %s\nGot: %s\n", args, expected, stdout.String())
+	}
+	if stderr.String() != "warning: +15550001111: no location; skipped\n" {
+		t.Errorf("%v reported unexpected warnings: %s", args, stderr.String())
+	}
+}
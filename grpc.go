@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/urfave/cli/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/rpc"
+)
+
+// keyAuth authenticates gRPC calls by the API key sent as a bearer token in the authorization metadata
+type keyAuth map[string]bool
+
+// newKeyAuth returns a keyAuth accepting the given keys
+func newKeyAuth(keys []apiKey) keyAuth {
+	auth := make(keyAuth)
+	for _, k := range keys {
+		auth[k.Key] = true
+	}
+	return auth
+}
+
+// check returns an Unauthenticated error unless ctx carries a known API key
+func (a keyAuth) check(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(v, "Bearer "); ok && a[token] {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid API key")
+}
+
+func (a keyAuth) unary(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := a.check(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a keyAuth) stream(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.check(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func cmdGRPC(c *cli.Context) error {
+	config, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(c.String("keys"))
+	if err != nil {
+		return err
+	}
+	keys, err := loadKeys(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("unable to read keys: %v", err)
+	}
+	auth := newKeyAuth(keys)
+	opts := []grpc.ServerOption{grpc.UnaryInterceptor(auth.unary), grpc.StreamInterceptor(auth.stream)}
+
+	if c.Int("cache-size") >= 0 {
+		config.Cache = whatphone.NewMemoryCache(c.Duration("cache-ttl"), c.Int("cache-size"))
+	}
+
+	metricsHandler := instrument(&config.API)
+	if addr := c.String("metrics"); addr != "" {
+		msrv, err := serveMetrics(addr, metricsHandler)
+		if err != nil {
+			return err
+		}
+		defer msrv.Close()
+		fmt.Fprintf(c.App.Writer, "Serving metrics on %s\n", addr)
+	}
+
+	srv := grpc.NewServer(opts...)
+	rpc.RegisterLookupServiceServer(srv, rpc.NewServer(&config.API,
+		rpc.WithDefaultData(config.Data...),
+		rpc.WithConcurrency(c.Int("concurrency")),
+	))
+
+	l, err := net.Listen("tcp", c.String("listen"))
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(l)
+	}()
+	fmt.Fprintf(c.App.Writer, "Serving gRPC on %s\n", l.Addr())
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintf(c.App.Writer, "Shutting down\n")
+	srv.GracefulStop()
+	return nil
+}
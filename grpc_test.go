@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestKeyAuth(t *testing.T) {
+	auth := newKeyAuth([]apiKey{{Name: "crm", Key: "crm-key"}})
+
+	calls := []struct {
+		md       metadata.MD
+		expected codes.Code
+	}{
+		{metadata.Pairs("authorization", "Bearer crm-key"), codes.OK},
+		{metadata.Pairs("authorization", "Bearer wrong-key"), codes.Unauthenticated},
+		{metadata.Pairs("authorization", "crm-key"), codes.Unauthenticated},
+		{nil, codes.Unauthenticated},
+	}
+
+	for _, call := range calls {
+		ctx := metadata.NewIncomingContext(context.Background(), call.md)
+		if got := status.Code(auth.check(ctx)); got != call.expected {
+			t.Errorf("%v returned unexpected code. Got: %v, Want: %v", call.md, got, call.expected)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+)
+
+// readNumbers returns the phone numbers given as arguments, followed by any read from the file
+// named by the --input flag, or stdin when it's "-"
+func readNumbers(c *cli.Context) ([]string, error) {
+	numbers := c.Args().Slice()
+
+	input := c.String("input")
+	if input == "" {
+		return numbers, nil
+	}
+
+	var r io.Reader
+	if input == "-" {
+		r = c.App.Metadata["stdin"].(io.Reader)
+	} else {
+		f, err := os.Open(input)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	more, err := scanNumbers(r)
+	if err != nil {
+		return nil, err
+	}
+	return append(numbers, more...), nil
+}
+
+// scanNumbers reads one phone number per line, skipping blank lines and lines starting with #
+func scanNumbers(r io.Reader) ([]string, error) {
+	var numbers []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		numbers = append(numbers, line)
+	}
+	return numbers, s.Err()
+}
+
+// dedupe normalizes phone numbers to E.164 form, returning the normalized numbers along with the
+// error of each one that isn't valid, and the number of distinct valid numbers
+func dedupe(numbers []string) ([]string, []error, int) {
+	normalized := make([]string, len(numbers))
+	errs := make([]error, len(numbers))
+	seen := make(map[string]bool)
+	for i, number := range numbers {
+		if normalized[i], errs[i] = whatphone.Normalize(number); errs[i] == nil {
+			seen[normalized[i]] = true
+		}
+	}
+	return normalized, errs, len(seen)
+}
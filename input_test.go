@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func TestScanNumbers(t *testing.T) {
+	numbers, err := scanNumbers(strings.NewReader("# numbers to check\n+15551234567\n\n  555-765-4321  \r\n#+15550000000\n"))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	want := []string{"+15551234567", "555-765-4321"}
+	if !reflect.DeepEqual(numbers, want) {
+		t.Errorf("Error: Unexpected numbers. Got: %q, Want: %q", numbers, want)
+	}
+}
+
+// multiServer returns a fake EveryoneAPI that knows the sample number and one other
+func multiServer() *apitest.Server {
+	name := "Jane Doe"
+	return apitest.NewServer(apitest.WithNumber("+15557654321", whatphone.Data{Name: &name}))
+}
+
+func TestMultipleNumbers(t *testing.T) {
+	srv := multiServer()
+	defer srv.Close()
+	cr := newConfigReader(testServerConfig(srv))
+
+	var stdout bytes.Buffer
+	args := []string{"whatphone", "lookup", "-n", "15551234567", "5557654321"}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	expected := `Number: +15551234567
+Name: Michael Seaver
+Note: THIS IS A SAMPLE, YOU WILL NOT BE CHARGED
+Price Total: -0.0100
+
+Number: +15557654321
+Name: Jane Doe
+Price Total: 0.0100
+`
+	if stdout.String() != expected {
+		t.Errorf("%v returned unexpected output.\nExpected: %s\nGot: %s\n", args, expected, stdout.String())
+	}
+
+	// numbers from a file are looked up after those given as arguments, and even a single number
+	// from a file is written as a list
+	file := filepath.Join(t.TempDir(), "numbers.txt")
+	if err := os.WriteFile(file, []byte("5557654321\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"whatphone", "lookup", "-n", "-f", "json", "--input", file},
+		{"whatphone", "lookup", "-n", "-f", "json", "--input", "-"},
+	} {
+		stdout.Reset()
+		if err := run(args, strings.NewReader("5557654321\n"), &stdout, io.Discard, cr); err != nil {
+			t.Fatalf("%v returned error: %v", args, err)
+		}
+
+		var results []whatphone.Result
+		if err := json.Unmarshal(stdout.Bytes(), &results); err != nil {
+			t.Fatalf("%v returned invalid JSON: %v\n%s", args, err, stdout.String())
+		}
+		if len(results) != 1 || results[0].Number != "+15557654321" {
+			t.Errorf("%v returned unexpected results: %+v", args, results)
+		}
+	}
+}
+
+func TestMultipleNumbersErrors(t *testing.T) {
+	srv := multiServer()
+	defer srv.Close()
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"whatphone", "lookup", "-n", "-f", "json", "15551234567", "123", "5557654321"}
+	err := run(args, strings.NewReader(""), &stdout, &stderr, newConfigReader(testServerConfig(srv)))
+	if err == nil || err.Error() != "1 of 3 lookups failed" {
+		t.Errorf("%v returned unexpected error: %v", args, err)
+	}
+	if stderr.String() != "123: invalid phone number\n" {
+		t.Errorf("%v reported unexpected errors: %s", args, stderr.String())
+	}
+
+	var results []whatphone.Result
+	if err := json.Unmarshal(stdout.Bytes(), &results); err != nil || len(results) != 2 {
+		t.Errorf("%v should still write the successful lookups (%v):\n%s", args, err, stdout.String())
+	}
+
+	args = []string{"whatphone", "lookup", "-n", "--input", filepath.Join(t.TempDir(), "missing.txt")}
+	if err := run(args, strings.NewReader(""), io.Discard, io.Discard, newConfigReader(testServerConfig(srv))); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("%v returned unexpected error: %v", args, err)
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	numbers := []string{"555-123-4567", "+15551234567", "(555) 1234567", "123", "5557654321"}
+	normalized, errs, unique := dedupe(numbers)
+
+	expected := []string{"+15551234567", "+15551234567", "+15551234567", "", "+15557654321"}
+	if !reflect.DeepEqual(normalized, expected) {
+		t.Errorf("Error: Unexpected normalized numbers. Got: %v, Want: %v", normalized, expected)
+	}
+	for i, err := range errs {
+		if (err != nil) != (i == 3) {
+			t.Errorf("Error: Unexpected error for %q: %v", numbers[i], err)
+		}
+	}
+	if unique != 2 {
+		t.Errorf("Error: Unexpected number of unique numbers. Got: %d, Want: %d", unique, 2)
+	}
+}
+
+func TestMultipleNumbersDuplicates(t *testing.T) {
+	srv := multiServer()
+	defer srv.Close()
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"whatphone", "lookup", "-n", "-f", "json", "555-123-4567", "+15551234567", "(555) 1234567", "5557654321"}
+	if err := run(args, strings.NewReader(""), &stdout, &stderr, newConfigReader(testServerConfig(srv))); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if srv.Requests() != 2 {
+		t.Errorf("%v made %d requests; each distinct number should only be looked up once", args, srv.Requests())
+	}
+	if expected := "Answered 2 duplicate numbers without a paid lookup\n"; stderr.String() != expected {
+		t.Errorf("%v returned unexpected stderr.\nExpected: %s\nGot: %s\n", args, expected, stderr.String())
+	}
+
+	var results []whatphone.Result
+	if err := json.Unmarshal(stdout.Bytes(), &results); err != nil {
+		t.Fatalf("%v returned invalid JSON: %v\n%s", args, err, stdout.String())
+	}
+	var got []string
+	for _, result := range results {
+		got = append(got, result.Number)
+	}
+	expected := []string{"+15551234567", "+15551234567", "+15551234567", "+15557654321"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("%v should return a result for every input number. Got: %v, Want: %v", args, got, expected)
+	}
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+)
+
+// Status of a job
+const (
+	jobIncomplete = "incomplete"
+	jobComplete   = "complete"
+)
+
+// jobExt is the extension of checkpoint files
+const jobExt = ".jsonl"
+
+// jobHeader describes a job. It's the first line of the job's checkpoint file. Redacted is set
+// when phone numbers were masked in Args, which means the job can't be resumed from them.
+type jobHeader struct {
+	ID       string    `json:"id"`
+	Command  string    `json:"command"`
+	Args     []string  `json:"args"`
+	Redacted bool      `json:"redacted,omitempty"`
+	Dir      string    `json:"dir"`
+	Total    int       `json:"total,omitempty"`
+	Started  time.Time `json:"started"`
+}
+
+// jobDone records the end of a run of a job
+type jobDone struct {
+	Total  int `json:"total"`
+	Failed int `json:"failed"`
+}
+
+// jobEntry is a single line of a checkpoint file: the job header, a completed lookup along with
+// the response it was answered with, or the end of a run. Numbers are kept as their
+// Redaction.NumberKey, so they're hashed when numbers are masked.
+type jobEntry struct {
+	Job      *jobHeader      `json:"job,omitempty"`
+	Number   string          `json:"number,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Done     *jobDone        `json:"done,omitempty"`
+	Time     time.Time       `json:"time"`
+}
+
+// job checkpoints a long running lookup command, so an interrupted run can be resumed without
+// looking up, and paying for, the numbers it already looked up. Checkpoints are kept as JSON
+// lines, appended to as lookups complete, so nothing but the lookup in flight is lost if the
+// process dies.
+type job struct {
+	jobHeader
+
+	path      string
+	redaction whatphone.Redaction
+	results   map[string]*whatphone.Result
+	cost      float64
+	done      *jobDone
+	updated   time.Time
+	size      int64
+	f         *os.File
+}
+
+// jobsDir returns the directory checkpoint files are kept in, creating it if needed
+func jobsDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(configDir, "whatphone", "jobs")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// jobID derives the ID of a job from the command line and working directory it was run with, so
+// running the same command again finds the same job. When a secret is given, the ID is keyed with
+// it, so it can't be used to guess the phone numbers on the command line.
+func jobID(secret, dir string, args []string) string {
+	h := sha256.New()
+	if secret != "" {
+		h = hmac.New(sha256.New, []byte(secret))
+	}
+	io.WriteString(h, dir)
+	for _, arg := range args {
+		io.WriteString(h, "\x00"+arg)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// jobArgs returns the command line the app was run with, without the program name or --resume
+func jobArgs(c *cli.Context) []string {
+	all, _ := c.App.Metadata["args"].([]string)
+	var args []string
+	for i, arg := range all {
+		if i == 0 || arg == "--resume" {
+			continue
+		}
+		args = append(args, arg)
+	}
+	return args
+}
+
+// startJob starts checkpointing a run of command, which looks up total numbers, or an unknown
+// number of them when total is 0. With --resume, the numbers already looked up by a previous run
+// of the same command line are loaded from its checkpoint, otherwise the job starts over. Phone
+// numbers are kept out of the checkpoint when r masks them.
+func startJob(c *cli.Context, command string, total int, r whatphone.Redaction) (*job, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	args := jobArgs(c)
+	var secret string
+	if r.MaskNumbers {
+		secret = r.Secret
+	}
+	id := jobID(secret, dir, args)
+
+	var redacted bool
+	kept := make([]string, len(args))
+	for i, arg := range args {
+		kept[i] = r.Text(arg)
+		redacted = redacted || kept[i] != arg
+	}
+
+	jd, err := jobsDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(jd, id+jobExt)
+
+	if c.Bool("resume") {
+		j, err := loadJob(path)
+		switch {
+		case err == nil:
+			if err := j.open(); err != nil {
+				return nil, err
+			}
+			j.redaction = r
+			j.done = nil
+			fmt.Fprintf(c.App.ErrWriter, "Resuming job %s: %d numbers already looked up\n", j.ID, len(j.results))
+			return j, nil
+		case !os.IsNotExist(err):
+			return nil, err
+		}
+	}
+
+	j := &job{
+		jobHeader: jobHeader{
+			ID:       id,
+			Command:  command,
+			Args:     kept,
+			Redacted: redacted,
+			Dir:      dir,
+			Total:    total,
+			Started:  time.Now().UTC(),
+		},
+		path:      path,
+		redaction: r,
+		results:   make(map[string]*whatphone.Result),
+	}
+	if j.f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600); err != nil {
+		return nil, err
+	}
+	if err := j.write(jobEntry{Job: &j.jobHeader, Time: j.Started}); err != nil {
+		j.close()
+		return nil, err
+	}
+	return j, nil
+}
+
+// loadJob reads a checkpoint file. A torn last line, left by a process that died while writing
+// it, is ignored.
+func loadJob(path string) (*job, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &job{path: path, results: make(map[string]*whatphone.Result)}
+	r := bufio.NewReader(bytes.NewReader(b))
+	for first := true; ; first = false {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			// a line without a newline was never completely written
+			break
+		}
+
+		var entry jobEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			if r.Buffered() == 0 {
+				break
+			}
+			return nil, fmt.Errorf("%s: corrupt checkpoint: %v", path, err)
+		}
+		if first {
+			if entry.Job == nil {
+				return nil, fmt.Errorf("%s: not a checkpoint file", path)
+			}
+			j.jobHeader = *entry.Job
+		}
+
+		switch {
+		case entry.Number != "":
+			result, err := whatphone.Decode(entry.Response)
+			if err != nil {
+				return nil, fmt.Errorf("%s: corrupt checkpoint: %s: %v", path, entry.Number, err)
+			}
+			if _, ok := j.results[entry.Number]; !ok {
+				j.cost += result.Pricing.Total
+			}
+			j.results[entry.Number] = result
+			j.done = nil
+		case entry.Done != nil:
+			j.done = entry.Done
+		}
+		j.updated = entry.Time
+		j.size += int64(len(line))
+	}
+
+	if j.ID == "" {
+		return nil, fmt.Errorf("%s: not a checkpoint file", path)
+	}
+	return j, nil
+}
+
+// open opens a loaded checkpoint file for appending, dropping any torn last line
+func (j *job) open() error {
+	f, err := os.OpenFile(j.path, os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(j.size); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(j.size, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	j.f = f
+	return nil
+}
+
+// write appends an entry to the checkpoint file
+func (j *job) write(entry jobEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := j.f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("writing checkpoint: %v", err)
+	}
+	return nil
+}
+
+// result returns the result of a number looked up by this or a previous run of the job. A nil job
+// has no results.
+func (j *job) result(number string) (*whatphone.Result, bool) {
+	if j == nil {
+		return nil, false
+	}
+	result, ok := j.results[j.redaction.NumberKey(number)]
+	return result, ok
+}
+
+// record checkpoints the result of a lookup. Nothing is recorded by a nil job.
+func (j *job) record(number string, result *whatphone.Result) error {
+	if j == nil {
+		return nil
+	}
+	response := result.Raw
+	if len(response) == 0 {
+		var err error
+		if response, err = json.Marshal(result); err != nil {
+			return err
+		}
+	}
+	key := j.redaction.NumberKey(number)
+	if err := j.write(jobEntry{Number: key, Response: response, Time: time.Now().UTC()}); err != nil {
+		return err
+	}
+	j.results[key] = result
+	return nil
+}
+
+// finish records the end of a run that went through total numbers, of which failed couldn't be
+// looked up, and closes the checkpoint file
+func (j *job) finish(total, failed int) error {
+	if j == nil {
+		return nil
+	}
+	j.done = &jobDone{Total: total, Failed: failed}
+	err := j.write(jobEntry{Done: j.done, Time: time.Now().UTC()})
+	if cerr := j.close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// close closes the checkpoint file, if it's open
+func (j *job) close() error {
+	if j == nil || j.f == nil {
+		return nil
+	}
+	err := j.f.Close()
+	j.f = nil
+	return err
+}
+
+// status returns whether the last run of the job looked up every number
+func (j *job) status() string {
+	if j.done != nil && j.done.Failed == 0 {
+		return jobComplete
+	}
+	return jobIncomplete
+}
+
+// progress returns the number of numbers looked up out of the total, if it's known
+func (j *job) progress() string {
+	total := j.Total
+	if j.done != nil {
+		total = j.done.Total
+	}
+	if total == 0 {
+		return fmt.Sprintf("%d/?", len(j.results))
+	}
+	return fmt.Sprintf("%d/%d", len(j.results), total)
+}
+
+// commandLine returns the command line the job was run with
+func (j *job) commandLine() string {
+	return strings.Join(append([]string{"whatphone"}, j.Args...), " ")
+}
+
+// listJobs returns every job, most recently started first. Checkpoints that can't be read are
+// skipped with a warning written to warn.
+func listJobs(warn io.Writer) ([]*job, error) {
+	dir, err := jobsDir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+jobExt))
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*job, 0, len(paths))
+	for _, path := range paths {
+		j, err := loadJob(path)
+		if err != nil {
+			fmt.Fprintf(warn, "warning: skipping job: %v\n", err)
+			continue
+		}
+		jobs = append(jobs, j)
+	}
+	sort.Slice(jobs, func(a, b int) bool {
+		return jobs[a].Started.After(jobs[b].Started)
+	})
+	return jobs, nil
+}
+
+// findJob loads the job with the given ID, which may be shortened to any unique prefix
+func findJob(warn io.Writer, id string) (*job, error) {
+	if id == "" {
+		return nil, fmt.Errorf("missing job ID")
+	}
+	jobs, err := listJobs(warn)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *job
+	for _, j := range jobs {
+		if !strings.HasPrefix(j.ID, id) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("ambiguous job ID: %s", id)
+		}
+		found = j
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no such job: %s", id)
+	}
+	return found, nil
+}
+
+func cmdJobsList(c *cli.Context) error {
+	jobs, err := listJobs(c.App.ErrWriter)
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		fmt.Fprintf(c.App.Writer, "No jobs\n")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID\tCOMMAND\tSTATUS\tDONE\tSTARTED\n")
+	for _, j := range jobs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", j.ID, j.Command, j.status(), j.progress(), j.Started.Local().Format("2006-01-02 15:04"))
+	}
+	return tw.Flush()
+}
+
+func cmdJobsShow(c *cli.Context) error {
+	j, err := findJob(c.App.ErrWriter, c.Args().First())
+	if err != nil {
+		return err
+	}
+	// jobs checkpointed before numbers were masked still hold them
+	r, err := whatphone.ParseRedaction(c.String("redact"))
+	if err != nil {
+		return err
+	}
+
+	w := c.App.Writer
+	fmt.Fprintf(w, "ID: %s\n", j.ID)
+	fmt.Fprintf(w, "Command: %s\n", r.Text(j.commandLine()))
+	fmt.Fprintf(w, "Directory: %s\n", j.Dir)
+	fmt.Fprintf(w, "Started: %s\n", j.Started.Local().Format(time.RFC3339))
+	fmt.Fprintf(w, "Updated: %s\n", j.updated.Local().Format(time.RFC3339))
+	fmt.Fprintf(w, "Status: %s\n", j.status())
+	fmt.Fprintf(w, "Looked up: %s\n", j.progress())
+	if j.done != nil && j.done.Failed > 0 {
+		fmt.Fprintf(w, "Failed: %d\n", j.done.Failed)
+	}
+	fmt.Fprintf(w, "Cost: %.4f\n", j.cost)
+	fmt.Fprintf(w, "Checkpoint: %s\n", j.path)
+	return nil
+}
+
+// cmdJobsResume runs a job's command line again with --resume, from the directory it was first
+// run in
+func cmdJobsResume(c *cli.Context) error {
+	j, err := findJob(c.App.ErrWriter, c.Args().First())
+	if err != nil {
+		return err
+	}
+	if j.Redacted {
+		return fmt.Errorf("job %s can't be resumed, since the phone numbers on its command line were masked; run the command again with --resume", j.ID)
+	}
+
+	// --resume is a flag of the job's command, so it has to follow the command name
+	args := []string{"whatphone"}
+	for i, arg := range j.Args {
+		if arg == j.Command {
+			args = append(append(append(args, j.Args[:i+1]...), "--resume"), j.Args[i+1:]...)
+			break
+		}
+	}
+	if len(args) == 1 {
+		return fmt.Errorf("job %s has no %s command to resume", j.ID, j.Command)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(j.Dir); err != nil {
+		return err
+	}
+	defer os.Chdir(wd)
+
+	cr := c.App.Metadata["configReader"].(configReader)
+	stdin := c.App.Metadata["stdin"].(io.Reader)
+	return run(args, stdin, c.App.Writer, c.App.ErrWriter, cr)
+}
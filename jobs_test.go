@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func TestLookupResume(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	name := "Jane Doe"
+	srv := apitest.NewServer(apitest.WithNumber("+15557654321", whatphone.Data{Name: &name}))
+	defer srv.Close()
+	cr := newConfigReader(testServerConfig(srv))
+
+	input := filepath.Join(t.TempDir(), "numbers.txt")
+	if err := os.WriteFile(input, []byte("5557654321\n15551234567\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the first lookup fails, so the job is left incomplete
+	srv.FailNext(1, 404)
+	args := []string{"whatphone", "lookup", "-n", "--input", input}
+	if err := run(args, strings.NewReader(""), io.Discard, io.Discard, cr); err == nil {
+		t.Fatalf("%v should have returned an error but didn't", args)
+	}
+	if got := srv.Requests(); got != 2 {
+		t.Fatalf("Error: Unexpected number of requests. Got: %d, Want: %d", got, 2)
+	}
+
+	var stdout, stderr bytes.Buffer
+	args = []string{"whatphone", "lookup", "-n", "--resume", "--input", input}
+	if err := run(args, strings.NewReader(""), &stdout, &stderr, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if got := srv.Requests(); got != 3 {
+		t.Errorf("%v didn't skip the number already looked up. Got %d requests, Want: %d", args, got, 3)
+	}
+	if !strings.Contains(stderr.String(), ": 1 numbers already looked up") {
+		t.Errorf("%v didn't report resuming the job:\n%s", args, stderr.String())
+	}
+	for _, expected := range []string{"Number: +15557654321\nName: Jane Doe\n", "Number: +15551234567\nName: Michael Seaver\n"} {
+		if !strings.Contains(stdout.String(), expected) {
+			t.Errorf("%v returned unexpected output.\nExpected to contain: %s\nGot: %s\n", args, expected, stdout.String())
+		}
+	}
+
+	jobs, err := listJobs(io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("Error: Unexpected number of jobs. Got: %d, Want: %d", len(jobs), 1)
+	}
+	j := jobs[0]
+	if j.status() != jobComplete || j.progress() != "2/2" {
+		t.Errorf("Error: Unexpected job status. Got: %s %s, Want: %s 2/2", j.status(), j.progress(), jobComplete)
+	}
+
+	stdout.Reset()
+	args = []string{"whatphone", "jobs"}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if !strings.Contains(stdout.String(), j.ID+"  lookup   complete  2/2") {
+		t.Errorf("%v returned unexpected output:\n%s", args, stdout.String())
+	}
+
+	stdout.Reset()
+	args = []string{"whatphone", "jobs", "show", j.ID[:6]}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	for _, expected := range []string{"ID: " + j.ID + "\n", "Command: whatphone lookup -n --input " + input + "\n", "Looked up: 2/2\n", "Cost: 0.0000\n"} {
+		if !strings.Contains(stdout.String(), expected) {
+			t.Errorf("%v returned unexpected output.\nExpected to contain: %s\nGot: %s\n", args, expected, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	args = []string{"whatphone", "jobs", "resume", j.ID}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if got := srv.Requests(); got != 3 {
+		t.Errorf("%v looked up numbers again. Got %d requests, Want: %d", args, got, 3)
+	}
+	if !strings.Contains(stdout.String(), "Name: Jane Doe\n") {
+		t.Errorf("%v returned unexpected output:\n%s", args, stdout.String())
+	}
+}
+
+func TestLookupResumeRedacted(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	name := "Jane Doe"
+	srv := apitest.NewServer(apitest.WithNumber("+15557654321", whatphone.Data{Name: &name}))
+	defer srv.Close()
+	cr := newConfigReader(testServerConfig(srv))
+
+	srv.FailNext(1, 404)
+	args := []string{"whatphone", "--redact", "mask-numbers", "lookup", "-n", "5557654321", "15551234567"}
+	if err := run(args, strings.NewReader(""), io.Discard, io.Discard, cr); err == nil {
+		t.Fatalf("%v should have returned an error but didn't", args)
+	}
+
+	args = []string{"whatphone", "--redact", "mask-numbers", "lookup", "-n", "--resume", "5557654321", "15551234567"}
+	if err := run(args, strings.NewReader(""), io.Discard, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if got := srv.Requests(); got != 3 {
+		t.Errorf("%v didn't skip the number already looked up. Got %d requests, Want: %d", args, got, 3)
+	}
+
+	jobs, err := listJobs(io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("Error: Unexpected number of jobs. Got: %d, Want: %d", len(jobs), 1)
+	}
+	j := jobs[0]
+	b, _ := os.ReadFile(j.path)
+	for _, leak := range []string{"5557654321", "5551234567"} {
+		if strings.Contains(string(b), leak) {
+			t.Errorf("Error: %s leaked into the checkpoint:\n%s", leak, b)
+		}
+	}
+
+	var stdout bytes.Buffer
+	args = []string{"whatphone", "jobs", "show", j.ID}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if expected := "Command: whatphone --redact mask-numbers lookup -n ******4321 *******4567\n"; !strings.Contains(stdout.String(), expected) {
+		t.Errorf("%v returned unexpected output.\nExpected to contain: %s\nGot: %s\n", args, expected, stdout.String())
+	}
+
+	args = []string{"whatphone", "jobs", "resume", j.ID}
+	if err := run(args, strings.NewReader(""), io.Discard, io.Discard, cr); err == nil || !strings.Contains(err.Error(), "were masked") {
+		t.Errorf("%v returned unexpected error: %v", args, err)
+	}
+}
+
+func TestLookupResumeWebhook(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	srv := apitest.NewServer()
+	defer srv.Close()
+	receiver, deliveries := webhookReceiver(http.StatusOK)
+	defer receiver.Close()
+	cr := newConfigReader(func() (*config, error) {
+		cfg, _ := testServerConfig(srv)()
+		cfg.Webhook = &webhookConfig{URL: receiver.URL}
+		return cfg, nil
+	})
+
+	srv.FailNext(1, 404)
+	args := []string{"whatphone", "lookup", "-n", "5557654321", "15551234567"}
+	if err := run(args, strings.NewReader(""), io.Discard, io.Discard, cr); err == nil {
+		t.Fatalf("%v should have returned an error but didn't", args)
+	}
+	args = []string{"whatphone", "lookup", "-n", "--resume", "5557654321", "15551234567"}
+	if err := run(args, strings.NewReader(""), io.Discard, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+
+	// the result resumed from the checkpoint was delivered by the first run only
+	got := deliveries()
+	if len(got) != 2 || got[0].result.Number == got[1].result.Number {
+		t.Fatalf("%v made unexpected deliveries: %+v", args, got)
+	}
+}
+
+func TestJobsListCorrupt(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	srv := apitest.NewServer()
+	defer srv.Close()
+	cr := newConfigReader(testServerConfig(srv))
+
+	args := []string{"whatphone", "lookup", "-n", "--resume", "15551234567", "5557654321"}
+	if err := run(args, strings.NewReader(""), io.Discard, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	dir, err := jobsDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "corrupt"+jobExt), []byte("{not json\n{}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	args = []string{"whatphone", "jobs"}
+	if err := run(args, strings.NewReader(""), &stdout, &stderr, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if !strings.Contains(stdout.String(), "lookup   complete  2/2") {
+		t.Errorf("%v returned unexpected output:\n%s", args, stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "warning: skipping job: ") || !strings.Contains(stderr.String(), "corrupt"+jobExt+": corrupt checkpoint") {
+		t.Errorf("%v didn't warn about the corrupt checkpoint:\n%s", args, stderr.String())
+	}
+}
+
+func TestLookupWithoutResume(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	srv := apitest.NewServer()
+	defer srv.Close()
+	cr := newConfigReader(testServerConfig(srv))
+
+	args := []string{"whatphone", "lookup", "-n", "15551234567", "5557654321"}
+	for i := 0; i < 2; i++ {
+		if err := run(args, strings.NewReader(""), io.Discard, io.Discard, cr); err != nil {
+			t.Fatalf("%v returned error: %v", args, err)
+		}
+	}
+	if got := srv.Requests(); got != 4 {
+		t.Errorf("Error: Unexpected number of requests. Got: %d, Want: %d", got, 4)
+	}
+}
+
+func TestEnrichResume(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	srv := enrichServer()
+	defer srv.Close()
+	cr := newConfigReader(testServerConfig(srv))
+
+	in := filepath.Join(t.TempDir(), "contacts.csv")
+	if err := os.WriteFile(in, []byte("phone\n5557654321\n15551234567\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{"whatphone", "enrich", "-d", "name", in}
+	if err := run(args, strings.NewReader(""), io.Discard, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+
+	var stdout bytes.Buffer
+	args = []string{"whatphone", "enrich", "--resume", "-d", "name", in}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if got := srv.Requests(); got != 2 {
+		t.Errorf("%v looked up numbers again. Got %d requests, Want: %d", args, got, 2)
+	}
+	expected := "Enriched 2 rows with 0 lookups (0 errors), costing 0.0000\nReused 2 lookups from the job's checkpoint\n"
+	if !strings.HasPrefix(stdout.String(), expected) {
+		t.Errorf("%v returned unexpected output.\nExpected: %s\nGot: %s\n", args, expected, stdout.String())
+	}
+
+	got, _ := os.ReadFile(strings.TrimSuffix(in, ".csv") + ".enriched.csv")
+	if !strings.Contains(string(got), "5557654321,Jane Doe,0.0000,\n") {
+		t.Errorf("%v wrote an unexpected file:\n%s", args, got)
+	}
+}
+
+func TestLoadJobTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "job"+jobExt)
+	checkpoint := `{"job":{"id":"abc","command":"lookup","args":["lookup","1","2"],"dir":"/","total":2,"started":"2026-01-02T03:04:05Z"},"time":"2026-01-02T03:04:05Z"}
+{"number":"1","response":{"number":"+15551234567","status":true,"pricing":{"total":0.01}},"time":"2026-01-02T03:04:06Z"}
+{"number":"2","resp`
+	if err := os.WriteFile(path, []byte(checkpoint), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	j, err := loadJob(path)
+	if err != nil {
+		t.Fatalf("Error: loadJob returned error: %v", err)
+	}
+	if _, ok := j.result("2"); ok {
+		t.Errorf("Error: loadJob loaded a torn line")
+	}
+	if result, ok := j.result("1"); !ok || result.Number != "+15551234567" {
+		t.Errorf("Error: loadJob didn't load a completed lookup. Got: %v", result)
+	}
+	if j.progress() != "1/2" || j.status() != jobIncomplete {
+		t.Errorf("Error: Unexpected job status. Got: %s %s, Want: %s 1/2", j.status(), j.progress(), jobIncomplete)
+	}
+
+	if err := j.open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.record("2", &whatphone.Result{Number: "+15557654321"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.finish(2, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	j, err = loadJob(path)
+	if err != nil {
+		t.Fatalf("Error: loadJob returned error after appending: %v", err)
+	}
+	if j.progress() != "2/2" || j.status() != jobComplete {
+		t.Errorf("Error: Unexpected job status. Got: %s %s, Want: %s 2/2", j.status(), j.progress(), jobComplete)
+	}
+	if j.cost != 0.01 {
+		t.Errorf("Error: Unexpected job cost. Got: %v, Want: %v", j.cost, 0.01)
+	}
+}
+
+func TestJobID(t *testing.T) {
+	a := jobID("", "/tmp", []string{"lookup", "--input", "numbers.txt"})
+	if a != jobID("", "/tmp", []string{"lookup", "--input", "numbers.txt"}) {
+		t.Errorf("Error: jobID isn't stable")
+	}
+	if a == jobID("", "/home", []string{"lookup", "--input", "numbers.txt"}) {
+		t.Errorf("Error: jobID ignores the working directory")
+	}
+	if a == jobID("", "/tmp", []string{"lookup", "--input numbers.txt"}) {
+		t.Errorf("Error: jobID doesn't separate arguments")
+	}
+	if len(a) != 12 {
+		t.Errorf("Error: Unexpected jobID length. Got: %d, Want: %d", len(a), 12)
+	}
+}
@@ -1,14 +1,26 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/urfave/cli/v2"
 	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/fixture"
+	"samhofi.us/x/whatphone/pkg/geo"
+	"samhofi.us/x/whatphone/pkg/sqlite"
+	"samhofi.us/x/whatphone/pkg/webhook"
 )
 
 const (
@@ -17,9 +29,30 @@ const (
 
 	// Exit code on failure
 	exitFail = 1
+
+	// Phone number that EveryoneAPI answers with sample data, free of charge
+	sampleNumber = "+15551234567"
 )
 
-type configFunc func() (*whatphone.API, error)
+// config holds the app settings stored in the config file
+type config struct {
+	whatphone.API
+
+	// Data holds the data points requested when none are given on the command line
+	Data []string `json:",omitempty"`
+
+	// Format holds the output format used when none is given on the command line
+	Format string `json:",omitempty"`
+
+	// Presets holds named lists of data points selectable with --preset, in addition to the
+	// built-in presets
+	Presets map[string][]string `json:",omitempty"`
+
+	// Webhook holds where lookup results are delivered, if anywhere
+	Webhook *webhookConfig `json:",omitempty"`
+}
+
+type configFunc func() (*config, error)
 
 type configReader struct {
 	reader configFunc
@@ -32,35 +65,126 @@ func newConfigReader(f configFunc) configReader {
 }
 
 func main() {
-	if err := run(os.Args, os.Stdout, newConfigReader(readConfig)); err != nil {
+	if err := run(os.Args, os.Stdin, os.Stdout, os.Stderr, newConfigReader(readConfig)); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(exitFail)
 	}
 }
 
-func run(args []string, stdout io.Writer, cr configReader) error {
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer, cr configReader) error {
 	app := cli.App{
 		Name:                   "WhatPhone",
 		HelpName:               "whatphone",
 		Usage:                  "Phone number lookup via EveryoneAPI",
 		UseShortOptionHandling: true,
 		Writer:                 stdout,
+		ErrWriter:              stderr,
 		Version:                version,
-		Metadata:               map[string]interface{}{"configReader": cr},
+		Metadata:               map[string]interface{}{"configReader": cr, "stdin": stdin, "args": args},
+
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "record",
+				Usage: "Record EveryoneAPI responses as fixtures in `DIR`",
+			},
+			&cli.StringFlag{
+				Name:  "replay",
+				Usage: "Answer lookups with fixtures previously recorded in `DIR` instead of EveryoneAPI",
+			},
+			&cli.BoolFlag{
+				Name:  "scrub-pii",
+				Usage: "Scrub phone numbers and personal data from recorded fixtures",
+			},
+			&cli.StringFlag{
+				Name:    "redact",
+				Usage:   "Redact personal data from every result before it's output, cached, stored or logged, as a comma separated list of rules (mask-numbers, drop-image, truncate-address, hash-names), all, or none",
+				Value:   "none",
+				EnvVars: []string{"WHATPHONE_REDACT"},
+			},
+			&cli.StringFlag{
+				Name:    "redact-secret",
+				Usage:   "Key the hashes made by --redact with `SECRET`, instead of the one generated and kept in the config directory. Anyone holding it can check a guessed name or number against a hash.",
+				EnvVars: []string{"WHATPHONE_REDACT_SECRET"},
+			},
+			&cli.BoolFlag{
+				Name:  "verbose",
+				Usage: "Log the URL, status and timing of every EveryoneAPI request to stderr",
+			},
+			&cli.BoolFlag{
+				Name:  "debug",
+				Usage: "Log like --verbose, but include raw response bodies",
+			},
+			&cli.StringFlag{
+				Name:    "otlp-endpoint",
+				Usage:   "Export lookup traces over OTLP/gRPC to `HOST:PORT`",
+				EnvVars: []string{"WHATPHONE_OTLP_ENDPOINT"},
+			},
+			&cli.BoolFlag{
+				Name:  "otlp-insecure",
+				Usage: "Export traces without TLS",
+			},
+		},
+		After: shutdownTracer,
 
 		Commands: []*cli.Command{
 			{
 				Name:      "lookup",
 				Usage:     "Perform a phone number lookup",
 				Action:    cmdLookup,
-				ArgsUsage: "<phone number>",
+				ArgsUsage: "<phone number>...",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
-						// TODO: implement this
-						Name:    "json",
-						Aliases: []string{"j"},
-						Usage:   "Output JSON data",
-						Hidden:  true,
+						Name:    "format",
+						Aliases: []string{"f"},
+						Usage:   "Output format (" + strings.Join(formats, ", ") + ")",
+						Value:   formatText,
+					},
+					&cli.StringFlag{
+						Name:  "vcard-dir",
+						Usage: "Write each result to its own .vcf file in `DIR`, implying --format vcard",
+					},
+					&cli.StringFlag{
+						Name:  "sqlite",
+						Usage: "Also store results in the SQLite database `FILE`, creating it if needed",
+					},
+					&cli.StringFlag{
+						Name:  "webhook",
+						Usage: "Also deliver results to the webhook `URL`, overriding the config",
+					},
+					&cli.StringFlag{
+						Name:    "webhook-secret",
+						Usage:   "Sign webhook deliveries with HMAC-SHA256 using `SECRET`",
+						EnvVars: []string{"WHATPHONE_WEBHOOK_SECRET"},
+					},
+					&cli.StringFlag{
+						Name:  "webhook-dead-letter",
+						Usage: "Append webhook deliveries that fail for good to `FILE`",
+					},
+					&cli.StringFlag{
+						Name:  "input",
+						Usage: "Also look up the phone numbers in `FILE`, one per line; - reads from stdin",
+					},
+					&cli.BoolFlag{
+						Name:  "raw",
+						Usage: "Output the untouched EveryoneAPI response body, ignoring --format",
+					},
+					&cli.BoolFlag{
+						Name:  "strict",
+						Usage: "Validate the response against the expected schema, printing any mismatches to stderr",
+					},
+					&cli.BoolFlag{
+						Name:  "resume",
+						Usage: "Skip the numbers already looked up by an interrupted run of the same command; see the jobs command",
+					},
+					&cli.StringFlag{
+						Name:  "progress",
+						Usage: "Report progress to stderr as a bar, periodic status lines, or not at all (auto, bar, lines, off)",
+						Value: progressAuto,
+					},
+					&cli.DurationFlag{
+						Name:  "progress-interval",
+						Usage: "Time between status lines when reporting progress as lines",
+						Value: 10 * time.Second,
 					},
 					&cli.BoolFlag{
 						Name:    "pricing-breakdown",
@@ -71,6 +195,19 @@ func run(args []string, stdout io.Writer, cr configReader) error {
 						Name:  "all",
 						Usage: "Request all data points",
 					},
+					&cli.StringFlag{
+						Name:    "data",
+						Aliases: []string{"d"},
+						Usage:   "Request data points as a comma separated list, e.g. name,carrier",
+					},
+					&cli.StringFlag{
+						Name:  "preset",
+						Usage: "Request the data points of a preset; see the datapoints command",
+					},
+					&cli.StringFlag{
+						Name:  "near",
+						Usage: "Show how far the number's location is from `ZIP|LAT,LONG`, and whether it's in the same state",
+					},
 					&cli.BoolFlag{
 						Name:    "name",
 						Aliases: []string{"n"},
@@ -129,22 +266,319 @@ func run(args []string, stdout io.Writer, cr configReader) error {
 				},
 			},
 			{
-				Name:   "init",
-				Usage:  "Initialize the app with your EveryoneAPI credentials",
-				Action: cmdInit,
+				Name:        "enrich",
+				Usage:       "Add lookup data to a CSV or vCard contacts file",
+				Description: "Writes a copy of the file with a whatphone_ column, or X-WHATPHONE- property, for each selected data point, plus the cost and any error of each lookup. Numbers are normalized to E.164 first, so each distinct number is only looked up once however it's written.",
+				Action:      cmdEnrich,
+				ArgsUsage:   "<file>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Write the enriched copy to `FILE` instead of <file>.enriched.<ext>",
+					},
+					&cli.StringFlag{
+						Name:  "type",
+						Usage: "Read the file as csv or vcard instead of guessing from its extension",
+					},
+					&cli.StringFlag{
+						Name:  "column",
+						Usage: "Read phone numbers from the CSV column with this header `NAME`, or at this 1-based position",
+						Value: "phone",
+					},
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "Request all data points",
+					},
+					&cli.StringFlag{
+						Name:    "data",
+						Aliases: []string{"d"},
+						Usage:   "Request data points as a comma separated list, e.g. name,carrier",
+					},
+					&cli.StringFlag{
+						Name:  "preset",
+						Usage: "Request the data points of a preset; see the datapoints command",
+					},
+					&cli.BoolFlag{
+						Name:  "resume",
+						Usage: "Skip the numbers already looked up by an interrupted run of the same command; see the jobs command",
+					},
+					&cli.StringFlag{
+						Name:  "progress",
+						Usage: "Report progress to stderr as a bar, periodic status lines, or not at all (auto, bar, lines, off)",
+						Value: progressAuto,
+					},
+					&cli.DurationFlag{
+						Name:  "progress-interval",
+						Usage: "Time between status lines when reporting progress as lines",
+						Value: 10 * time.Second,
+					},
+				},
+			},
+			{
+				Name:        "watch",
+				Usage:       "Alert when the carrier, line type or line provider of watched numbers changes",
+				Description: "Looks up the numbers in the watchlist file, one per line, with the carrier, linetype and line_provider data points, then again on every run of the schedule. Alerts are only emitted for numbers whose data changed since they were last checked; the first check of a number is its baseline. The watchlist is read again every cycle, and what was last seen is kept in a state file so restarts pick up where they left off.",
+				Action:      cmdWatch,
+				ArgsUsage:   "<watchlist>",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
-						Name:     "accountsid",
-						Aliases:  []string{"s"},
-						Usage:    "EveryoneAPI Account SID",
+						Name:  "schedule",
+						Usage: "When to check the numbers again, as a cron expression (minute hour day month weekday), @hourly, @daily, @weekly, @monthly, or @every <duration>",
+						Value: "@daily",
+					},
+					&cli.BoolFlag{
+						Name:  "once",
+						Usage: "Check the numbers once and exit",
+					},
+					&cli.Float64Flag{
+						Name:  "budget",
+						Usage: "Stop a cycle before its cost would exceed this many US dollars, checking the rest of the numbers first next cycle; 0 for no limit",
+					},
+					&cli.StringFlag{
+						Name:  "state",
+						Usage: "Keep what was last seen of each number in `FILE` instead of the config directory",
+					},
+					&cli.StringFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Usage:   "Format of the alerts printed to stdout (text, json)",
+						Value:   formatText,
+					},
+					&cli.StringFlag{
+						Name:  "alert-file",
+						Usage: "Also append alerts to `FILE` as JSON lines",
+					},
+					&cli.StringFlag{
+						Name:  "webhook",
+						Usage: "Also deliver alerts to the webhook `URL` as change events, overriding the config",
+					},
+					&cli.StringFlag{
+						Name:    "webhook-secret",
+						Usage:   "Sign webhook deliveries with HMAC-SHA256 using `SECRET`",
+						EnvVars: []string{"WHATPHONE_WEBHOOK_SECRET"},
+					},
+					&cli.StringFlag{
+						Name:  "webhook-dead-letter",
+						Usage: "Append webhook deliveries that fail for good to `FILE`",
+					},
+				},
+			},
+			{
+				Name:        "jobs",
+				Usage:       "List, inspect and resume checkpointed lookup jobs",
+				Description: "Lookups of more than one number, and enrich runs, are checkpointed as they go. Running the same command again with --resume, or resuming its job, skips the numbers it already looked up.",
+				Action:      cmdJobsList,
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "List jobs, most recent first",
+						Action: cmdJobsList,
+					},
+					{
+						Name:      "show",
+						Usage:     "Show the details of a job",
+						ArgsUsage: "<job ID>",
+						Action:    cmdJobsShow,
+					},
+					{
+						Name:      "resume",
+						Usage:     "Run a job's command again, skipping the numbers it already looked up",
+						ArgsUsage: "<job ID>",
+						Action:    cmdJobsResume,
+					},
+				},
+			},
+			{
+				Name:  "webhook",
+				Usage: "Manage webhook delivery of lookup results",
+				Subcommands: []*cli.Command{
+					{
+						Name:        "test",
+						Usage:       "Deliver a test event to a webhook",
+						Description: "Delivers a sample result to the webhook given as an argument, or configured with --webhook or in the config, to check the receiver accepts it. The delivery is made once, without retrying.",
+						ArgsUsage:   "[URL]",
+						Action:      cmdWebhookTest,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "webhook-secret",
+								Usage:   "Sign the delivery with HMAC-SHA256 using `SECRET`",
+								EnvVars: []string{"WHATPHONE_WEBHOOK_SECRET"},
+							},
+						},
+					},
+				},
+			},
+			{
+				Name:   "datapoints",
+				Usage:  "List every data point and preset with its estimated price",
+				Action: cmdDataPoints,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Usage:   "Output format (text, json)",
+						Value:   formatText,
+					},
+				},
+			},
+			{
+				Name:        "init",
+				Usage:       "Initialize the app with your EveryoneAPI credentials",
+				Description: "Credentials not given as flags are prompted for, or read line by line from stdin when it is not a terminal",
+				Action:      cmdInit,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "accountsid",
+						Aliases: []string{"s"},
+						Usage:   "EveryoneAPI Account SID",
+					},
+					&cli.StringFlag{
+						Name:    "authtoken",
+						Aliases: []string{"t"},
+						Usage:   "EveryoneAPI Auth Token",
+					},
+					&cli.StringFlag{
+						Name:  "base-url",
+						Usage: "Send lookups to `URL` instead of EveryoneAPI",
+					},
+					&cli.StringFlag{
+						Name:    "data",
+						Aliases: []string{"d"},
+						Usage:   "Default data points to request, as a comma separated list",
+					},
+					&cli.StringFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Usage:   "Default output format (" + strings.Join(formats, ", ") + ")",
+					},
+					&cli.BoolFlag{
+						Name:  "no-verify",
+						Usage: "Skip verifying the credentials with EveryoneAPI",
+					},
+				},
+			},
+			{
+				Name:        "serve",
+				Usage:       "Serve phone number lookups over HTTP",
+				Description: "Clients look up numbers with GET /v1/lookup/<phone number>?data=name,carrier, authenticating with an API key from the keys file as a bearer token. Prometheus metrics are served at /metrics.",
+				Action:      cmdServe,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "listen",
+						Usage: "Listen on `ADDRESS`",
+						Value: ":8080",
+					},
+					&cli.StringFlag{
+						Name:     "keys",
+						Usage:    "Read client API keys and quotas from JSON `FILE`",
 						Required: true,
 					},
+					&cli.DurationFlag{
+						Name:  "quota-period",
+						Usage: "Period after which client quotas are reset",
+						Value: 24 * time.Hour,
+					},
+					&cli.DurationFlag{
+						Name:  "cache-ttl",
+						Usage: "How long lookup results are cached",
+						Value: 24 * time.Hour,
+					},
+					&cli.IntFlag{
+						Name:  "cache-size",
+						Usage: "Maximum number of cached lookup results; 0 for no limit, -1 to disable caching",
+						Value: 10000,
+					},
+					&cli.DurationFlag{
+						Name:  "shutdown-timeout",
+						Usage: "How long to wait for lookups in progress when shutting down",
+						Value: 10 * time.Second,
+					},
+				},
+			},
+			{
+				Name:        "callerid",
+				Usage:       "Serve caller names to PBX systems over HTTP and FastAGI",
+				Description: "HTTP queries are answered in plain text at /cnam/<phone number>, and FastAGI sessions set CALLERID(name), e.g. AGI(agi://127.0.0.1:4573/?key=KEY). Either --key or --allow is required.",
+				Action:      cmdCallerID,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "http",
+						Usage: "Serve plain text caller ID queries on `ADDRESS`",
+					},
+					&cli.StringFlag{
+						Name:  "agi",
+						Usage: "Serve FastAGI on `ADDRESS`",
+					},
+					&cli.StringFlag{
+						Name:  "key",
+						Usage: "Require HTTP queries and FastAGI URLs to pass `KEY` as the key query parameter",
+					},
+					&cli.StringSliceFlag{
+						Name:  "allow",
+						Usage: "Only answer clients from `ADDRESS`, an IP address or CIDR prefix; may be repeated",
+					},
 					&cli.StringFlag{
-						Name:     "authtoken",
-						Aliases:  []string{"t"},
-						Usage:    "EveryoneAPI Auth Token",
+						Name:  "metrics",
+						Usage: "Serve Prometheus metrics at /metrics on `ADDRESS`",
+					},
+					&cli.DurationFlag{
+						Name:  "timeout",
+						Usage: "Answer without a name when a lookup takes longer than this",
+						Value: 1500 * time.Millisecond,
+					},
+					&cli.IntFlag{
+						Name:  "max-length",
+						Usage: "Truncate caller names to this many characters; 0 for no limit",
+						Value: cnamMaxLength,
+					},
+					&cli.DurationFlag{
+						Name:  "cache-ttl",
+						Usage: "How long caller names are cached",
+						Value: 7 * 24 * time.Hour,
+					},
+					&cli.IntFlag{
+						Name:  "cache-size",
+						Usage: "Maximum number of cached caller names; 0 for no limit",
+						Value: 10000,
+					},
+				},
+			},
+			{
+				Name:        "grpc",
+				Usage:       "Serve phone number lookups over gRPC",
+				Description: "Serves the whatphone.v1.LookupService defined in pkg/rpc/whatphone.proto",
+				Action:      cmdGRPC,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "listen",
+						Usage: "Listen on `ADDRESS`",
+						Value: ":9090",
+					},
+					&cli.StringFlag{
+						Name:     "keys",
+						Usage:    "Require callers to send an API key from JSON `FILE` as a bearer token",
 						Required: true,
 					},
+					&cli.StringFlag{
+						Name:  "metrics",
+						Usage: "Serve Prometheus metrics at /metrics on `ADDRESS`",
+					},
+					&cli.IntFlag{
+						Name:  "concurrency",
+						Usage: "Maximum number of lookups performed at once per LookupMany stream",
+						Value: 4,
+					},
+					&cli.DurationFlag{
+						Name:  "cache-ttl",
+						Usage: "How long lookup results are cached",
+						Value: 24 * time.Hour,
+					},
+					&cli.IntFlag{
+						Name:  "cache-size",
+						Usage: "Maximum number of cached lookup results; 0 for no limit, -1 to disable caching",
+						Value: 10000,
+					},
 				},
 			},
 		},
@@ -166,14 +600,71 @@ func cmdInit(c *cli.Context) error {
 		return err
 	}
 
-	f, err := os.OpenFile(configFile, os.O_RDWR|os.O_CREATE, 0644)
+	p := newPrompter(c.App.Metadata["stdin"].(io.Reader), c.App.Writer)
+
+	cfg := config{API: *whatphone.New(c.String("accountsid"), c.String("authtoken"))}
+	cfg.BaseURL = c.String("base-url")
+
+	// presets and the webhook are only ever edited by hand, so keep any from an existing config
+	if old, err := readConfig(); err == nil {
+		cfg.Presets = old.Presets
+		cfg.Webhook = old.Webhook
+	}
+
+	// only walk through the optional settings when we had to ask for credentials
+	wizard := cfg.AccountSID == "" || cfg.AuthToken == ""
+
+	if cfg.AccountSID == "" {
+		if cfg.AccountSID, err = p.ask("Account SID"); err != nil {
+			return err
+		}
+		if cfg.AccountSID == "" {
+			return fmt.Errorf("missing account SID")
+		}
+	}
+	if cfg.AuthToken == "" {
+		if cfg.AuthToken, err = p.askSecret("Auth Token"); err != nil {
+			return err
+		}
+		if cfg.AuthToken == "" {
+			return fmt.Errorf("missing auth token")
+		}
+	}
+
+	if !c.Bool("no-verify") {
+		if err = verifyCredentials(&cfg.API); err != nil {
+			return fmt.Errorf("unable to verify credentials: %v", err)
+		}
+		fmt.Fprintf(c.App.Writer, "Credentials verified\n")
+	}
+
+	data := c.String("data")
+	if wizard && !c.IsSet("data") {
+		if data, err = p.ask("Default data points (comma separated, blank for none)"); err != nil {
+			return err
+		}
+	}
+	if cfg.Data, err = parseDataPoints(data); err != nil {
+		return err
+	}
+
+	cfg.Format = c.String("format")
+	if wizard && !c.IsSet("format") {
+		if cfg.Format, err = p.ask("Default output format (" + strings.Join(formats, ", ") + ")"); err != nil {
+			return err
+		}
+	}
+	if cfg.Format != "" && !validFormat(cfg.Format) {
+		return fmt.Errorf("unknown output format: %s", cfg.Format)
+	}
+
+	f, err := os.OpenFile(configFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	api := whatphone.New(c.String("accountsid"), c.String("authtoken"))
-	err = json.NewEncoder(f).Encode(api)
+	err = json.NewEncoder(f).Encode(cfg)
 	if err != nil {
 		return err
 	}
@@ -182,137 +673,419 @@ func cmdInit(c *cli.Context) error {
 	return nil
 }
 
+// verifyCredentials performs a lookup on EveryoneAPI's sample number, which is answered with
+// sample data free of charge, to make sure the api's credentials are accepted
+func verifyCredentials(api *whatphone.API) error {
+	_, err := api.Lookup(sampleNumber, whatphone.WithLineType())
+	return err
+}
+
 func cmdLookup(c *cli.Context) error {
-	cr := c.App.Metadata["configReader"].(configReader)
-	reader := cr.reader
-	config, err := reader()
+	config, err := getConfig(c)
+	if err != nil {
+		return err
+	}
 
+	numbers, err := readNumbers(c)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("unable to read config; you may need to run the init command")
-		}
 		return err
 	}
+	if len(numbers) == 0 {
+		return fmt.Errorf("missing phone number")
+	}
+	multi := len(numbers) > 1 || c.IsSet("input")
 
-	if config.AccountSID == "" || config.AuthToken == "" {
-		return fmt.Errorf("authentication strings not set")
+	set, err := requestedDataPoints(c, config)
+	if err != nil {
+		return err
 	}
 
-	if c.NArg() < 1 {
-		return fmt.Errorf("missing phone number")
+	var opts []whatphone.Option
+	if set.Len() > 0 {
+		opts = append(opts, whatphone.WithDataPoints(set.DataPoints()...))
 	}
 
-	opts := make([]whatphone.Option, 0)
-	if c.Bool("name") {
-		opts = append(opts, whatphone.WithName())
+	var near *geo.Place
+	if c.IsSet("near") {
+		place, err := geo.ParsePlace(c.String("near"))
+		if err != nil {
+			return err
+		}
+		near = &place
+		if set.Len() > 0 {
+			opts = append(opts, whatphone.WithLocation())
+		}
 	}
-	if c.Bool("profile") {
-		opts = append(opts, whatphone.WithProfile())
+
+	format := c.String("format")
+	if !c.IsSet("format") && config.Format != "" {
+		format = config.Format
 	}
-	if c.Bool("cnam") {
-		opts = append(opts, whatphone.WithCNAM())
+	if !validFormat(format) {
+		return fmt.Errorf("unknown output format: %s", format)
 	}
-	if c.Bool("gender") {
-		opts = append(opts, whatphone.WithGender())
+	// masked numbers aren't unique, so they can't name files
+	if config.Redaction.MaskNumbers && c.String("vcard-dir") != "" {
+		return fmt.Errorf("--vcard-dir can't be used with the %s redaction rule", whatphone.RedactMaskNumbers)
 	}
-	if c.Bool("image") {
-		opts = append(opts, whatphone.WithImage())
+	vcardDir := c.String("vcard-dir")
+	if vcardDir != "" {
+		if c.IsSet("format") && format != formatVCard {
+			return fmt.Errorf("--vcard-dir can only be used with --format %s", formatVCard)
+		}
+		format = formatVCard
 	}
-	if c.Bool("address") {
-		opts = append(opts, whatphone.WithAddress())
+
+	// in multi mode, each distinct number is only looked up once, whatever form it's written in
+	normalized, invalid, unique := numbers, make([]error, len(numbers)), len(numbers)
+	if multi {
+		normalized, invalid, unique = dedupe(numbers)
+	}
+
+	var j *job
+	var p *progress
+	if multi {
+		if j, err = startJob(c, "lookup", unique, config.Redaction); err != nil {
+			return err
+		}
+		defer j.close()
+		if p, err = newProgress(c, unique); err != nil {
+			return err
+		}
 	}
-	if c.Bool("location") {
-		opts = append(opts, whatphone.WithLocation())
+
+	var sink *sqlite.Sink
+	if path := c.String("sqlite"); path != "" {
+		if sink, err = sqlite.Open(path); err != nil {
+			return err
+		}
+		defer sink.Close()
+	}
+
+	hook, err := newWebhook(c, config)
+	if err != nil {
+		return err
 	}
-	if c.Bool("line-provider") {
-		opts = append(opts, whatphone.WithLineProvider())
+	// results are delivered in the background, so a slow or unreachable receiver doesn't hold up
+	// the lookups
+	var deliveries *webhook.Queue
+	if hook != nil {
+		deliveries = webhook.NewQueue(hook, 100)
+		defer deliveries.Close()
 	}
-	if c.Bool("carrier") {
-		opts = append(opts, whatphone.WithCarrier())
+
+	config.Strict = c.Bool("strict")
+	outs := make([]lookupOutput, 0, len(numbers))
+	results := make(map[string]*whatphone.Result)
+	errs := make(map[string]error)
+	var failed, avoided int
+	for i, phonenumber := range numbers {
+		number := normalized[i]
+		err := invalid[i]
+		if err == nil {
+			err = errs[number]
+		}
+		if err != nil {
+			p.clear()
+			fmt.Fprintf(c.App.ErrWriter, "%s: %v\n", config.Redaction.Number(phonenumber), err)
+			failed++
+			continue
+		}
+
+		result, ok := results[number]
+		if ok {
+			avoided++
+		} else {
+			var resumed bool
+			if result, resumed = j.result(number); resumed {
+				// the job may have been checkpointed before redaction was asked for
+				result = config.Redaction.Apply(result)
+			} else {
+				if result, err = config.Lookup(number, opts...); err != nil {
+					if !multi {
+						return err
+					}
+					p.clear()
+					fmt.Fprintf(c.App.ErrWriter, "%s: %v\n", config.Redaction.Number(phonenumber), err)
+					p.add(nil, err, false)
+					errs[number] = err
+					failed++
+					continue
+				}
+				if err := j.record(number, result); err != nil {
+					return err
+				}
+			}
+			results[number] = result
+			// resumed results were already stored and delivered by the run that looked them up
+			if sink != nil && !resumed {
+				// masked numbers can be shared by many numbers, so those results are stored
+				// under a hash of the number instead
+				key := result.Number
+				if config.Redaction.MaskNumbers {
+					key = config.Redaction.NumberKey(number)
+				}
+				if err := sink.WriteNumber(context.Background(), key, result); err != nil {
+					return err
+				}
+			}
+			if deliveries != nil && !resumed {
+				deliveries.Write(result)
+			}
+
+			if len(result.Warnings) > 0 {
+				p.clear()
+			}
+			for _, w := range result.Warnings {
+				fmt.Fprintf(c.App.ErrWriter, "warning: %s\n", w)
+			}
+			p.add(result, nil, resumed)
+		}
+
+		out := lookupOutput{Result: result}
+		if near != nil {
+			report := geo.Compare(geo.FromLocation(result.Data.Location), *near)
+			out.Near = &report
+		}
+		outs = append(outs, out)
 	}
-	if c.Bool("original-carrier") {
-		opts = append(opts, whatphone.WithOriginalCarrier())
+
+	p.finish()
+	if deliveries != nil {
+		// failed deliveries are kept in the dead-letter file, so they don't fail the lookup
+		for _, err := range deliveries.Close() {
+			fmt.Fprintf(c.App.ErrWriter, "warning: webhook: %v\n", err)
+		}
 	}
-	if c.Bool("linetype") {
-		opts = append(opts, whatphone.WithLineType())
+	if avoided > 0 {
+		fmt.Fprintf(c.App.ErrWriter, "Answered %d duplicate numbers without a paid lookup\n", avoided)
 	}
 
-	if len(opts) == 0 && !c.Bool("all") {
-		return fmt.Errorf("no data points selected; use --all to request all data points")
+	if c.Bool("raw") {
+		for _, out := range outs {
+			if err := writeRaw(c.App.Writer, out.Result); err != nil {
+				return err
+			}
+		}
+	} else if vcardDir != "" {
+		if err := writeVCardFiles(c.App.Writer, vcardDir, outs); err != nil {
+			return err
+		}
+	} else if err := writeResults(c.App.Writer, c.App.ErrWriter, format, outs, c.Bool("pricing-breakdown"), multi); err != nil {
+		return err
 	}
 
-	phonenumber := c.Args().Get(0)
-	result, err := config.Lookup(phonenumber, opts...)
-	if err != nil {
+	if err := j.finish(unique, len(errs)); err != nil {
 		return err
 	}
 
+	if failed > 0 {
+		return fmt.Errorf("%d of %d lookups failed", failed, len(numbers))
+	}
+	return nil
+}
+
+// writeText writes a lookup result in human readable form
+func writeText(w io.Writer, result *whatphone.Result, breakdown bool) error {
 	if result.Data.Name != nil {
-		fmt.Fprintf(c.App.Writer, "Name: %s\n", *result.Data.Name)
+		fmt.Fprintf(w, "Name: %s\n", *result.Data.Name)
 	}
 	if result.Data.Profile != nil {
 		profile := *result.Data.Profile
-		fmt.Fprintf(c.App.Writer, "Profile:\n")
-		fmt.Fprintf(c.App.Writer, "  Edu: %s\n  Job: %s\n  Relationship: %s\n", profile.Edu, profile.Job, profile.Relationship)
+		fmt.Fprintf(w, "Profile:\n")
+		fmt.Fprintf(w, "  Edu: %s\n  Job: %s\n  Relationship: %s\n", profile.Edu, profile.Job, profile.Relationship)
 	}
 	if result.Data.Cnam != nil {
-		fmt.Fprintf(c.App.Writer, "CNAM: %s\n", *result.Data.Cnam)
+		fmt.Fprintf(w, "CNAM: %s\n", *result.Data.Cnam)
 	}
 	if result.Data.Gender != nil {
-		fmt.Fprintf(c.App.Writer, "Gender: %s\n", *result.Data.Gender)
+		fmt.Fprintf(w, "Gender: %s\n", *result.Data.Gender)
 	}
 	if result.Data.Image != nil {
 		image := *result.Data.Image
-		fmt.Fprintf(c.App.Writer, "Image:\n")
-		fmt.Fprintf(c.App.Writer, "  Cover: %s\n  Small: %s\n  Medium: %s\n  Large: %s\n", image.Cover, image.Small, image.Med, image.Large)
+		fmt.Fprintf(w, "Image:\n")
+		fmt.Fprintf(w, "  Cover: %s\n  Small: %s\n  Medium: %s\n  Large: %s\n", image.Cover, image.Small, image.Med, image.Large)
 	}
 	if result.Data.Address != nil {
-		fmt.Fprintf(c.App.Writer, "Address: %s\n", *result.Data.Address)
+		fmt.Fprintf(w, "Address: %s\n", *result.Data.Address)
 	}
 	if result.Data.Location != nil {
 		location := *result.Data.Location
-		fmt.Fprintf(c.App.Writer, "Location:\n")
-		fmt.Fprintf(c.App.Writer, "  City, State, Zip: %s, %s, %s\n", location.City, location.State, location.Zip)
-		fmt.Fprintf(c.App.Writer, "  Lat, Long: %s, %s\n", location.Geo.Latitude, location.Geo.Longitude)
+		fmt.Fprintf(w, "Location:\n")
+		fmt.Fprintf(w, "  City, State, Zip: %s, %s, %s\n", location.City, location.State, location.Zip)
+		fmt.Fprintf(w, "  Lat, Long: %s, %s\n", location.Geo.Latitude, location.Geo.Longitude)
 	}
 	if result.Data.LineProvider != nil {
 		lineprovider := *result.Data.LineProvider
-		fmt.Fprintf(c.App.Writer, "Line Provider:\n")
-		fmt.Fprintf(c.App.Writer, "  ID: %s\n  Name: %s\n  MMS E-mail: %s\n  SMS E-mail: %s\n", lineprovider.ID, lineprovider.Name, lineprovider.MmsEmail, lineprovider.SmsEmail)
+		fmt.Fprintf(w, "Line Provider:\n")
+		fmt.Fprintf(w, "  ID: %s\n  Name: %s\n  MMS E-mail: %s\n  SMS E-mail: %s\n", lineprovider.ID, lineprovider.Name, lineprovider.MmsEmail, lineprovider.SmsEmail)
 	}
 	if result.Data.Carrier != nil {
 		carrier := *result.Data.Carrier
-		fmt.Fprintf(c.App.Writer, "Carrier:\n")
-		fmt.Fprintf(c.App.Writer, "  ID: %s\n  Name: %s\n", carrier.ID, carrier.Name)
+		fmt.Fprintf(w, "Carrier:\n")
+		fmt.Fprintf(w, "  ID: %s\n  Name: %s\n", carrier.ID, carrier.Name)
 	}
 	if result.Data.CarrierO != nil {
 		carriero := *result.Data.CarrierO
-		fmt.Fprintf(c.App.Writer, "Original Carrier:\n")
-		fmt.Fprintf(c.App.Writer, "  ID: %s\n  Name: %s\n", carriero.ID, carriero.Name)
+		fmt.Fprintf(w, "Original Carrier:\n")
+		fmt.Fprintf(w, "  ID: %s\n  Name: %s\n", carriero.ID, carriero.Name)
 	}
 	if result.Data.Linetype != nil {
-		fmt.Fprintf(c.App.Writer, "Linetype: %s\n", *result.Data.Linetype)
+		fmt.Fprintf(w, "Linetype: %s\n", *result.Data.Linetype)
+	}
+	for _, name := range sortedKeys(result.Data.Extra) {
+		fmt.Fprintf(w, "%s: %s\n", name, result.Data.Extra[name])
 	}
 	if result.Note != "" {
-		fmt.Fprintf(c.App.Writer, "Note: %s\n", result.Note)
-	}
-	fmt.Fprintf(c.App.Writer, "Price Total: %.4f\n", result.Pricing.Total)
-	if c.Bool("pricing-breakdown") {
-		fmt.Fprintf(c.App.Writer, "  Name: %.4f\n", result.Pricing.Breakdown.Name)
-		fmt.Fprintf(c.App.Writer, "  Profile: %.4f\n", result.Pricing.Breakdown.Profile)
-		fmt.Fprintf(c.App.Writer, "  CNAM: %.4f\n", result.Pricing.Breakdown.Cnam)
-		fmt.Fprintf(c.App.Writer, "  Gender: %.4f\n", result.Pricing.Breakdown.Gender)
-		fmt.Fprintf(c.App.Writer, "  Image: %.4f\n", result.Pricing.Breakdown.Image)
-		fmt.Fprintf(c.App.Writer, "  Address: %.4f\n", result.Pricing.Breakdown.Address)
-		fmt.Fprintf(c.App.Writer, "  Location: %.4f\n", result.Pricing.Breakdown.Location)
-		fmt.Fprintf(c.App.Writer, "  Line Provider: %.4f\n", result.Pricing.Breakdown.LineProvider)
-		fmt.Fprintf(c.App.Writer, "  Carrier: %.4f\n", result.Pricing.Breakdown.Carrier)
-		fmt.Fprintf(c.App.Writer, "  Original Carrier: %.4f\n", result.Pricing.Breakdown.Carrier0)
-		fmt.Fprintf(c.App.Writer, "  Linetype: %.4f\n", result.Pricing.Breakdown.Linetype)
+		fmt.Fprintf(w, "Note: %s\n", result.Note)
+	}
+	fmt.Fprintf(w, "Price Total: %.4f\n", result.Pricing.Total)
+	if breakdown {
+		fmt.Fprintf(w, "  Name: %.4f\n", result.Pricing.Breakdown.Name)
+		fmt.Fprintf(w, "  Profile: %.4f\n", result.Pricing.Breakdown.Profile)
+		fmt.Fprintf(w, "  CNAM: %.4f\n", result.Pricing.Breakdown.Cnam)
+		fmt.Fprintf(w, "  Gender: %.4f\n", result.Pricing.Breakdown.Gender)
+		fmt.Fprintf(w, "  Image: %.4f\n", result.Pricing.Breakdown.Image)
+		fmt.Fprintf(w, "  Address: %.4f\n", result.Pricing.Breakdown.Address)
+		fmt.Fprintf(w, "  Location: %.4f\n", result.Pricing.Breakdown.Location)
+		fmt.Fprintf(w, "  Line Provider: %.4f\n", result.Pricing.Breakdown.LineProvider)
+		fmt.Fprintf(w, "  Carrier: %.4f\n", result.Pricing.Breakdown.Carrier)
+		fmt.Fprintf(w, "  Original Carrier: %.4f\n", result.Pricing.Breakdown.Carrier0)
+		fmt.Fprintf(w, "  Linetype: %.4f\n", result.Pricing.Breakdown.Linetype)
 	}
 
 	if len(result.Missed) > 0 {
-		fmt.Fprintf(c.App.Writer, "\nMissed: %s\n", strings.Join(result.Missed, ", "))
+		fmt.Fprintf(w, "\nMissed: %s\n", strings.Join(result.Missed, ", "))
+	}
+
+	return nil
+}
+
+// getConfig reads the config, making sure it holds credentials, and applies the global flags to its api
+func getConfig(c *cli.Context) (*config, error) {
+	cr := c.App.Metadata["configReader"].(configReader)
+	reader := cr.reader
+	config, err := reader()
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("unable to read config; you may need to run the init command")
+		}
+		return nil, err
+	}
+
+	if config.AccountSID == "" || config.AuthToken == "" {
+		return nil, fmt.Errorf("authentication strings not set")
+	}
+
+	if err := setRedaction(c, &config.API); err != nil {
+		return nil, err
+	}
+	if err := setTransport(c, &config.API); err != nil {
+		return nil, err
+	}
+	setLogger(c, &config.API)
+	if err := setTracer(c, &config.API); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// setLogger makes the api log its requests to stderr when requested by the global flags
+func setLogger(c *cli.Context, api *whatphone.API) {
+	var level slog.Level
+	switch {
+	case c.Bool("debug"):
+		level = slog.LevelDebug
+	case c.Bool("verbose"):
+		level = slog.LevelInfo
+	default:
+		return
+	}
+
+	api.Logger = slog.New(slog.NewTextHandler(c.App.ErrWriter, &slog.HandlerOptions{Level: level}))
+}
+
+// setRedaction makes the api redact its results and logs as requested by the global flags
+func setRedaction(c *cli.Context, api *whatphone.API) error {
+	r, err := parseRedaction(c)
+	if err != nil {
+		return err
+	}
+	api.Redaction = r
+	return nil
+}
+
+// parseRedaction returns the redaction asked for by the global flags. Hashes of names, and of the
+// numbers kept in checkpoints and watch state, are keyed with --redact-secret, or else with a
+// secret kept in the config directory, so they can be matched up across runs.
+func parseRedaction(c *cli.Context) (whatphone.Redaction, error) {
+	r, err := whatphone.ParseRedaction(c.String("redact"))
+	if err != nil || (!r.HashNames && !r.MaskNumbers) {
+		return r, err
+	}
+
+	if r.Secret = c.String("redact-secret"); r.Secret == "" {
+		if r.Secret, err = redactionSecret(); err != nil {
+			return whatphone.Redaction{}, fmt.Errorf("unable to read redaction secret: %v", err)
+		}
+	}
+	return r, nil
+}
+
+// redactionSecret returns the secret kept in the config directory, generating it the first time
+// it's needed
+func redactionSecret() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(configDir, "whatphone")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "redaction-secret")
+
+	b, err := os.ReadFile(path)
+	if err == nil && len(bytes.TrimSpace(b)) > 0 {
+		return string(bytes.TrimSpace(b)), nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	s := hex.EncodeToString(secret)
+	if err := os.WriteFile(path, []byte(s+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+// setTransport makes the api record or replay fixtures when requested by the global flags
+func setTransport(c *cli.Context, api *whatphone.API) error {
+	record, replay := c.String("record"), c.String("replay")
+
+	switch {
+	case record != "" && replay != "":
+		return fmt.Errorf("--record and --replay cannot be used together")
+	case record != "":
+		opts := []fixture.RecorderOption{fixture.ScrubAuth()}
+		// fixtures hold raw responses, which can only be scrubbed wholesale
+		if c.Bool("scrub-pii") || !api.Redaction.IsZero() {
+			opts = append(opts, fixture.ScrubPII())
+		}
+		var next http.RoundTripper
+		if api.Client != nil {
+			next = api.Client.Transport
+		}
+		api.Client = &http.Client{Transport: fixture.NewRecorder(record, next, opts...)}
+	case replay != "":
+		api.Client = &http.Client{Transport: fixture.NewReplayer(replay)}
 	}
 
 	return nil
@@ -336,21 +1109,21 @@ func getConfigFile() (string, error) {
 	return appDir + "/config.json", nil
 }
 
-// loadConfig loads a config from a reader and returns an api object
-func loadConfig(r io.Reader) (*whatphone.API, error) {
-	var config whatphone.API
+// loadConfig loads a config from a reader
+func loadConfig(r io.Reader) (*config, error) {
+	var cfg config
 	var err error
 
-	err = json.NewDecoder(r).Decode(&config)
+	err = json.NewDecoder(r).Decode(&cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	return &config, nil
+	return &cfg, nil
 }
 
-// readConfig gets the config location, opens it, and returns an api object
-func readConfig() (*whatphone.API, error) {
+// readConfig gets the config location, opens it, and returns the config
+func readConfig() (*config, error) {
 	configFile, err := getConfigFile()
 	if err != nil {
 		return nil, err
@@ -364,3 +1137,26 @@ func readConfig() (*whatphone.API, error) {
 
 	return loadConfig(f)
 }
+
+// parseDataPoints splits a comma separated list of data point names, making sure each one is known
+func parseDataPoints(s string) ([]string, error) {
+	set, err := whatphone.ParseDataPoints(s)
+	if err != nil || set.Len() == 0 {
+		return nil, err
+	}
+	return set.Strings(), nil
+}
+
+// dataPointOptions returns the lookup options for a list of data point names, skipping unknown names
+func dataPointOptions(names []string) []whatphone.Option {
+	var set whatphone.DataPointSet
+	for _, name := range names {
+		if dp, err := whatphone.ParseDataPoint(name); err == nil {
+			set.Add(dp)
+		}
+	}
+	if set.Len() == 0 {
+		return nil
+	}
+	return []whatphone.Option{whatphone.WithDataPoints(set.DataPoints()...)}
+}
@@ -2,20 +2,52 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+	"samhofi.us/x/whatphone/pkg/sqlite"
 )
 
-func testReadConfig() (*whatphone.API, error) {
-	return &whatphone.API{
-		AccountSID: "test",
-		AuthToken:  "test",
+// TestMain keeps the job checkpoints written by tests out of the real config directory
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "whatphone-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func testReadConfig() (*config, error) {
+	return &config{
+		API: whatphone.API{
+			AccountSID: "test",
+			AuthToken:  "test",
+		},
 	}, nil
 }
 
+// testServerConfig returns a config func for performing lookups against a fake EveryoneAPI
+func testServerConfig(srv *apitest.Server) configFunc {
+	return func() (*config, error) {
+		return &config{API: *srv.API()}, nil
+	}
+}
+
 func TestLookup(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
 	lookups := []struct {
 		args     []string
 		expected string
@@ -97,7 +129,7 @@ Price Total: -0.0050
 
 	for _, lookup := range lookups {
 		var stdout bytes.Buffer
-		err := run(lookup.args, &stdout, newConfigReader(testReadConfig))
+		err := run(lookup.args, strings.NewReader(""), &stdout, io.Discard, newConfigReader(testServerConfig(srv)))
 		if err != nil {
 			t.Errorf("%v returned error: %v", lookup.args, err)
 		}
@@ -121,11 +153,19 @@ func TestErrors(t *testing.T) {
 			[]string{"whatphone", "lookup"},
 			errors.New("missing phone number"),
 		},
+		{
+			[]string{"whatphone", "lookup", "-d", "name,spam_score", "15551234567"},
+			errors.New("unknown data point: spam_score"),
+		},
+		{
+			[]string{"whatphone", "lookup", "--preset", "marketing", "15551234567"},
+			errors.New("unknown preset: marketing"),
+		},
 	}
 
 	for _, lookup := range lookups {
 		var stdout bytes.Buffer
-		err := run(lookup.args, &stdout, newConfigReader(testReadConfig))
+		err := run(lookup.args, strings.NewReader(""), &stdout, io.Discard, newConfigReader(testReadConfig))
 
 		// we expect all of these to return an error
 		if err == nil {
@@ -136,3 +176,397 @@ func TestErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	srv := apitest.NewServer()
+	cr := newConfigReader(testServerConfig(srv))
+
+	var recorded bytes.Buffer
+	args := []string{"whatphone", "--record", dir, "lookup", "-nc", "15551234567"}
+	if err := run(args, strings.NewReader(""), &recorded, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	srv.Close()
+
+	var replayed bytes.Buffer
+	args = []string{"whatphone", "--replay", dir, "lookup", "-nc", "15551234567"}
+	if err := run(args, strings.NewReader(""), &replayed, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if replayed.String() != recorded.String() {
+		t.Errorf("%v returned unexpected output.\nExpected: %s\nGot: %s\n", args, recorded.String(), replayed.String())
+	}
+
+	args = []string{"whatphone", "--record", dir, "--replay", dir, "lookup", "-n", "15551234567"}
+	if err := run(args, strings.NewReader(""), &replayed, io.Discard, cr); err == nil {
+		t.Errorf("%v should have returned an error but didn't", args)
+	}
+}
+
+func TestRaw(t *testing.T) {
+	srv := apitest.NewServer(apitest.WithExtraData(apitest.SampleNumber, "spam_score", 7))
+	defer srv.Close()
+	cr := newConfigReader(testServerConfig(srv))
+
+	var stdout bytes.Buffer
+	args := []string{"whatphone", "lookup", "--raw", "-n", "+15551234567"}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	res, err := srv.API().Lookup(apitest.SampleNumber, whatphone.WithName())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if stdout.String() != string(res.Raw) {
+		t.Errorf("%v returned unexpected output.\nExpected: %s\nGot: %s\n", args, res.Raw, stdout.String())
+	}
+
+	// data points unknown to this package are still shown
+	stdout.Reset()
+	args = []string{"whatphone", "lookup", "--all", "+15551234567"}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if !strings.Contains(stdout.String(), "Linetype: mobile\nspam_score: 7\n") {
+		t.Errorf("%v is missing the unknown data point:\n%s", args, stdout.String())
+	}
+}
+
+func TestStrict(t *testing.T) {
+	srv := apitest.NewServer(apitest.WithExtraData(apitest.SampleNumber, "spam_score", 7))
+	defer srv.Close()
+	cr := newConfigReader(testServerConfig(srv))
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"whatphone", "lookup", "--strict", "--all", "+15551234567"}
+	if err := run(args, strings.NewReader(""), &stdout, &stderr, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	expected := "warning: data.spam_score: unknown field (integer)\n"
+	if stderr.String() != expected {
+		t.Errorf("%v returned unexpected warnings.\nExpected: %s\nGot: %s\n", args, expected, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Name: Michael Seaver") {
+		t.Errorf("%v should still print the result:\n%s", args, stdout.String())
+	}
+
+	stderr.Reset()
+	args = []string{"whatphone", "lookup", "--all", "+15551234567"}
+	if err := run(args, strings.NewReader(""), io.Discard, &stderr, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("%v should not validate the response:\n%s", args, stderr.String())
+	}
+}
+
+func TestVerbose(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	logs := []struct {
+		flag     string
+		expected []string
+		hidden   []string
+	}{
+		{"--verbose", []string{"+15551234567?data=name", "status=200", "duration="}, []string{"Michael Seaver", "test"}},
+		{"--debug", []string{"+15551234567?data=name", "status=200", "Michael Seaver"}, []string{"Authorization"}},
+	}
+
+	for _, l := range logs {
+		var stdout, stderr bytes.Buffer
+		args := []string{"whatphone", l.flag, "lookup", "-n", "+15551234567"}
+		if err := run(args, strings.NewReader(""), &stdout, &stderr, newConfigReader(testServerConfig(srv))); err != nil {
+			t.Fatalf("%v returned error: %v", args, err)
+		}
+
+		out := stderr.String()
+		for _, e := range l.expected {
+			if !strings.Contains(out, e) {
+				t.Errorf("%v is missing %q from stderr:\n%s", args, e, out)
+			}
+		}
+		for _, h := range l.hidden {
+			if strings.Contains(out, h) {
+				t.Errorf("%v shouldn't log %q to stderr:\n%s", args, h, out)
+			}
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+	cr := newConfigReader(testServerConfig(srv))
+
+	for _, format := range formats {
+		var stdout, stderr bytes.Buffer
+		args := []string{"whatphone", "--redact", "all", "--debug", "lookup", "-a", "-f", format, "15551234567"}
+		if err := run(args, strings.NewReader(""), &stdout, &stderr, cr); err != nil {
+			t.Fatalf("%v returned error: %v", args, err)
+		}
+
+		for _, leak := range []string{"5551234567", "Seaver", "SEAVER", "Robin Hood", "teloimg"} {
+			if strings.Contains(stdout.String(), leak) || strings.Contains(stderr.String(), leak) {
+				t.Errorf("%v leaked %q:\n%s\n%s", args, leak, stdout.String(), stderr.String())
+			}
+		}
+		if !strings.Contains(stdout.String(), "Long Island") {
+			t.Errorf("%v dropped data that isn't personal:\n%s", args, stdout.String())
+		}
+	}
+
+	var stdout bytes.Buffer
+	args := []string{"whatphone", "--redact", "mask-numbers", "lookup", "-n", "--raw", "15551234567"}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if !strings.Contains(stdout.String(), `"number":"+*******4567"`) || !strings.Contains(stdout.String(), "Michael Seaver") {
+		t.Errorf("%v returned unexpected output: %s", args, stdout.String())
+	}
+}
+
+func TestRedactSecret(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+	cr := newConfigReader(testServerConfig(srv))
+
+	name := func(extra ...string) string {
+		args := append([]string{"whatphone", "--redact", "hash-names"}, extra...)
+		args = append(args, "lookup", "-d", "name", "-f", "json", "15551234567")
+		var stdout bytes.Buffer
+		if err := run(args, strings.NewReader(""), &stdout, io.Discard, cr); err != nil {
+			t.Fatalf("%v returned error: %v", args, err)
+		}
+		var result whatphone.Result
+		if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+			t.Fatalf("%v returned invalid JSON (%v): %s", args, err, stdout.String())
+		}
+		return result.Data.GetName()
+	}
+
+	// the generated secret is kept, so names hash the same across runs
+	first := name()
+	if !strings.HasPrefix(first, "hmac-sha256:") || name() != first {
+		t.Errorf("Names should hash the same across runs. Got: %s", first)
+	}
+	configDir, _ := os.UserConfigDir()
+	info, err := os.Stat(filepath.Join(configDir, "whatphone", "redaction-secret"))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("The redaction secret wasn't kept privately: %v %v", info, err)
+	}
+
+	if name("--redact-secret", "other") == first {
+		t.Errorf("--redact-secret should change the hash")
+	}
+}
+
+func TestRedactErrors(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"whatphone", "--redact", "blur", "lookup", "15551234567"}, "unknown redaction rule: blur"},
+		{[]string{"whatphone", "--redact", "mask-numbers", "lookup", "-n", "--vcard-dir", dir, "15551234567"}, "--vcard-dir can't be used with the mask-numbers redaction rule"},
+	}
+
+	for _, test := range tests {
+		err := run(test.args, strings.NewReader(""), io.Discard, io.Discard, newConfigReader(testServerConfig(srv)))
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%v returned unexpected error.\nExpected: %s\nGot: %v\n", test.args, test.expected, err)
+		}
+	}
+}
+
+func TestInit(t *testing.T) {
+	srv := apitest.NewServer(apitest.WithCredentials("sid", "token"))
+	defer srv.Close()
+
+	inits := []struct {
+		args     []string
+		stdin    string
+		expected config
+	}{
+		{
+			[]string{"whatphone", "init", "--base-url", srv.URL},
+			"sid\ntoken\n",
+			config{API: whatphone.API{AccountSID: "sid", AuthToken: "token", BaseURL: srv.URL}},
+		},
+		{
+			[]string{"whatphone", "init", "--base-url", srv.URL},
+			"sid\ntoken\nName, carrier\njson\n",
+			config{
+				API:    whatphone.API{AccountSID: "sid", AuthToken: "token", BaseURL: srv.URL},
+				Data:   []string{"name", "carrier"},
+				Format: "json",
+			},
+		},
+		{
+			[]string{"whatphone", "init", "-s", "sid", "-t", "token", "--base-url", srv.URL, "-d", "linetype"},
+			"",
+			config{API: whatphone.API{AccountSID: "sid", AuthToken: "token", BaseURL: srv.URL}, Data: []string{"linetype"}},
+		},
+		{
+			[]string{"whatphone", "init", "-s", "other", "--no-verify"},
+			"secret\n",
+			config{API: whatphone.API{AccountSID: "other", AuthToken: "secret"}},
+		},
+	}
+
+	for _, init := range inits {
+		t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+		var stdout bytes.Buffer
+		err := run(init.args, strings.NewReader(init.stdin), &stdout, io.Discard, newConfigReader(testReadConfig))
+		if err != nil {
+			t.Errorf("%v returned error: %v", init.args, err)
+			continue
+		}
+
+		cfg, err := readConfig()
+		if err != nil {
+			t.Errorf("%v wrote unreadable config: %v", init.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(*cfg, init.expected) {
+			t.Errorf("%v wrote unexpected config.\nExpected: %+v\nGot: %+v\n", init.args, init.expected, *cfg)
+		}
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	srv := apitest.NewServer(apitest.WithCredentials("sid", "token"))
+	defer srv.Close()
+
+	inits := []struct {
+		args     []string
+		stdin    string
+		expected error
+	}{
+		{
+			[]string{"whatphone", "init", "--base-url", srv.URL},
+			"sid\nbad\n",
+			errors.New("unable to verify credentials: 401 Unauthorized"),
+		},
+		{
+			[]string{"whatphone", "init"},
+			"",
+			errors.New("missing account SID"),
+		},
+		{
+			[]string{"whatphone", "init", "-s", "sid", "-t", "token", "--no-verify", "-d", "name,shoe_size"},
+			"",
+			errors.New("unknown data point: shoe_size"),
+		},
+		{
+			[]string{"whatphone", "init", "-s", "sid", "-t", "token", "--no-verify", "-f", "xml"},
+			"",
+			errors.New("unknown output format: xml"),
+		},
+	}
+
+	for _, init := range inits {
+		dir := t.TempDir()
+		t.Setenv("XDG_CONFIG_HOME", dir)
+
+		var stdout bytes.Buffer
+		err := run(init.args, strings.NewReader(init.stdin), &stdout, io.Discard, newConfigReader(testReadConfig))
+		if err == nil {
+			t.Errorf("%v should have returned an error but didn't", init.args)
+			continue
+		}
+		if err.Error() != init.expected.Error() {
+			t.Errorf("%v returned unexpected error.\nExpected: %s\nGot: %s\n", init.args, init.expected.Error(), err.Error())
+		}
+		if _, err := os.Stat(filepath.Join(dir, "whatphone", "config.json")); !os.IsNotExist(err) {
+			t.Errorf("%v wrote a config despite failing", init.args)
+		}
+	}
+}
+
+func TestLookupSQLite(t *testing.T) {
+	srv := multiServer()
+	defer srv.Close()
+	cr := newConfigReader(testServerConfig(srv))
+	path := filepath.Join(t.TempDir(), "lookups.db")
+
+	for _, args := range [][]string{
+		{"whatphone", "lookup", "-n", "--sqlite", path, "15551234567"},
+		{"whatphone", "lookup", "-n", "-c", "--sqlite", path, "15551234567", "555-765-4321", "5557654321"},
+	} {
+		if err := run(args, strings.NewReader(""), io.Discard, io.Discard, cr); err != nil {
+			t.Fatalf("%v returned error: %v", args, err)
+		}
+	}
+
+	sink, err := sqlite.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	rows, err := sink.DB().Query(`SELECT l.number, coalesce(l.name, ''), coalesce(c.name, '') FROM lookups l
+		LEFT JOIN carriers c ON c.id = l.carrier_id ORDER BY l.number`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var got []string
+	for rows.Next() {
+		var number, name, carrier string
+		if err := rows.Scan(&number, &name, &carrier); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, number+"|"+name+"|"+carrier)
+	}
+	expected := []string{"+15551234567|Michael Seaver|Growing Wireless Inc.", "+15557654321|Jane Doe|"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Error: Unexpected stored lookups. Got: %v, Want: %v", got, expected)
+	}
+}
+
+func TestLookupSQLiteMasked(t *testing.T) {
+	srv := multiServer()
+	defer srv.Close()
+	cr := newConfigReader(testServerConfig(srv))
+	path := filepath.Join(t.TempDir(), "lookups.db")
+
+	// both numbers are masked to +*******4567
+	args := []string{"whatphone", "--redact", "mask-numbers", "lookup", "-n", "--sqlite", path, "15551234567", "15559994567"}
+	if err := run(args, strings.NewReader(""), io.Discard, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+
+	sink, err := sqlite.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	rows, err := sink.DB().Query(`SELECT number FROM lookups`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var got []string
+	for rows.Next() {
+		var number string
+		if err := rows.Scan(&number); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(number, "hmac-sha256:") {
+			t.Errorf("Error: lookup stored under an unhashed number: %s", number)
+		}
+		got = append(got, number)
+	}
+	if len(got) != 2 || got[0] == got[1] {
+		t.Errorf("Error: numbers sharing their last digits weren't stored apart. Got: %v", got)
+	}
+}
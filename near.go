@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"samhofi.us/x/whatphone/pkg/geo"
+)
+
+// writeNearText writes a distance report in human readable form
+func writeNearText(w io.Writer, r geo.Report) error {
+	fmt.Fprintf(w, "Near %s:\n", placeName(r.Near))
+
+	if r.Distance != nil {
+		fmt.Fprintf(w, "  Distance: %.1f mi (%.1f km)\n", r.Miles(), *r.Distance)
+	} else {
+		fmt.Fprintf(w, "  Distance: unknown\n")
+	}
+
+	var err error
+	switch {
+	case r.Location.State == "":
+		_, err = fmt.Fprintf(w, "  State: unknown\n")
+	case r.StateMismatch:
+		_, err = fmt.Fprintf(w, "  State: %s, not %s (mismatch)\n", r.Location.State, r.Near.State)
+	default:
+		_, err = fmt.Fprintf(w, "  State: %s\n", r.Location.State)
+	}
+	return err
+}
+
+// placeName describes a place by its ZIP code or coordinates, along with its state when known
+func placeName(p geo.Place) string {
+	name := p.ZIP
+	if name == "" && p.Point != nil {
+		name = p.Point.String()
+	}
+	if p.State != "" {
+		name += " (" + p.State + ")"
+	}
+	return name
+}
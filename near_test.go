@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func TestNear(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+	cr := newConfigReader(testServerConfig(srv))
+
+	lookups := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			[]string{"whatphone", "lookup", "-n", "--near", "10001", "15551234567"},
+			`Name: Michael Seaver
+Location:
+  City, State, Zip: Long Island, NY, 10003
+  Lat, Long: 40.799787, -73.971421
+Note: THIS IS A SAMPLE, YOU WILL NOT BE CHARGED
+Price Total: -0.0150
+Near 10001 (NY):
+  Distance: 3.7 mi (5.9 km)
+  State: NY
+`,
+		},
+		{
+			[]string{"whatphone", "lookup", "-l", "--near", "34.06,-118.24", "15551234567"},
+			`Location:
+  City, State, Zip: Long Island, NY, 10003
+  Lat, Long: 40.799787, -73.971421
+Note: THIS IS A SAMPLE, YOU WILL NOT BE CHARGED
+Price Total: -0.0050
+Near 34.060000,-118.240000 (CA):
+  Distance: 2446.6 mi (3937.4 km)
+  State: NY, not CA (mismatch)
+`,
+		},
+		{
+			[]string{"whatphone", "lookup", "-l", "--near", "07030", "15551234567"},
+			`Location:
+  City, State, Zip: Long Island, NY, 10003
+  Lat, Long: 40.799787, -73.971421
+Note: THIS IS A SAMPLE, YOU WILL NOT BE CHARGED
+Price Total: -0.0050
+Near 07030 (NJ):
+  Distance: unknown
+  State: NY, not NJ (mismatch)
+`,
+		},
+	}
+
+	for _, lookup := range lookups {
+		var stdout bytes.Buffer
+		if err := run(lookup.args, strings.NewReader(""), &stdout, io.Discard, cr); err != nil {
+			t.Errorf("%v returned error: %v", lookup.args, err)
+			continue
+		}
+		if stdout.String() != lookup.expected {
+			t.Errorf("%v returned unexpected output.\nExpected: %s\nGot: %s\n", lookup.args, lookup.expected, stdout.String())
+		}
+	}
+}
+
+func TestNearJSON(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	var stdout bytes.Buffer
+	args := []string{"whatphone", "lookup", "-f", "json", "-n", "--near", "90012", "15551234567"}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, newConfigReader(testServerConfig(srv))); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+
+	var out struct {
+		Number string
+		Data   struct {
+			Name string
+		}
+		Near struct {
+			Near struct {
+				ZIP   string
+				State string
+			}
+			DistanceKm    float64 `json:"distance_km"`
+			StateMismatch bool    `json:"state_mismatch"`
+		}
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		t.Fatalf("%v returned invalid JSON: %v", args, err)
+	}
+	if out.Number != "+15551234567" || out.Data.Name != "Michael Seaver" {
+		t.Errorf("%v is missing the lookup result:\n%s", args, stdout.String())
+	}
+	if out.Near.Near.ZIP != "90012" || out.Near.Near.State != "CA" || out.Near.DistanceKm < 3900 || !out.Near.StateMismatch {
+		t.Errorf("%v returned an unexpected report: %+v", args, out.Near)
+	}
+}
+
+func TestNearErrors(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	lookups := []struct {
+		args     []string
+		expected error
+	}{
+		{[]string{"whatphone", "lookup", "-n", "--near", "nowhere", "15551234567"}, errors.New("invalid ZIP code: nowhere")},
+		{[]string{"whatphone", "lookup", "-n", "--near", "95,0", "15551234567"}, errors.New("invalid latitude: 95")},
+	}
+
+	for _, lookup := range lookups {
+		err := run(lookup.args, strings.NewReader(""), io.Discard, io.Discard, newConfigReader(testServerConfig(srv)))
+		if err == nil || err.Error() != lookup.expected.Error() {
+			t.Errorf("%v returned unexpected error.\nExpected: %v\nGot: %v\n", lookup.args, lookup.expected, err)
+		}
+	}
+	if srv.Requests() != 0 {
+		t.Errorf("Error: invalid --near values shouldn't perform a lookup")
+	}
+}
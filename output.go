@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/geo"
+)
+
+// Output formats supported by the lookup command
+const (
+	formatText    = "text"
+	formatJSON    = "json"
+	formatGeoJSON = "geojson"
+	formatKML     = "kml"
+	formatVCard   = "vcard"
+)
+
+var formats = []string{formatText, formatJSON, formatGeoJSON, formatKML, formatVCard}
+
+// lookupOutput is a lookup result along with anything worked out from it for output
+type lookupOutput struct {
+	*whatphone.Result
+
+	// Near compares the result's location with the place given by --near, if any
+	Near *geo.Report `json:"near,omitempty"`
+}
+
+// validFormat reports whether format is a supported output format
+func validFormat(format string) bool {
+	for _, f := range formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// writeResults writes lookup results to w in the given format. When multi is false, the single
+// result is written on its own rather than as a list. Results that can't be written in the format,
+// such as results without a location on a map, are reported to errw and skipped.
+func writeResults(w, errw io.Writer, format string, outs []lookupOutput, breakdown, multi bool) error {
+	switch format {
+	case formatText:
+		for i, out := range outs {
+			if multi {
+				if i > 0 {
+					fmt.Fprintf(w, "\n")
+				}
+				fmt.Fprintf(w, "Number: %s\n", out.Number)
+			}
+			if err := writeText(w, out.Result, breakdown); err != nil {
+				return err
+			}
+			if out.Near != nil {
+				if err := writeNearText(w, *out.Near); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if !multi && len(outs) == 1 {
+			return enc.Encode(outs[0])
+		}
+		return enc.Encode(outs)
+	case formatGeoJSON:
+		return writeGeoJSON(w, errw, outs)
+	case formatKML:
+		return writeKML(w, errw, outs)
+	case formatVCard:
+		return writeVCards(w, outs)
+	}
+	return fmt.Errorf("unknown output format: %s", format)
+}
+
+// writeRaw writes the untouched response body a lookup result was decoded from
+func writeRaw(w io.Writer, result *whatphone.Result) error {
+	if len(result.Raw) == 0 {
+		return fmt.Errorf("raw response is not available")
+	}
+
+	raw := result.Raw
+	if raw[len(raw)-1] != '\n' {
+		raw = append(raw[:len(raw):len(raw)], '\n')
+	}
+	_, err := w.Write(raw)
+	return err
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,235 @@
+package whatphone // import "samhofi.us/x/whatphone/pkg/api"
+
+import (
+	"strconv"
+	"strings"
+)
+
+// LineType is the kind of line a phone number is assigned to
+type LineType int
+
+const (
+	// LineTypeUnknown means the line type wasn't returned, or isn't one this package recognizes
+	LineTypeUnknown LineType = iota
+	LineTypeMobile
+	LineTypeLandline
+	LineTypeVoIP
+	LineTypeTollFree
+	LineTypePremium
+	LineTypePager
+)
+
+// String returns the name of a LineType
+func (l LineType) String() string {
+	switch l {
+	case LineTypeMobile:
+		return "mobile"
+	case LineTypeLandline:
+		return "landline"
+	case LineTypeVoIP:
+		return "voip"
+	case LineTypeTollFree:
+		return "toll-free"
+	case LineTypePremium:
+		return "premium"
+	case LineTypePager:
+		return "pager"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseLineType converts a line type as returned by EveryoneAPI into a LineType. Matching ignores
+// case, spaces, hyphens and underscores, so "Toll Free", "toll-free" and "tollfree" are all
+// LineTypeTollFree.
+func ParseLineType(s string) LineType {
+	s = strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(s)))
+	switch s {
+	case "mobile", "wireless", "cell", "cellular":
+		return LineTypeMobile
+	case "landline", "fixed", "fixedline":
+		return LineTypeLandline
+	case "voip", "fixedvoip", "nonfixedvoip":
+		return LineTypeVoIP
+	case "tollfree":
+		return LineTypeTollFree
+	case "premium", "premiumrate":
+		return LineTypePremium
+	case "pager":
+		return LineTypePager
+	default:
+		return LineTypeUnknown
+	}
+}
+
+// Gender is the gender associated with a phone number
+type Gender int
+
+const (
+	// GenderUnknown means the gender wasn't returned, or isn't one this package recognizes
+	GenderUnknown Gender = iota
+	GenderMale
+	GenderFemale
+)
+
+// String returns the name of a Gender
+func (g Gender) String() string {
+	switch g {
+	case GenderMale:
+		return "male"
+	case GenderFemale:
+		return "female"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseGender converts a gender as returned by EveryoneAPI, such as "M" or "F", into a Gender
+func ParseGender(s string) Gender {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "m", "male":
+		return GenderMale
+	case "f", "female":
+		return GenderFemale
+	default:
+		return GenderUnknown
+	}
+}
+
+// Coordinates parses the latitude and longitude of a Geo. ok is false if either is missing or
+// isn't a valid coordinate.
+func (g Geo) Coordinates() (lat, lon float64, ok bool) {
+	lat, err := strconv.ParseFloat(strings.TrimSpace(g.Latitude), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, false
+	}
+	lon, err = strconv.ParseFloat(strings.TrimSpace(g.Longitude), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+// The accessors below are safe to call on a nil *Data, and return the zero value for any data
+// point that wasn't returned. Accessors for data points whose field would share the accessor's
+// name are prefixed with Get.
+
+// GetName returns the name associated with the phone number
+func (d *Data) GetName() string {
+	if d == nil {
+		return ""
+	}
+	return deref(d.Name)
+}
+
+// GetCNAM returns the caller ID name of the phone number
+func (d *Data) GetCNAM() string {
+	if d == nil {
+		return ""
+	}
+	return deref(d.Cnam)
+}
+
+// GetAddress returns the address associated with the phone number
+func (d *Data) GetAddress() string {
+	if d == nil {
+		return ""
+	}
+	return deref(d.Address)
+}
+
+// GetGender returns the gender associated with the phone number
+func (d *Data) GetGender() Gender {
+	if d == nil {
+		return GenderUnknown
+	}
+	return ParseGender(deref(d.Gender))
+}
+
+// FirstName returns the first name from the expanded name of the phone number
+func (d *Data) FirstName() string {
+	if d == nil || d.ExpandedName == nil {
+		return ""
+	}
+	return d.ExpandedName.First
+}
+
+// LastName returns the last name from the expanded name of the phone number
+func (d *Data) LastName() string {
+	if d == nil || d.ExpandedName == nil {
+		return ""
+	}
+	return d.ExpandedName.Last
+}
+
+// CarrierName returns the name of the carrier currently providing line service
+func (d *Data) CarrierName() string {
+	if d == nil || d.Carrier == nil {
+		return ""
+	}
+	return d.Carrier.Name
+}
+
+// OriginalCarrierName returns the name of the carrier originally assigned the phone number
+func (d *Data) OriginalCarrierName() string {
+	if d == nil || d.CarrierO == nil {
+		return ""
+	}
+	return d.CarrierO.Name
+}
+
+// LineProviderName returns the name of the consumer facing line provider
+func (d *Data) LineProviderName() string {
+	if d == nil || d.LineProvider == nil {
+		return ""
+	}
+	return d.LineProvider.Name
+}
+
+// LineType returns the type of line the phone number is assigned to
+func (d *Data) LineType() LineType {
+	if d == nil {
+		return LineTypeUnknown
+	}
+	return ParseLineType(deref(d.Linetype))
+}
+
+// City returns the city the phone number is located in
+func (d *Data) City() string {
+	if d == nil || d.Location == nil {
+		return ""
+	}
+	return d.Location.City
+}
+
+// State returns the state the phone number is located in
+func (d *Data) State() string {
+	if d == nil || d.Location == nil {
+		return ""
+	}
+	return d.Location.State
+}
+
+// Zip returns the ZIP code the phone number is located in
+func (d *Data) Zip() string {
+	if d == nil || d.Location == nil {
+		return ""
+	}
+	return d.Location.Zip
+}
+
+// Coordinates returns the parsed latitude and longitude of the phone number's location. ok is
+// false if the location wasn't returned or its coordinates aren't valid.
+func (d *Data) Coordinates() (lat, lon float64, ok bool) {
+	if d == nil || d.Location == nil {
+		return 0, 0, false
+	}
+	return d.Location.Geo.Coordinates()
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,128 @@
+package whatphone_test
+
+import (
+	"testing"
+
+	. "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func TestAccessors(t *testing.T) {
+	data := apitest.Sample()
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"GetName", data.GetName(), "Michael Seaver"},
+		{"GetCNAM", data.GetCNAM(), "MICHAEL SEAVER"},
+		{"GetAddress", data.GetAddress(), "15 Robin Hood Lane"},
+		{"GetGender", data.GetGender().String(), "male"},
+		{"FirstName", data.FirstName(), "Michael"},
+		{"LastName", data.LastName(), "Seaver"},
+		{"CarrierName", data.CarrierName(), "Growing Wireless Inc."},
+		{"OriginalCarrierName", data.OriginalCarrierName(), "Paine Mobile Inc."},
+		{"LineProviderName", data.LineProviderName(), "MysticVoice"},
+		{"LineType", data.LineType().String(), "mobile"},
+		{"City", data.City(), "Long Island"},
+		{"State", data.State(), "NY"},
+		{"Zip", data.Zip(), "10003"},
+	}
+
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("Error: %s returned unexpected value. Got: %s, Want: %s", test.name, test.got, test.want)
+		}
+	}
+
+	lat, lon, ok := data.Coordinates()
+	if !ok || lat != 40.799787 || lon != -73.971421 {
+		t.Errorf("Error: Unexpected coordinates. Got: %v,%v (%v), Want: 40.799787,-73.971421", lat, lon, ok)
+	}
+}
+
+func TestAccessorsNil(t *testing.T) {
+	for _, data := range []*Data{nil, {}} {
+		if data.GetName() != "" || data.GetCNAM() != "" || data.GetAddress() != "" || data.FirstName() != "" ||
+			data.LastName() != "" || data.CarrierName() != "" || data.OriginalCarrierName() != "" ||
+			data.LineProviderName() != "" || data.City() != "" || data.State() != "" || data.Zip() != "" {
+			t.Errorf("Error: accessors on %#v should return empty strings", data)
+		}
+		if data.GetGender() != GenderUnknown || data.LineType() != LineTypeUnknown {
+			t.Errorf("Error: accessors on %#v should return unknown enums", data)
+		}
+		if _, _, ok := data.Coordinates(); ok {
+			t.Errorf("Error: coordinates on %#v should not be ok", data)
+		}
+	}
+}
+
+func TestGeoCoordinates(t *testing.T) {
+	tests := []struct {
+		geo Geo
+		lat float64
+		lon float64
+		ok  bool
+	}{
+		{Geo{Latitude: "40.7", Longitude: "-74.0"}, 40.7, -74, true},
+		{Geo{Latitude: " 0 ", Longitude: "0"}, 0, 0, true},
+		{Geo{Latitude: "", Longitude: "-74.0"}, 0, 0, false},
+		{Geo{Latitude: "40.7", Longitude: "west"}, 0, 0, false},
+		{Geo{Latitude: "91", Longitude: "0"}, 0, 0, false},
+		{Geo{Latitude: "0", Longitude: "-181"}, 0, 0, false},
+	}
+
+	for _, test := range tests {
+		lat, lon, ok := test.geo.Coordinates()
+		if lat != test.lat || lon != test.lon || ok != test.ok {
+			t.Errorf("Error: %+v returned unexpected coordinates. Got: %v,%v (%v), Want: %v,%v (%v)", test.geo, lat, lon, ok, test.lat, test.lon, test.ok)
+		}
+	}
+}
+
+func TestParseLineType(t *testing.T) {
+	tests := []struct {
+		in   string
+		want LineType
+	}{
+		{"mobile", LineTypeMobile},
+		{"Wireless", LineTypeMobile},
+		{"landline", LineTypeLandline},
+		{"Fixed Line", LineTypeLandline},
+		{"voip", LineTypeVoIP},
+		{"non-fixed voip", LineTypeVoIP},
+		{"toll-free", LineTypeTollFree},
+		{"Toll Free", LineTypeTollFree},
+		{"toll_free", LineTypeTollFree},
+		{"premium", LineTypePremium},
+		{"pager", LineTypePager},
+		{"satellite", LineTypeUnknown},
+		{"", LineTypeUnknown},
+	}
+
+	for _, test := range tests {
+		if got := ParseLineType(test.in); got != test.want {
+			t.Errorf("Error: ParseLineType(%q). Got: %v, Want: %v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestParseGender(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Gender
+	}{
+		{"M", GenderMale},
+		{"male", GenderMale},
+		{"F", GenderFemale},
+		{" Female ", GenderFemale},
+		{"X", GenderUnknown},
+		{"", GenderUnknown},
+	}
+
+	for _, test := range tests {
+		if got := ParseGender(test.in); got != test.want {
+			t.Errorf("Error: ParseGender(%q). Got: %v, Want: %v", test.in, got, test.want)
+		}
+	}
+}
@@ -1,10 +1,17 @@
 package whatphone // import "samhofi.us/x/whatphone/pkg/api"
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -21,37 +28,148 @@ func New(accountsid string, authtoken string) *API {
 
 // Lookup performs a phone number lookup and returns the Result
 func (a *API) Lookup(phonenumber string, opts ...Option) (*Result, error) {
-	f := new(fields)
-	for _, opt := range opts {
-		opt(f)
+	return a.LookupContext(context.Background(), phonenumber, opts...)
+}
+
+// LookupContext performs a phone number lookup and returns the Result. The request is canceled
+// when ctx is done.
+func (a *API) LookupContext(ctx context.Context, phonenumber string, opts ...Option) (*Result, error) {
+	if !validNumber(phonenumber) {
+		return nil, fmt.Errorf("invalid phone number")
 	}
+	f := Requested(opts...)
 
-	var data string
-	if len(*f) > 0 {
-		data = fmt.Sprintf("?data=%s", strings.Join(*f, ","))
+	ctx, span := a.tracer().Start(ctx, spanName, trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	key := f.cacheKey(phonenumber)
+	cache := CacheDisabled
+	if a.Cache != nil {
+		if ret, ok := a.Cache.Get(key); ok {
+			a.logger().DebugContext(ctx, "lookup answered from cache", "number", a.logNumber(phonenumber))
+			a.observe(span, LookupStats{Number: phonenumber, Data: f.Strings(), Result: ret, Cache: CacheHit})
+			return ret, nil
+		}
+		cache = CacheMiss
 	}
 
-	client := &http.Client{}
-	req, err := http.NewRequest(http.MethodGet, baseurl+phonenumber+data, nil)
+	start := time.Now()
+	ret, status, err := a.do(ctx, phonenumber, f)
+	a.observe(span, LookupStats{
+		Number:   phonenumber,
+		Data:     f.Strings(),
+		Status:   status,
+		Duration: time.Since(start),
+		Result:   ret,
+		Err:      err,
+		Cache:    cache,
+	})
 	if err != nil {
 		return nil, err
 	}
+
+	ret = a.Redaction.Apply(ret)
+	if a.Cache != nil {
+		a.Cache.Set(key, ret)
+	}
+
+	return ret, nil
+}
+
+// do sends a lookup request to EveryoneAPI, returning the decoded result along with the response's
+// HTTP status code, which is 0 if no response was received
+func (a *API) do(ctx context.Context, phonenumber string, f DataPointSet) (*Result, int, error) {
+	var data string
+	if f.Len() > 0 {
+		data = fmt.Sprintf("?data=%s", f)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.endpoint()+url.PathEscape(phonenumber)+data, nil)
+	if err != nil {
+		return nil, 0, err
+	}
 	req.SetBasicAuth(a.AccountSID, a.AuthToken)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
-	resp, err := client.Do(req)
+	logURL := a.endpoint() + a.logNumber(phonenumber) + data
+	a.logger().DebugContext(ctx, "sending lookup request", "url", logURL)
+	if a.Hooks.BeforeRequest != nil {
+		a.Hooks.BeforeRequest(req)
+	}
+
+	start := time.Now()
+	resp, err := a.httpClient().Do(req)
 	if err != nil {
-		return nil, err
+		return nil, 0, a.fail(req, logURL, err)
 	}
 	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	elapsed := time.Since(start)
+	if err != nil {
+		return nil, resp.StatusCode, a.fail(req, logURL, err)
+	}
+
+	a.logger().InfoContext(ctx, "lookup", "url", logURL, "status", resp.StatusCode, "duration", elapsed)
+	a.logger().DebugContext(ctx, "lookup response body", "url", logURL, "body", a.logBody(body))
+	if a.Hooks.AfterResponse != nil {
+		a.Hooks.AfterResponse(req, resp, body, elapsed)
+	}
+
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("%s", resp.Status)
+		return nil, resp.StatusCode, a.fail(req, logURL, fmt.Errorf("%s", resp.Status))
 	}
 
-	var ret Result
-	if err := json.NewDecoder(resp.Body).Decode(&ret); err != nil {
-		return nil, err
+	var warnings []Warning
+	ret, err := Decode(body)
+	if a.Strict {
+		// in strict mode, fields of the wrong type are reported as warnings rather than failing
+		// the lookup
+		if err != nil {
+			ret, warnings, err = decodeLenient(body)
+		} else {
+			warnings, _ = Validate(body)
+		}
+	}
+	if err != nil {
+		return nil, resp.StatusCode, a.fail(req, logURL, err)
 	}
 
-	return &ret, nil
+	if a.Strict {
+		ret.Warnings = warnings
+		for _, w := range ret.Warnings {
+			a.logger().WarnContext(ctx, "unexpected response schema", "url", logURL, "path", w.Path, "kind", w.Kind.String(), "expected", w.Expected, "got", w.Got)
+		}
+	}
+
+	return ret, resp.StatusCode, nil
+}
+
+// fail logs a failed lookup request and passes it to the OnError hook, returning err
+func (a *API) fail(req *http.Request, logURL string, err error) error {
+	// errors from the client quote the URL, which holds the phone number
+	if uerr, ok := err.(*url.Error); ok && a.Redaction.MaskNumbers {
+		uerr.URL = logURL
+	}
+	a.logger().WarnContext(req.Context(), "lookup failed", "url", logURL, "error", err)
+	if a.Hooks.OnError != nil {
+		a.Hooks.OnError(req, err)
+	}
+	return err
+}
+
+// endpoint returns the URL that phone numbers are appended to when performing a lookup
+func (a *API) endpoint() string {
+	if a.BaseURL == "" {
+		return baseurl
+	}
+	return strings.TrimSuffix(a.BaseURL, "/") + "/"
+}
+
+// httpClient returns the HTTP client used to perform lookups
+func (a *API) httpClient() *http.Client {
+	if a.Client == nil {
+		return http.DefaultClient
+	}
+	return a.Client
 }
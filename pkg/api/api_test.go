@@ -1,7 +1,10 @@
-package whatphone // import "samhofi.us/x/whatphone/pkg/api"
+package whatphone_test
 
 import (
 	"testing"
+
+	. "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
 )
 
 func TestNew(t *testing.T) {
@@ -20,10 +23,13 @@ func TestSingleField(t *testing.T) {
 	name := "Michael Seaver"
 	expandedname := ExpandedName{First: "Michael", Last: "Seaver"}
 
-	api := New("test", "test")
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	api := srv.API()
 	res, err := api.Lookup("+15551234567", WithName())
 	if err != nil {
-		t.Errorf("Error: %v", err)
+		t.Fatalf("Error: %v", err)
 	}
 
 	if *res.Data.Name != name {
@@ -62,10 +68,13 @@ func TestSingleField(t *testing.T) {
 }
 
 func TestNoField(t *testing.T) {
-	api := New("test", "test")
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	api := srv.API()
 	res, err := api.Lookup("+15551234567")
 	if err != nil {
-		t.Errorf("Error: %v", err)
+		t.Fatalf("Error: %v", err)
 	}
 
 	if res.Data.Name == nil {
@@ -0,0 +1,439 @@
+// Package apitest provides a fake EveryoneAPI server for testing code that performs phone number
+// lookups without touching the network.
+package apitest // import "samhofi.us/x/whatphone/pkg/api/apitest"
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+)
+
+const (
+	// SampleNumber is answered with sample data free of charge, like it is by EveryoneAPI
+	SampleNumber = "+15551234567"
+
+	// SampleNote is the note attached to lookups of SampleNumber
+	SampleNote = "THIS IS A SAMPLE, YOU WILL NOT BE CHARGED"
+
+	// AccountSID is the account SID accepted by a server unless WithCredentials is used
+	AccountSID = "test"
+
+	// AuthToken is the auth token accepted by a server unless WithCredentials is used
+	AuthToken = "test"
+)
+
+// Prices holds the price of each data point charged by a server unless WithPrices is used
+var Prices = whatphone.Breakdown{
+	Address:      0.08,
+	Carrier:      0.003,
+	Carrier0:     0.005,
+	Cnam:         0.01,
+	Gender:       0.01,
+	Image:        0.02,
+	LineProvider: 0.012,
+	Linetype:     0.001,
+	Location:     0.005,
+	Name:         0.01,
+	Profile:      0.005,
+}
+
+// dataPoints lists every data point understood by a server, in the order EveryoneAPI documents them
+var dataPoints = []string{
+	"name", "profile", "cnam", "gender", "image", "address",
+	"location", "line_provider", "carrier", "carrier_o", "linetype",
+}
+
+// Sample returns the data EveryoneAPI returns for SampleNumber
+func Sample() whatphone.Data {
+	str := func(s string) *string { return &s }
+	return whatphone.Data{
+		Address:      str("15 Robin Hood Lane"),
+		Carrier:      &whatphone.Carrier{ID: "214", Name: "Growing Wireless Inc."},
+		CarrierO:     &whatphone.CarrierO{ID: "213", Name: "Paine Mobile Inc."},
+		Cnam:         str("MICHAEL SEAVER"),
+		ExpandedName: &whatphone.ExpandedName{First: "Michael", Last: "Seaver"},
+		Gender:       str("M"),
+		Image: &whatphone.Image{
+			Cover: "//teloimg-pub.com.s3.amazonaws.com/cover.jpg",
+			Large: "//teloimg-pub.com.s3.amazonaws.com/large.jpg",
+			Med:   "//teloimg-pub.com.s3.amazonaws.com/med.jpg",
+			Small: "//teloimg-pub.com.s3.amazonaws.com/small.jpg",
+		},
+		LineProvider: &whatphone.LineProvider{
+			ID:       "215",
+			MmsEmail: "5551234567@mms.mysticvoice.com",
+			Name:     "MysticVoice",
+			SmsEmail: "5551234567@sms.mysticvoice.com",
+		},
+		Linetype: str("mobile"),
+		Location: &whatphone.Location{
+			City:  "Long Island",
+			Geo:   whatphone.Geo{Latitude: "40.799787", Longitude: "-73.971421"},
+			State: "NY",
+			Zip:   "10003",
+		},
+		Name:    str("Michael Seaver"),
+		Profile: &whatphone.Profile{Edu: "Thomas Dewey High School", Job: "Custodian", Relationship: "April Lerman"},
+	}
+}
+
+// Server is a fake EveryoneAPI. Point an API at it by setting its BaseURL to the server's URL.
+type Server struct {
+	*httptest.Server
+
+	accountSID string
+	authToken  string
+	prices     whatphone.Breakdown
+
+	mu       sync.Mutex
+	numbers  map[string]whatphone.Data
+	extras   map[string]map[string]interface{}
+	latency  time.Duration
+	status   int
+	failures int
+	failWith int
+	requests int
+}
+
+// Option configures a Server
+type Option func(s *Server)
+
+// WithCredentials sets the account SID and auth token accepted by the server
+func WithCredentials(accountsid string, authtoken string) Option {
+	return func(s *Server) {
+		s.accountSID = accountsid
+		s.authToken = authtoken
+	}
+}
+
+// WithNumber adds a phone number and the data returned when it's looked up. Fields left nil are
+// reported as missed when requested.
+func WithNumber(phonenumber string, data whatphone.Data) Option {
+	return func(s *Server) {
+		s.numbers[normalize(phonenumber)] = data
+	}
+}
+
+// WithExtraData adds a data point unknown to this package to the data returned for a phone
+// number, to simulate data points added to EveryoneAPI after the package was released. It is
+// returned when all data points are requested, or when it's requested by name.
+func WithExtraData(phonenumber string, field string, value interface{}) Option {
+	return func(s *Server) {
+		number := normalize(phonenumber)
+		if s.extras[number] == nil {
+			s.extras[number] = make(map[string]interface{})
+		}
+		s.extras[number][field] = value
+	}
+}
+
+// WithPrices sets the price of each data point charged by the server
+func WithPrices(prices whatphone.Breakdown) Option {
+	return func(s *Server) {
+		s.prices = prices
+	}
+}
+
+// WithLatency delays every response by d
+func WithLatency(d time.Duration) Option {
+	return func(s *Server) {
+		s.latency = d
+	}
+}
+
+// WithStatus makes the server answer every request with the given HTTP status code
+func WithStatus(code int) Option {
+	return func(s *Server) {
+		s.status = code
+	}
+}
+
+// NewServer starts and returns a new fake EveryoneAPI server. The caller should call Close when finished.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		accountSID: AccountSID,
+		authToken:  AuthToken,
+		prices:     Prices,
+		numbers:    map[string]whatphone.Data{SampleNumber: Sample()},
+		extras:     make(map[string]map[string]interface{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// API returns an API that performs lookups against the server
+func (s *Server) API() *whatphone.API {
+	api := whatphone.New(s.accountSID, s.authToken)
+	api.BaseURL = s.URL
+	return api
+}
+
+// FailNext makes the next n requests fail with the given HTTP status code
+func (s *Server) FailNext(n int, code int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = n
+	s.failWith = code
+}
+
+// SetLatency delays every following response by d
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// SetNumber adds a phone number, or replaces the data returned when it's looked up
+func (s *Server) SetNumber(phonenumber string, data whatphone.Data) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.numbers[normalize(phonenumber)] = data
+}
+
+// Requests returns the number of requests the server has received
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// handle answers a single lookup request
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests++
+	latency := s.latency
+	status := s.status
+	if s.failures > 0 {
+		s.failures--
+		status = s.failWith
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if status != 0 {
+		writeError(w, status)
+		return
+	}
+
+	sid, token, ok := r.BasicAuth()
+	if !ok || sid != s.accountSID || token != s.authToken {
+		writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, "/") {
+		writeError(w, http.StatusMethodNotAllowed)
+		return
+	}
+
+	number := normalize(strings.TrimPrefix(r.URL.Path, "/"))
+	if number == "" {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	requested := dataPoints
+	all := true
+	if data := r.URL.Query().Get("data"); data != "" {
+		requested = strings.Split(data, ",")
+		all = false
+	}
+
+	s.mu.Lock()
+	data, known := s.numbers[number]
+	extras := s.extras[number]
+	s.mu.Unlock()
+
+	result := whatphone.Result{
+		Missed: make([]string, 0),
+		Number: number,
+		Status: true,
+		Type:   "person",
+	}
+	extra := make(map[string]interface{})
+	for field, value := range extras {
+		if all {
+			extra[field] = value
+		}
+	}
+	for _, field := range requested {
+		if value, ok := extras[field]; ok {
+			extra[field] = value
+			continue
+		}
+		if !s.selectField(&result, data, field) {
+			result.Missed = append(result.Missed, field)
+		}
+	}
+	total(&result.Pricing)
+
+	if known && number == SampleNumber {
+		result.Note = SampleNote
+		negate(&result.Pricing)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(extra) == 0 {
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	// unknown data points can't be added to whatphone.Data, so merge them into its encoded form
+	var body map[string]interface{}
+	b, _ := json.Marshal(result)
+	json.Unmarshal(b, &body)
+	fields := body["data"].(map[string]interface{})
+	for field, value := range extra {
+		fields[field] = value
+	}
+	json.NewEncoder(w).Encode(body)
+}
+
+// selectField copies a single data point from data into the result and charges for it, reporting
+// whether the data point was available
+func (s *Server) selectField(result *whatphone.Result, data whatphone.Data, field string) bool {
+	out := &result.Data
+	price := &result.Pricing.Breakdown
+	switch field {
+	case "name":
+		if data.Name == nil {
+			return false
+		}
+		out.Name, out.ExpandedName = data.Name, data.ExpandedName
+		price.Name = s.prices.Name
+		if data.ExpandedName != nil {
+			price.ExpandedName = s.prices.ExpandedName
+		}
+	case "profile":
+		if data.Profile == nil {
+			return false
+		}
+		out.Profile = data.Profile
+		price.Profile = s.prices.Profile
+	case "cnam":
+		if data.Cnam == nil {
+			return false
+		}
+		out.Cnam = data.Cnam
+		price.Cnam = s.prices.Cnam
+	case "gender":
+		if data.Gender == nil {
+			return false
+		}
+		out.Gender = data.Gender
+		price.Gender = s.prices.Gender
+	case "image":
+		if data.Image == nil {
+			return false
+		}
+		out.Image = data.Image
+		price.Image = s.prices.Image
+	case "address":
+		// address lookups include the location free of charge
+		if data.Address == nil {
+			return false
+		}
+		out.Address = data.Address
+		if out.Location == nil {
+			out.Location = data.Location
+		}
+		price.Address = s.prices.Address
+	case "location":
+		if data.Location == nil {
+			return false
+		}
+		out.Location = data.Location
+		price.Location = s.prices.Location
+	case "line_provider":
+		if data.LineProvider == nil {
+			return false
+		}
+		out.LineProvider = data.LineProvider
+		price.LineProvider = s.prices.LineProvider
+	case "carrier":
+		if data.Carrier == nil {
+			return false
+		}
+		out.Carrier = data.Carrier
+		price.Carrier = s.prices.Carrier
+	case "carrier_o":
+		if data.CarrierO == nil {
+			return false
+		}
+		out.CarrierO = data.CarrierO
+		price.Carrier0 = s.prices.Carrier0
+	case "linetype":
+		if data.Linetype == nil {
+			return false
+		}
+		out.Linetype = data.Linetype
+		price.Linetype = s.prices.Linetype
+	default:
+		return false
+	}
+	return true
+}
+
+// total sums the pricing breakdown into the pricing total
+func total(p *whatphone.Pricing) {
+	b := p.Breakdown
+	p.Total = b.Address + b.Carrier + b.Carrier0 + b.Cnam + b.ExpandedName + b.Gender +
+		b.Image + b.LineProvider + b.Linetype + b.Location + b.Name + b.Profile
+}
+
+// negate flips the sign of every price, which is how EveryoneAPI prices sample lookups
+func negate(p *whatphone.Pricing) {
+	b := &p.Breakdown
+	for _, f := range []*float64{
+		&b.Address, &b.Carrier, &b.Carrier0, &b.Cnam, &b.ExpandedName, &b.Gender, &b.Image,
+		&b.LineProvider, &b.Linetype, &b.Location, &b.Name, &b.Profile, &p.Total,
+	} {
+		if *f != 0 {
+			*f = -*f
+		}
+	}
+}
+
+// normalize returns a phone number in E.164 form, or an empty string if it isn't a valid US number
+func normalize(phonenumber string) string {
+	var digits strings.Builder
+	for _, r := range phonenumber {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+
+	d := digits.String()
+	switch {
+	case len(d) == 10:
+		return "+1" + d
+	case len(d) == 11 && d[0] == '1':
+		return "+" + d
+	}
+	return ""
+}
+
+// writeError writes an EveryoneAPI style error response
+func writeError(w http.ResponseWriter, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  false,
+		"message": http.StatusText(code),
+	})
+}
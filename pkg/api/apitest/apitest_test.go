@@ -0,0 +1,136 @@
+package apitest
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+)
+
+func TestFieldSelection(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	res, err := srv.API().Lookup(SampleNumber, whatphone.WithName(), whatphone.WithAddress())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if res.Data.Name == nil || res.Data.Address == nil || res.Data.Location == nil {
+		t.Errorf("Error: requested fields missing from result: %+v", res.Data)
+	}
+	if res.Data.Carrier != nil || res.Data.Profile != nil {
+		t.Errorf("Error: unrequested fields present in result: %+v", res.Data)
+	}
+	if res.Note != SampleNote {
+		t.Errorf("Error: Unexpected note. Got: %s, Want: %s", res.Note, SampleNote)
+	}
+	if want := -(Prices.Name + Prices.Address); res.Pricing.Total != want {
+		t.Errorf("Error: Unexpected total. Got: %v, Want: %v", res.Pricing.Total, want)
+	}
+}
+
+func TestSamplePrices(t *testing.T) {
+	prices := Prices
+	prices.ExpandedName = 0.005
+	srv := NewServer(WithPrices(prices))
+	defer srv.Close()
+
+	res, err := srv.API().Lookup(SampleNumber, whatphone.WithName())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	b := res.Pricing.Breakdown
+	if b.Name != -prices.Name || b.ExpandedName != -prices.ExpandedName {
+		t.Errorf("Error: Sample prices should be negated. Got: %v/%v, Want: %v/%v", b.Name, b.ExpandedName, -prices.Name, -prices.ExpandedName)
+	}
+	if want := -(prices.Name + prices.ExpandedName); res.Pricing.Total != want {
+		t.Errorf("Error: Unexpected total. Got: %v, Want: %v", res.Pricing.Total, want)
+	}
+}
+
+func TestMissed(t *testing.T) {
+	carrier := whatphone.Carrier{ID: "1", Name: "Carrier"}
+	srv := NewServer(WithNumber("(555) 765-4321", whatphone.Data{Carrier: &carrier}))
+	defer srv.Close()
+
+	res, err := srv.API().Lookup("15557654321", whatphone.WithCarrier(), whatphone.WithGender())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if res.Data.Carrier == nil || *res.Data.Carrier != carrier {
+		t.Errorf("Error: Unexpected carrier. Got: %v, Want: %v", res.Data.Carrier, carrier)
+	}
+	if !reflect.DeepEqual(res.Missed, []string{"gender"}) {
+		t.Errorf("Error: Unexpected missed fields. Got: %v, Want: %v", res.Missed, []string{"gender"})
+	}
+	if res.Pricing.Total != Prices.Carrier {
+		t.Errorf("Error: Unexpected total. Got: %v, Want: %v", res.Pricing.Total, Prices.Carrier)
+	}
+	if res.Note != "" {
+		t.Errorf("Error: Unexpected note on a non-sample number: %s", res.Note)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv := NewServer(WithCredentials("sid", "token"))
+	defer srv.Close()
+
+	if _, err := whatphone.New("sid", "wrong").Lookup(SampleNumber); err == nil {
+		t.Errorf("Error: lookup without a base URL should not reach the fake server")
+	}
+
+	api := srv.API()
+	api.AuthToken = "wrong"
+	if _, err := api.Lookup(SampleNumber); err == nil || err.Error() != "401 Unauthorized" {
+		t.Errorf("Error: Unexpected error for bad credentials: %v", err)
+	}
+
+	srv.FailNext(1, http.StatusServiceUnavailable)
+	if _, err := srv.API().Lookup(SampleNumber); err == nil || err.Error() != "503 Service Unavailable" {
+		t.Errorf("Error: Unexpected error for injected failure: %v", err)
+	}
+	if _, err := srv.API().Lookup(SampleNumber); err != nil {
+		t.Errorf("Error: injected failure was not cleared: %v", err)
+	}
+
+	if _, err := srv.API().Lookup("123"); err == nil || err.Error() != "404 Not Found" {
+		t.Errorf("Error: Unexpected error for invalid number: %v", err)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	latency := 50 * time.Millisecond
+	srv := NewServer(WithLatency(latency))
+	defer srv.Close()
+
+	start := time.Now()
+	if _, err := srv.API().Lookup(SampleNumber, whatphone.WithName()); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < latency {
+		t.Errorf("Error: response arrived before injected latency. Got: %v, Want: >= %v", elapsed, latency)
+	}
+	if srv.Requests() != 1 {
+		t.Errorf("Error: Unexpected request count. Got: %d, Want: 1", srv.Requests())
+	}
+}
+
+func TestSetNumber(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	name := "Jane Doe"
+	srv.SetNumber("5557654321", whatphone.Data{Name: &name})
+	result, err := srv.API().Lookup("+15557654321", whatphone.WithName())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if result.Data.GetName() != name {
+		t.Errorf("Error: Unexpected name. Got: %s, Want: %s", result.Data.GetName(), name)
+	}
+}
@@ -0,0 +1,102 @@
+package whatphone // import "samhofi.us/x/whatphone/pkg/api"
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache stores lookup results. Results are keyed by phone number and requested data points, so a
+// lookup is only answered from the cache when it asks for exactly the same data. Results returned
+// by a Cache may be shared and must not be modified.
+type Cache interface {
+	Get(key string) (*Result, bool)
+	Set(key string, result *Result)
+}
+
+// MemoryCache is an in-memory Cache that expires entries after a fixed time and evicts the least
+// recently used entries once full. It is safe for concurrent use.
+type MemoryCache struct {
+	ttl  time.Duration
+	size int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+// cacheEntry is a single result stored in a MemoryCache
+type cacheEntry struct {
+	key     string
+	result  *Result
+	expires time.Time
+}
+
+// NewMemoryCache returns a MemoryCache holding up to size results for ttl each. A size of 0 means
+// the cache is unbounded, and a ttl of 0 means results never expire.
+func NewMemoryCache(ttl time.Duration, size int) *MemoryCache {
+	return &MemoryCache{
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Get returns the cached result for key, if there is one that hasn't expired
+func (c *MemoryCache) Get(key string) (*Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(el)
+	return entry.result, true
+}
+
+// Set stores result under key, evicting the least recently used result if the cache is full
+func (c *MemoryCache) Set(key string, result *Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if c.ttl > 0 {
+		expires = time.Now().Add(c.ttl)
+	}
+
+	if el, ok := c.entries[key]; ok {
+		el.Value = &cacheEntry{key: key, result: result, expires: expires}
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, result: result, expires: expires})
+	if c.size > 0 && c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Len returns the number of results in the cache, including any that have expired but haven't
+// been evicted yet
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// cacheKey returns the key a lookup of phonenumber for these data points is cached under
+func (s DataPointSet) cacheKey(phonenumber string) string {
+	return phonenumber + "?" + s.String()
+}
@@ -0,0 +1,70 @@
+package whatphone_test
+
+import (
+	"testing"
+	"time"
+
+	. "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func TestMemoryCache(t *testing.T) {
+	c := NewMemoryCache(0, 2)
+	a, b, d := &Result{Number: "a"}, &Result{Number: "b"}, &Result{Number: "d"}
+
+	c.Set("a", a)
+	c.Set("b", b)
+	if _, ok := c.Get("a"); !ok {
+		t.Errorf("Error: a should be cached")
+	}
+
+	// b is now the least recently used, so it's evicted first
+	c.Set("d", d)
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("Error: b should have been evicted")
+	}
+	if res, ok := c.Get("d"); !ok || res != d {
+		t.Errorf("Error: Unexpected cached result. Got: %v, Want: %v", res, d)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Error: Unexpected cache length. Got: %d, Want: 2", c.Len())
+	}
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	c := NewMemoryCache(10*time.Millisecond, 0)
+	c.Set("a", &Result{})
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("Error: a should have expired")
+	}
+}
+
+func TestLookupCache(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	api := srv.API()
+	api.Cache = NewMemoryCache(time.Minute, 0)
+
+	first, err := api.Lookup(apitest.SampleNumber, WithName(), WithCarrier())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// the order data points are requested in doesn't matter
+	second, err := api.Lookup(apitest.SampleNumber, WithCarrier(), WithName())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if second != first || srv.Requests() != 1 {
+		t.Errorf("Error: second lookup was not answered from the cache")
+	}
+
+	if _, err := api.Lookup(apitest.SampleNumber, WithName()); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if srv.Requests() != 2 {
+		t.Errorf("Error: lookup of different data points was answered from the cache")
+	}
+}
@@ -0,0 +1,229 @@
+package whatphone // import "samhofi.us/x/whatphone/pkg/api"
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DataPoint is a piece of data that can be requested by a lookup. Each data point is billed
+// separately. See "Data Points" section at https://www.everyoneapi.com/docs for more info
+type DataPoint int
+
+// Data points, in the order EveryoneAPI documents them
+const (
+	DataPointName DataPoint = iota + 1
+	DataPointProfile
+	DataPointCNAM
+	DataPointGender
+	DataPointImage
+	DataPointAddress
+	DataPointLocation
+	DataPointLineProvider
+	DataPointCarrier
+	DataPointOriginalCarrier
+	DataPointLineType
+)
+
+// dataPointNames holds the name EveryoneAPI uses for each data point
+var dataPointNames = [...]string{
+	DataPointName:            "name",
+	DataPointProfile:         "profile",
+	DataPointCNAM:            "cnam",
+	DataPointGender:          "gender",
+	DataPointImage:           "image",
+	DataPointAddress:         "address",
+	DataPointLocation:        "location",
+	DataPointLineProvider:    "line_provider",
+	DataPointCarrier:         "carrier",
+	DataPointOriginalCarrier: "carrier_o",
+	DataPointLineType:        "linetype",
+}
+
+// dataPointPrices holds the list price of each data point in US dollars
+var dataPointPrices = [...]float64{
+	DataPointName:            0.01,
+	DataPointProfile:         0.005,
+	DataPointCNAM:            0.01,
+	DataPointGender:          0.01,
+	DataPointImage:           0.02,
+	DataPointAddress:         0.08,
+	DataPointLocation:        0.005,
+	DataPointLineProvider:    0.012,
+	DataPointCarrier:         0.003,
+	DataPointOriginalCarrier: 0.005,
+	DataPointLineType:        0.001,
+}
+
+// dataPointAliases holds alternate names accepted by ParseDataPoint
+var dataPointAliases = map[string]DataPoint{
+	"line_type": DataPointLineType,
+}
+
+// AllDataPoints returns every data point, in the order EveryoneAPI documents them
+func AllDataPoints() []DataPoint {
+	all := make([]DataPoint, 0, len(dataPointNames)-1)
+	for dp := DataPointName; dp <= DataPointLineType; dp++ {
+		all = append(all, dp)
+	}
+	return all
+}
+
+// String returns the name EveryoneAPI uses for a data point
+func (dp DataPoint) String() string {
+	if !dp.valid() {
+		return fmt.Sprintf("DataPoint(%d)", int(dp))
+	}
+	return dataPointNames[dp]
+}
+
+// Price returns the list price of a data point in US dollars, as published by EveryoneAPI. It's
+// only an estimate: the amount actually charged for a lookup is returned in Result.Pricing, and
+// data points that aren't found aren't charged for.
+func (dp DataPoint) Price() float64 {
+	if !dp.valid() {
+		return 0
+	}
+	return dataPointPrices[dp]
+}
+
+// Price returns the estimated cost in US dollars of requesting every data point in the set
+func (s DataPointSet) Price() float64 {
+	var total float64
+	for _, dp := range s.DataPoints() {
+		total += dp.Price()
+	}
+	return total
+}
+
+// MarshalText encodes a data point as its name
+func (dp DataPoint) MarshalText() ([]byte, error) {
+	if !dp.valid() {
+		return nil, fmt.Errorf("unknown data point: %d", int(dp))
+	}
+	return []byte(dp.String()), nil
+}
+
+// UnmarshalText decodes a data point from its name
+func (dp *DataPoint) UnmarshalText(text []byte) error {
+	parsed, err := ParseDataPoint(string(text))
+	if err != nil {
+		return err
+	}
+	*dp = parsed
+	return nil
+}
+
+func (dp DataPoint) valid() bool {
+	return dp >= DataPointName && dp <= DataPointLineType
+}
+
+// ParseDataPoint returns the data point with the given name. Names are case insensitive.
+func ParseDataPoint(name string) (DataPoint, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for dp := DataPointName; dp <= DataPointLineType; dp++ {
+		if dataPointNames[dp] == name {
+			return dp, nil
+		}
+	}
+	if dp, ok := dataPointAliases[name]; ok {
+		return dp, nil
+	}
+	return 0, fmt.Errorf("unknown data point: %s", name)
+}
+
+// ParseDataPoints parses a comma separated list of data point names, such as "name,carrier".
+// Blank entries are skipped, and data points named more than once are only included once.
+func ParseDataPoints(s string) (DataPointSet, error) {
+	var set DataPointSet
+	for _, name := range strings.Split(s, ",") {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		dp, err := ParseDataPoint(name)
+		if err != nil {
+			return DataPointSet{}, err
+		}
+		set.Add(dp)
+	}
+	return set, nil
+}
+
+// DataPointSet is a set of data points. The zero value is an empty set, ready to use.
+type DataPointSet struct {
+	bits uint32
+}
+
+// NewDataPointSet returns a set holding the given data points
+func NewDataPointSet(dps ...DataPoint) DataPointSet {
+	var set DataPointSet
+	set.Add(dps...)
+	return set
+}
+
+// Requested returns the data points a lookup with the given options would request
+func Requested(opts ...Option) DataPointSet {
+	var set DataPointSet
+	for _, opt := range opts {
+		opt(&set)
+	}
+	return set
+}
+
+// Add adds data points to the set. Data points already in the set and unknown data points are
+// ignored.
+func (s *DataPointSet) Add(dps ...DataPoint) {
+	for _, dp := range dps {
+		if dp.valid() {
+			s.bits |= 1 << dp
+		}
+	}
+}
+
+// Remove removes data points from the set
+func (s *DataPointSet) Remove(dps ...DataPoint) {
+	for _, dp := range dps {
+		if dp.valid() {
+			s.bits &^= 1 << dp
+		}
+	}
+}
+
+// Has reports whether a data point is in the set
+func (s DataPointSet) Has(dp DataPoint) bool {
+	return dp.valid() && s.bits&(1<<dp) != 0
+}
+
+// Len returns the number of data points in the set
+func (s DataPointSet) Len() int {
+	var n int
+	for bits := s.bits; bits != 0; bits &= bits - 1 {
+		n++
+	}
+	return n
+}
+
+// DataPoints returns the data points in the set, in the order EveryoneAPI documents them
+func (s DataPointSet) DataPoints() []DataPoint {
+	dps := make([]DataPoint, 0, s.Len())
+	for dp := DataPointName; dp <= DataPointLineType; dp++ {
+		if s.Has(dp) {
+			dps = append(dps, dp)
+		}
+	}
+	return dps
+}
+
+// Strings returns the names of the data points in the set, in the order EveryoneAPI documents them
+func (s DataPointSet) Strings() []string {
+	names := make([]string, 0, s.Len())
+	for _, dp := range s.DataPoints() {
+		names = append(names, dp.String())
+	}
+	return names
+}
+
+// String returns the names of the data points in the set as a comma separated list, in the form
+// accepted by ParseDataPoints
+func (s DataPointSet) String() string {
+	return strings.Join(s.Strings(), ",")
+}
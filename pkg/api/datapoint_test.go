@@ -0,0 +1,178 @@
+package whatphone_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	. "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func TestAllDataPoints(t *testing.T) {
+	all := AllDataPoints()
+	if len(all) != 11 {
+		t.Fatalf("Error: Unexpected number of data points. Got: %d, Want: 11", len(all))
+	}
+
+	prices := Breakdown{}.Prices()
+	for _, dp := range all {
+		if _, ok := prices[dp.String()]; !ok {
+			t.Errorf("Error: %v has no price", dp)
+		}
+		parsed, err := ParseDataPoint(dp.String())
+		if err != nil || parsed != dp {
+			t.Errorf("Error: %v didn't round trip. Got: %v (%v)", dp, parsed, err)
+		}
+	}
+
+	if got := NewDataPointSet(all...).Price(); got < 0.160 || got > 0.162 {
+		t.Errorf("Error: Unexpected price of all data points. Got: %v, Want: 0.161", got)
+	}
+	if DataPoint(0).Price() != 0 {
+		t.Errorf("Error: invalid data point should be free")
+	}
+
+	if got := DataPoint(0).String(); got != "DataPoint(0)" {
+		t.Errorf("Error: Unexpected string for an invalid data point. Got: %s", got)
+	}
+}
+
+func TestParseDataPoints(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []DataPoint
+		err  error
+	}{
+		{"", []DataPoint{}, nil},
+		{"name", []DataPoint{DataPointName}, nil},
+		{" Carrier , name,,", []DataPoint{DataPointName, DataPointCarrier}, nil},
+		{"name,name,cnam,name", []DataPoint{DataPointName, DataPointCNAM}, nil},
+		{"carrier_o,line_type", []DataPoint{DataPointOriginalCarrier, DataPointLineType}, nil},
+		{"name,spam_score", nil, errors.New("unknown data point: spam_score")},
+	}
+
+	for _, test := range tests {
+		set, err := ParseDataPoints(test.in)
+		if !reflect.DeepEqual(err, test.err) {
+			t.Errorf("Error: ParseDataPoints(%q) returned unexpected error. Got: %v, Want: %v", test.in, err, test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got := set.DataPoints(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Error: ParseDataPoints(%q). Got: %v, Want: %v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestDataPointSet(t *testing.T) {
+	set := NewDataPointSet(DataPointLineType, DataPointName, DataPointName, DataPoint(0), DataPoint(99))
+	if set.Len() != 2 || !set.Has(DataPointName) || !set.Has(DataPointLineType) || set.Has(DataPointCNAM) {
+		t.Errorf("Error: Unexpected set: %v", set)
+	}
+	if got := set.String(); got != "name,linetype" {
+		t.Errorf("Error: Unexpected string. Got: %s, Want: name,linetype", got)
+	}
+
+	set.Remove(DataPointName)
+	if got := set.Strings(); !reflect.DeepEqual(got, []string{"linetype"}) {
+		t.Errorf("Error: Unexpected names after remove. Got: %v", got)
+	}
+
+	var empty DataPointSet
+	if empty.Len() != 0 || empty.String() != "" {
+		t.Errorf("Error: zero value should be an empty set: %v", empty)
+	}
+}
+
+func TestRequested(t *testing.T) {
+	got := Requested(WithName(), WithCarrier(), WithName(), WithDataPoints(DataPointCNAM, DataPointCarrier))
+	want := NewDataPointSet(DataPointName, DataPointCNAM, DataPointCarrier)
+	if got != want {
+		t.Errorf("Error: Unexpected data points. Got: %v, Want: %v", got, want)
+	}
+}
+
+func TestDataPointText(t *testing.T) {
+	var dps []DataPoint
+	if err := json.Unmarshal([]byte(`["name", "Carrier_O"]`), &dps); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	b, err := json.Marshal(dps)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if string(b) != `["name","carrier_o"]` {
+		t.Errorf("Error: Unexpected JSON. Got: %s", b)
+	}
+
+	if err := json.Unmarshal([]byte(`["spam_score"]`), &dps); err == nil {
+		t.Errorf("Error: unknown data point should have returned an error")
+	}
+	if _, err := json.Marshal([]DataPoint{0}); err == nil {
+		t.Errorf("Error: invalid data point should have returned an error")
+	}
+}
+
+func TestLookupDeduplicates(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	var query string
+	api := srv.API()
+	api.Hooks.BeforeRequest = func(req *http.Request) {
+		query = req.URL.RawQuery
+	}
+
+	res, err := api.Lookup(apitest.SampleNumber, WithLineType(), WithName(), WithName(), WithDataPoints(DataPointLineType))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if query != "data=name,linetype" {
+		t.Errorf("Error: Unexpected query. Got: %s, Want: data=name,linetype", query)
+	}
+	if res.Data.LineType() != LineTypeMobile || len(res.Missed) != 0 {
+		t.Errorf("Error: line type should have been returned. Got: %v, missed: %v", res.Data.LineType(), res.Missed)
+	}
+}
+
+func TestWithQueryString(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	var query string
+	api := srv.API()
+	api.Hooks.BeforeRequest = func(req *http.Request) {
+		query = req.URL.RawQuery
+	}
+
+	// these are the names EveryoneAPI documents, so they must not change
+	tests := []struct {
+		opt      Option
+		expected string
+	}{
+		{WithName(), "data=name"},
+		{WithProfile(), "data=profile"},
+		{WithCNAM(), "data=cnam"},
+		{WithGender(), "data=gender"},
+		{WithImage(), "data=image"},
+		{WithAddress(), "data=address"},
+		{WithLocation(), "data=location"},
+		{WithLineProvider(), "data=line_provider"},
+		{WithCarrier(), "data=carrier"},
+		{WithOriginalCarrier(), "data=carrier_o"},
+		{WithLineType(), "data=linetype"},
+	}
+	for _, test := range tests {
+		if _, err := api.Lookup(apitest.SampleNumber, test.opt); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if query != test.expected {
+			t.Errorf("Error: Unexpected query. Got: %s, Want: %s", query, test.expected)
+		}
+	}
+}
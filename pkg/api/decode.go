@@ -0,0 +1,75 @@
+package whatphone // import "samhofi.us/x/whatphone/pkg/api"
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Decode decodes a lookup response body into a Result, keeping the body in Result.Raw and any
+// fields this package doesn't know about in Result.Extra and Result.Data.Extra
+func Decode(body []byte) (*Result, error) {
+	var ret Result
+	if err := json.Unmarshal(body, &ret); err != nil {
+		return nil, err
+	}
+	ret.Raw = append(json.RawMessage(nil), body...)
+
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(body, &top); err != nil {
+		return nil, err
+	}
+	ret.Extra = unknownFields(top, reflect.TypeOf(ret))
+
+	if data, ok := top["data"]; ok {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err == nil {
+			ret.Data.Extra = unknownFields(fields, reflect.TypeOf(ret.Data))
+		}
+	}
+
+	return &ret, nil
+}
+
+// decodeLenient decodes a lookup response body like Decode, but leaves fields of an unexpected type
+// at their zero value rather than failing, returning them as warnings along with any undeclared
+// fields
+func decodeLenient(body []byte) (*Result, []Warning, error) {
+	v, warnings, err := check(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pruned, err := json.Marshal(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	ret, err := Decode(pruned)
+	if err != nil {
+		return nil, nil, err
+	}
+	ret.Raw = append(json.RawMessage(nil), body...)
+	return ret, warnings, nil
+}
+
+// unknownFields returns the fields that don't map to any field of struct type t, or nil if there
+// are none
+func unknownFields(fields map[string]json.RawMessage, t reflect.Type) map[string]json.RawMessage {
+	known := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		if name := jsonName(t.Field(i)); name != "" {
+			known[name] = true
+		}
+	}
+
+	var extra map[string]json.RawMessage
+	for k, v := range fields {
+		if known[k] {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]json.RawMessage)
+		}
+		extra[k] = v
+	}
+	return extra
+}
@@ -0,0 +1,53 @@
+package whatphone_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func TestDecode(t *testing.T) {
+	body := []byte(`{"data": {"name": "Jane Doe", "spam_score": {"score": 7}}, "number": "+15557654321", "status": true, "request_id": "abc"}`)
+
+	res, err := Decode(body)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if *res.Data.Name != "Jane Doe" || res.Number != "+15557654321" {
+		t.Errorf("Error: known fields not decoded: %+v", res)
+	}
+	if string(res.Raw) != string(body) {
+		t.Errorf("Error: Unexpected raw body. Got: %s, Want: %s", res.Raw, body)
+	}
+	if len(res.Extra) != 1 || string(res.Extra["request_id"]) != `"abc"` {
+		t.Errorf("Error: Unexpected extra fields: %s", res.Extra)
+	}
+	if len(res.Data.Extra) != 1 || string(res.Data.Extra["spam_score"]) != `{"score": 7}` {
+		t.Errorf("Error: Unexpected extra data points: %s", res.Data.Extra)
+	}
+
+	if _, err := Decode([]byte(`{"data": `)); err == nil {
+		t.Errorf("Error: invalid JSON should have returned an error")
+	}
+}
+
+func TestLookupExtraData(t *testing.T) {
+	srv := apitest.NewServer(apitest.WithExtraData(apitest.SampleNumber, "spam_score", 7))
+	defer srv.Close()
+
+	res, err := srv.API().Lookup(apitest.SampleNumber)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	var score int
+	if err := json.Unmarshal(res.Data.Extra["spam_score"], &score); err != nil || score != 7 {
+		t.Errorf("Error: Unexpected spam_score. Got: %d (%v), Want: 7", score, err)
+	}
+	if res.Extra != nil {
+		t.Errorf("Error: Unexpected extra fields: %s", res.Extra)
+	}
+}
@@ -0,0 +1,349 @@
+// Package fixture records EveryoneAPI responses to files and replays them, so lookups can be tested
+// against real responses without paying for them twice.
+//
+// Both the Recorder and the Replayer are http.RoundTrippers meant to be used as the Transport of an
+// API's Client:
+//
+//	api.Client = &http.Client{Transport: fixture.NewRecorder("testdata", nil, fixture.ScrubAuth())}
+package fixture // import "samhofi.us/x/whatphone/pkg/api/fixture"
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces scrubbed values in recorded fixtures
+const Redacted = "REDACTED"
+
+// piiFields lists the data points that are scrubbed from recorded responses by ScrubPII
+var piiFields = []string{"address", "cnam", "expanded_name", "gender", "image", "location", "name", "profile"}
+
+// numberPattern matches phone numbers embedded in other values, like line provider email addresses
+var numberPattern = regexp.MustCompile(`\+?\d{10,15}`)
+
+// Fixture holds a single recorded request and its response
+type Fixture struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request holds the recorded parts of a request
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+}
+
+// Response holds the recorded parts of a response
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// Recorder is an http.RoundTripper that passes requests on to another RoundTripper and writes
+// each request and its response to a fixture file
+type Recorder struct {
+	dir       string
+	next      http.RoundTripper
+	scrubAuth bool
+	scrubPII  bool
+
+	// mu keeps concurrent recordings from picking the same scrubbed fixture number
+	mu sync.Mutex
+}
+
+// RecorderOption configures a Recorder
+type RecorderOption func(r *Recorder)
+
+// ScrubAuth removes the Authorization header from recorded requests
+func ScrubAuth() RecorderOption {
+	return func(r *Recorder) {
+		r.scrubAuth = true
+	}
+}
+
+// ScrubPII replaces the phone number and personal data points (names, addresses, images, etc.) in
+// recorded requests and responses with Redacted. Since a fixture's file name can't be derived from
+// the phone number without revealing it, scrubbed fixtures are named after the scrubbed request and
+// numbered in the order they're recorded, and are replayed in that order to lookups of any number
+// requesting the same data points.
+func ScrubPII() RecorderOption {
+	return func(r *Recorder) {
+		r.scrubPII = true
+	}
+}
+
+// NewRecorder returns a Recorder that writes fixtures to dir. Requests are performed by next, or
+// http.DefaultTransport if next is nil.
+func NewRecorder(dir string, next http.RoundTripper, opts ...RecorderOption) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	r := &Recorder{
+		dir:  dir,
+		next: next,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// RoundTrip performs the request and records it along with its response
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	f := Fixture{
+		Request: Request{
+			Method: req.Method,
+			URL:    req.URL.RequestURI(),
+			Header: req.Header.Clone(),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+			Body:       string(body),
+		},
+	}
+	if r.scrubAuth {
+		f.Request.Header.Del("Authorization")
+	}
+	if r.scrubPII {
+		scrubPII(&f)
+	}
+
+	if err := r.write(req, f); err != nil {
+		return nil, fmt.Errorf("fixture: unable to record %s %s: %v", req.Method, req.URL.Path, err)
+	}
+
+	return resp, nil
+}
+
+// write writes a recorded fixture to its file. Scrubbed fixtures are given the first number not
+// already taken by an earlier recording of the same scrubbed request.
+func (r *Recorder) write(req *http.Request, f Fixture) error {
+	if !r.scrubPII {
+		return write(filepath.Join(r.dir, name(req)), f)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := scrubbedName(req.Method, f.Request.URL)
+	for n := 1; ; n++ {
+		path := filepath.Join(r.dir, numbered(key, n))
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return write(path, f)
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// Replayer is an http.RoundTripper that answers requests with previously recorded fixtures
+type Replayer struct {
+	dir string
+
+	mu       sync.Mutex
+	replayed map[string]int
+}
+
+// NewReplayer returns a Replayer that reads fixtures from dir
+func NewReplayer(dir string) *Replayer {
+	return &Replayer{
+		dir:      dir,
+		replayed: make(map[string]int),
+	}
+}
+
+// RoundTrip answers the request with its recorded response. An error is returned if the request
+// was never recorded.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	b, err := os.ReadFile(filepath.Join(r.dir, name(req)))
+	if os.IsNotExist(err) {
+		b, err = r.readScrubbed(req)
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("fixture: no recording for %s %s", req.Method, req.URL.Path)
+		}
+		return nil, err
+	}
+
+	var f Fixture
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("fixture: unable to read recording for %s %s: %v", req.Method, req.URL.Path, err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Response.StatusCode, http.StatusText(f.Response.StatusCode)),
+		StatusCode:    f.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        f.Response.Header,
+		Body:          io.NopCloser(strings.NewReader(f.Response.Body)),
+		ContentLength: int64(len(f.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// readScrubbed reads the next fixture recorded with ScrubPII for a request, starting over once
+// every one of them has been replayed
+func (r *Replayer) readScrubbed(req *http.Request) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := scrubbedName(req.Method, scrubURI(req.URL.RequestURI()))
+	n := r.replayed[key] + 1
+	b, err := os.ReadFile(filepath.Join(r.dir, numbered(key, n)))
+	if os.IsNotExist(err) && n > 1 {
+		n = 1
+		b, err = os.ReadFile(filepath.Join(r.dir, numbered(key, n)))
+	}
+	if err == nil {
+		r.replayed[key] = n
+	}
+	return b, err
+}
+
+// name returns the fixture file name for a request. The name is derived from the method, path and
+// query only, so fixtures can be replayed regardless of the host or credentials used.
+func name(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.RequestURI()))
+	return hex.EncodeToString(sum[:12]) + ".json"
+}
+
+// scrubbedName returns the name shared by the fixtures of a request once it's been scrubbed,
+// which is numbered by numbered
+func scrubbedName(method, scrubbedURI string) string {
+	sum := sha256.Sum256([]byte(method + " " + scrubbedURI))
+	return hex.EncodeToString(sum[:12])
+}
+
+// numbered returns the file name of the nth fixture recorded for a scrubbed request
+func numbered(key string, n int) string {
+	return fmt.Sprintf("%s-%d.json", key, n)
+}
+
+// write writes a fixture to a file, creating its directory if needed
+func write(path string, f Fixture) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// scrubPII redacts the phone number from a fixture's request, and the phone number and personal
+// data points from its response body
+func scrubPII(f *Fixture) {
+	f.Request.URL = scrubURI(f.Request.URL)
+
+	var body map[string]interface{}
+	if err := json.Unmarshal([]byte(f.Response.Body), &body); err != nil {
+		// not a lookup result, so there's nothing to scrub
+		return
+	}
+
+	if _, ok := body["number"]; ok {
+		body["number"] = Redacted
+	}
+	if data, ok := body["data"].(map[string]interface{}); ok {
+		for _, field := range piiFields {
+			if v, ok := data[field]; ok && v != nil {
+				data[field] = redact(v)
+			}
+		}
+	}
+
+	// phone numbers also turn up inside fields that aren't personal, like the email addresses of
+	// the line provider
+	scrubNumbers(body)
+
+	if b, err := json.Marshal(body); err == nil {
+		f.Response.Body = string(b)
+	}
+}
+
+// scrubNumbers replaces the phone numbers within every string of a decoded JSON value with
+// Redacted
+func scrubNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return numberPattern.ReplaceAllString(v, Redacted)
+	case map[string]interface{}:
+		for k, val := range v {
+			v[k] = scrubNumbers(val)
+		}
+		return v
+	case []interface{}:
+		for i, val := range v {
+			v[i] = scrubNumbers(val)
+		}
+		return v
+	}
+	return v
+}
+
+// scrubURI replaces the phone number at the end of a request URI's path with Redacted
+func scrubURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	u.Path = path.Join(path.Dir(u.Path), Redacted)
+	u.RawPath = ""
+	return u.RequestURI()
+}
+
+// redact replaces every string within a decoded JSON value with Redacted, keeping its shape
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return Redacted
+	case map[string]interface{}:
+		for k, val := range v {
+			v[k] = redact(val)
+		}
+		return v
+	case []interface{}:
+		for i, val := range v {
+			v[i] = redact(val)
+		}
+		return v
+	}
+	return v
+}
@@ -0,0 +1,137 @@
+package fixture
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func TestRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	srv := apitest.NewServer()
+
+	api := srv.API()
+	api.Client = &http.Client{Transport: NewRecorder(dir, nil, ScrubAuth())}
+	recorded, err := api.Lookup(apitest.SampleNumber, whatphone.WithName())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	srv.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("Error: Unexpected number of fixtures. Got: %d, Want: 1", len(files))
+	}
+	b, _ := os.ReadFile(files[0])
+	if strings.Contains(string(b), "Authorization") {
+		t.Errorf("Error: Authorization header was not scrubbed:\n%s", b)
+	}
+
+	// the server is closed, so this can only be answered by the replayer
+	api.Client = &http.Client{Transport: NewReplayer(dir)}
+	replayed, err := api.Lookup(apitest.SampleNumber, whatphone.WithName())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if *replayed.Data.Name != *recorded.Data.Name || replayed.Pricing != recorded.Pricing {
+		t.Errorf("Error: Unexpected replayed result. Got: %+v, Want: %+v", replayed, recorded)
+	}
+
+	if _, err := api.Lookup(apitest.SampleNumber, whatphone.WithCarrier()); err == nil {
+		t.Errorf("Error: lookup that was never recorded should have failed")
+	}
+}
+
+func TestScrubPII(t *testing.T) {
+	dir := t.TempDir()
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	api := srv.API()
+	api.Client = &http.Client{Transport: NewRecorder(dir, nil, ScrubPII())}
+	if _, err := api.Lookup(apitest.SampleNumber, whatphone.WithName(), whatphone.WithCarrier(), whatphone.WithLineProvider()); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("Error: Unexpected number of fixtures. Got: %d, Want: 1", len(files))
+	}
+	b, _ := os.ReadFile(files[0])
+	fixture := string(b)
+	for _, pii := range []string{"Michael", "Seaver", "5551234567"} {
+		if strings.Contains(fixture, pii) {
+			t.Errorf("Error: %q was not scrubbed:\n%s", pii, fixture)
+		}
+	}
+	for _, kept := range []string{"Growing Wireless Inc.", "MysticVoice", "REDACTED@sms.mysticvoice.com"} {
+		if !strings.Contains(fixture, kept) {
+			t.Errorf("Error: %q should not be scrubbed:\n%s", kept, fixture)
+		}
+	}
+	if !strings.Contains(fixture, "Authorization") {
+		t.Errorf("Error: Authorization header should only be scrubbed with ScrubAuth:\n%s", fixture)
+	}
+
+	// the fixture is still found using the real phone number
+	api.Client = &http.Client{Transport: NewReplayer(dir)}
+	res, err := api.Lookup(apitest.SampleNumber, whatphone.WithName(), whatphone.WithCarrier(), whatphone.WithLineProvider())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if *res.Data.Name != Redacted {
+		t.Errorf("Error: Unexpected replayed name. Got: %s, Want: %s", *res.Data.Name, Redacted)
+	}
+}
+
+func TestScrubPIIFileNames(t *testing.T) {
+	dir := t.TempDir()
+	jane := "Jane Doe"
+	srv := apitest.NewServer(apitest.WithNumber("+15557654321", whatphone.Data{Name: &jane}))
+
+	var requests []*http.Request
+	api := srv.API()
+	api.Hooks.BeforeRequest = func(req *http.Request) {
+		requests = append(requests, req)
+	}
+	api.Client = &http.Client{Transport: NewRecorder(dir, nil, ScrubPII())}
+	for _, number := range []string{apitest.SampleNumber, "+15557654321"} {
+		if _, err := api.Lookup(number, whatphone.WithName()); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	srv.Close()
+
+	// the file names can't be traced back to the phone numbers
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 2 {
+		t.Fatalf("Error: Unexpected number of fixtures. Got: %d, Want: 2", len(files))
+	}
+	for _, req := range requests {
+		if _, err := os.Stat(filepath.Join(dir, name(req))); err == nil {
+			t.Errorf("Error: The fixture of %s is named after its phone number", req.URL.Path)
+		}
+	}
+
+	// scrubbed fixtures are replayed in the order they were recorded, then over again
+	api.Hooks.BeforeRequest = nil
+	api.Client = &http.Client{Transport: NewReplayer(dir)}
+	for i, negative := range []bool{true, false, true} {
+		res, err := api.Lookup("+15550000000", whatphone.WithName())
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if (res.Pricing.Total < 0) != negative {
+			t.Errorf("Error: Replay %d answered with the wrong fixture: %+v", i+1, res.Pricing)
+		}
+	}
+
+	if _, err := api.Lookup("+15550000000", whatphone.WithCarrier()); err == nil {
+		t.Errorf("Error: lookup that was never recorded should have failed")
+	}
+}
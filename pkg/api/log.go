@@ -0,0 +1,69 @@
+package whatphone // import "samhofi.us/x/whatphone/pkg/api"
+
+import (
+	"log/slog"
+	"regexp"
+)
+
+// redacted replaces secrets in logs
+const redacted = "REDACTED"
+
+// discard is used when an API has no Logger
+var discard = slog.New(slog.DiscardHandler)
+
+// numberPattern matches phone numbers embedded in response bodies
+var numberPattern = regexp.MustCompile(`\+?\d{10,15}`)
+
+// LogValue keeps the auth token out of logs when an API is logged
+func (a API) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("account_sid", a.AccountSID),
+		slog.String("auth_token", redacted),
+		slog.String("base_url", a.endpoint()),
+	)
+}
+
+// logger returns the API's Logger, or a logger that discards everything if it has none
+func (a *API) logger() *slog.Logger {
+	if a.Logger == nil {
+		return discard
+	}
+	return a.Logger
+}
+
+// logNumber returns a phone number as it should appear in logs
+func (a *API) logNumber(phonenumber string) string {
+	return a.Redaction.Number(phonenumber)
+}
+
+// logBody returns a response body as it should appear in logs, redacted like the result decoded
+// from it would be
+func (a *API) logBody(body []byte) string {
+	if !a.Redaction.IsZero() {
+		if result, err := Decode(body); err == nil {
+			body = a.Redaction.Apply(result).Raw
+		}
+	}
+	if !a.Redaction.MaskNumbers {
+		return string(body)
+	}
+	return numberPattern.ReplaceAllStringFunc(string(body), MaskNumber)
+}
+
+// MaskNumber replaces all but the last four digits of a phone number with asterisks, leaving any
+// formatting characters in place
+func MaskNumber(phonenumber string) string {
+	masked := []rune(phonenumber)
+	keep := 4
+	for i := len(masked) - 1; i >= 0; i-- {
+		if masked[i] < '0' || masked[i] > '9' {
+			continue
+		}
+		if keep > 0 {
+			keep--
+			continue
+		}
+		masked[i] = '*'
+	}
+	return string(masked)
+}
@@ -0,0 +1,103 @@
+package whatphone_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	. "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func TestLogging(t *testing.T) {
+	srv := apitest.NewServer(apitest.WithCredentials("sid", "supersecret"))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	api := srv.API()
+	api.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	api.Redaction.MaskNumbers = true
+
+	if _, err := api.Lookup("+15551234567", WithName()); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	api.Logger.Info("config", "api", api)
+
+	logs := buf.String()
+	for _, secret := range []string{"supersecret", "c2lkOnN1cGVyc2VjcmV0", "5551234567"} {
+		if strings.Contains(logs, secret) {
+			t.Errorf("Error: %q leaked into logs:\n%s", secret, logs)
+		}
+	}
+	for _, expected := range []string{"+*******4567?data=name", "status=200", "duration=", "Michael Seaver"} {
+		if !strings.Contains(logs, expected) {
+			t.Errorf("Error: %q missing from logs:\n%s", expected, logs)
+		}
+	}
+
+	// raw bodies are only logged at the debug level
+	buf.Reset()
+	api.Logger = slog.New(slog.NewTextHandler(&buf, nil))
+	if _, err := api.Lookup("+15551234567", WithName()); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if logs := buf.String(); !strings.Contains(logs, "status=200") || strings.Contains(logs, "Michael Seaver") {
+		t.Errorf("Error: Unexpected info level logs:\n%s", logs)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	var calls []string
+	api := srv.API()
+	api.Hooks = Hooks{
+		BeforeRequest: func(req *http.Request) {
+			calls = append(calls, "before "+req.URL.Path)
+		},
+		AfterResponse: func(req *http.Request, resp *http.Response, body []byte, elapsed time.Duration) {
+			calls = append(calls, "after "+resp.Status)
+			if len(body) == 0 || elapsed <= 0 {
+				t.Errorf("Error: Unexpected AfterResponse arguments. Body: %q, Elapsed: %v", body, elapsed)
+			}
+		},
+		OnError: func(req *http.Request, err error) {
+			calls = append(calls, "error "+err.Error())
+		},
+	}
+
+	if _, err := api.Lookup(apitest.SampleNumber, WithName()); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	srv.FailNext(1, http.StatusServiceUnavailable)
+	api.Lookup(apitest.SampleNumber, WithName())
+
+	expected := []string{
+		"before /+15551234567",
+		"after 200 OK",
+		"before /+15551234567",
+		"after 503 Service Unavailable",
+		"error 503 Service Unavailable",
+	}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Error: Unexpected hook calls.\nGot: %q\nWant: %q", calls, expected)
+	}
+}
+
+func TestMaskNumber(t *testing.T) {
+	numbers := map[string]string{
+		"+15551234567":   "+*******4567",
+		"(555) 123-4567": "(***) ***-4567",
+		"123":            "123",
+	}
+	for number, expected := range numbers {
+		if got := MaskNumber(number); got != expected {
+			t.Errorf("Error: Unexpected mask for %s. Got: %s, Want: %s", number, got, expected)
+		}
+	}
+}
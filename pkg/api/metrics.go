@@ -0,0 +1,72 @@
+package whatphone // import "samhofi.us/x/whatphone/pkg/api"
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CacheResult describes the part a Cache played in a lookup
+type CacheResult int
+
+const (
+	// CacheDisabled means the API has no Cache
+	CacheDisabled CacheResult = iota
+
+	// CacheHit means the lookup was answered from the Cache
+	CacheHit
+
+	// CacheMiss means the Cache was checked, but the lookup had to be sent to EveryoneAPI
+	CacheMiss
+)
+
+// String returns the name of the cache result
+func (c CacheResult) String() string {
+	switch c {
+	case CacheHit:
+		return "hit"
+	case CacheMiss:
+		return "miss"
+	}
+	return "disabled"
+}
+
+// LookupStats holds the measurements of a single lookup
+type LookupStats struct {
+	// Number is the phone number that was looked up
+	Number string
+
+	// Data lists the data points that were requested, or is empty if all were requested
+	Data []string
+
+	// Status is the HTTP status code returned by EveryoneAPI. It is 0 if the lookup was answered
+	// from the cache, or no response was received.
+	Status int
+
+	// Duration is how long the request to EveryoneAPI took, or 0 if it was answered from the cache
+	Duration time.Duration
+
+	// Result is the result of the lookup, or nil if it failed
+	Result *Result
+
+	// Err is the error the lookup failed with, if any
+	Err error
+
+	// Cache is the part the cache played in the lookup
+	Cache CacheResult
+}
+
+// Metrics receives the measurements of every lookup performed by an API. ObserveLookup may be
+// called concurrently and should return quickly.
+type Metrics interface {
+	ObserveLookup(stats LookupStats)
+}
+
+// observe records a lookup's measurements on its span, and passes them to the API's Metrics if it
+// has any
+func (a *API) observe(span trace.Span, stats LookupStats) {
+	a.annotate(span, stats)
+	if a.Metrics != nil {
+		a.Metrics.ObserveLookup(stats)
+	}
+}
@@ -0,0 +1,56 @@
+package whatphone
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Normalize returns a phone number in E.164 form. Numbers without a country code are taken to be
+// North American, like they are by EveryoneAPI, so 10 digit numbers, and 11 digit numbers starting
+// with 1, are given the +1 country code. Spaces, dots, dashes, slashes and parentheses are ignored.
+func Normalize(phonenumber string) (string, error) {
+	s := strings.TrimSpace(phonenumber)
+	international := strings.HasPrefix(s, "+")
+	if international {
+		s = s[1:]
+	}
+
+	var digits strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case strings.ContainsRune(" .-/()", r):
+		default:
+			return "", fmt.Errorf("invalid phone number")
+		}
+	}
+
+	d := digits.String()
+	switch {
+	case international && strings.HasPrefix(d, "1") && len(d) == 11:
+	case international && !strings.HasPrefix(d, "1") && !strings.HasPrefix(d, "0") && len(d) >= 8 && len(d) <= 15:
+	case !international && len(d) == 10:
+		d = "1" + d
+	case !international && len(d) == 11 && d[0] == '1':
+	default:
+		return "", fmt.Errorf("invalid phone number")
+	}
+	return "+" + d, nil
+}
+
+// validNumber reports whether a phone number is safe to send to EveryoneAPI as is: it's made of
+// digits, an optional leading +, and the formatting characters Normalize ignores. Anything else,
+// like a ? or a bare .., could rewrite the request's path or query.
+func validNumber(phonenumber string) bool {
+	s := strings.TrimPrefix(phonenumber, "+")
+	if !strings.ContainsAny(s, "0123456789") {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && !strings.ContainsRune(" .-/()", r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package whatphone_test
+
+import (
+	"net/http"
+	"testing"
+
+	. "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		number string
+		want   string
+		ok     bool
+	}{
+		{"555-123-4567", "+15551234567", true},
+		{"+15551234567", "+15551234567", true},
+		{"(555) 1234567", "+15551234567", true},
+		{"1.555.123.4567", "+15551234567", true},
+		{" +1 (555) 123-4567 ", "+15551234567", true},
+		{"555/123 4567", "+15551234567", true},
+		{"+44 20 7946 0958", "+442079460958", true},
+		{"", "", false},
+		{"123", "", false},
+		{"25551234567", "", false},
+		{"+1555123456", "", false},
+		{"+0123456789", "", false},
+		{"555-123-4567 x12", "", false},
+		{"++15551234567", "", false},
+		{"+1234567890123456", "", false},
+	}
+
+	for _, test := range tests {
+		got, err := Normalize(test.number)
+		if (err == nil) != test.ok {
+			t.Errorf("Error: Normalize(%q) returned unexpected error: %v", test.number, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Error: Normalize(%q). Got: %s, Want: %s", test.number, got, test.want)
+		}
+	}
+}
+
+func TestLookupRejectsUnsafeNumbers(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	var paths []string
+	api := srv.API()
+	api.Hooks.BeforeRequest = func(req *http.Request) {
+		paths = append(paths, req.URL.EscapedPath()+"?"+req.URL.RawQuery)
+	}
+
+	for _, number := range []string{"", "..", "+", "../account", "5551234567?data=image", "5551234567#x", "5551234567&x"} {
+		if _, err := api.Lookup(number, WithName()); err == nil || err.Error() != "invalid phone number" {
+			t.Errorf("Error: Lookup(%q) returned unexpected error: %v", number, err)
+		}
+	}
+	if len(paths) != 0 {
+		t.Errorf("Error: Unsafe numbers were sent upstream: %v", paths)
+	}
+
+	// formatted numbers are escaped, so they can't add path segments
+	api.Lookup("555/123/4567", WithName())
+	if len(paths) != 1 || paths[0] != "/555%2F123%2F4567?data=name" {
+		t.Errorf("Error: Unexpected request. Got: %v, Want: [/555%%2F123%%2F4567?data=name]", paths)
+	}
+}
@@ -0,0 +1,224 @@
+package whatphone // import "samhofi.us/x/whatphone/pkg/api"
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Redaction rules, as named by ParseRedaction
+const (
+	RedactMaskNumbers     = "mask-numbers"
+	RedactDropImage       = "drop-image"
+	RedactTruncateAddress = "truncate-address"
+	RedactHashNames       = "hash-names"
+)
+
+// Redaction says which personal data is removed from lookup results. The zero value removes
+// nothing.
+type Redaction struct {
+	// MaskNumbers masks all but the last four digits of phone numbers, including the ones in line
+	// provider email addresses
+	MaskNumbers bool
+
+	// DropImage removes the image data point
+	DropImage bool
+
+	// TruncateAddress replaces the street address with the city and state of the number's
+	// location, or removes it when the location isn't known
+	TruncateAddress bool
+
+	// HashNames replaces names with a hash of them, so results about the same person can still be
+	// matched up without revealing who they are
+	HashNames bool
+
+	// Secret keys the hashes names are replaced with, and the ones NumberKey returns. Hashes made
+	// with the same secret can be matched up, across results and runs, which is what they're for,
+	// but that also means anyone holding the secret can confirm a guessed name or number by hashing
+	// it, so it should be kept like a password. When it's empty, a random secret is made for the
+	// life of the process, and hashes can't be matched up with those made by another process.
+	Secret string
+}
+
+// RedactAll applies every redaction rule
+var RedactAll = Redaction{MaskNumbers: true, DropImage: true, TruncateAddress: true, HashNames: true}
+
+// ParseRedaction parses a redaction policy given as a comma separated list of rules
+// (mask-numbers, drop-image, truncate-address, hash-names), "all", or "none"
+func ParseRedaction(policy string) (Redaction, error) {
+	var r Redaction
+	for _, rule := range strings.Split(policy, ",") {
+		switch strings.ToLower(strings.TrimSpace(rule)) {
+		case "", "none":
+		case "all":
+			r = RedactAll
+		case RedactMaskNumbers:
+			r.MaskNumbers = true
+		case RedactDropImage:
+			r.DropImage = true
+		case RedactTruncateAddress:
+			r.TruncateAddress = true
+		case RedactHashNames:
+			r.HashNames = true
+		default:
+			return Redaction{}, fmt.Errorf("unknown redaction rule: %s", strings.TrimSpace(rule))
+		}
+	}
+	return r, nil
+}
+
+// String returns the rules of a redaction as a comma separated list, or "none"
+func (r Redaction) String() string {
+	var rules []string
+	for _, rule := range []struct {
+		name string
+		set  bool
+	}{
+		{RedactMaskNumbers, r.MaskNumbers},
+		{RedactDropImage, r.DropImage},
+		{RedactTruncateAddress, r.TruncateAddress},
+		{RedactHashNames, r.HashNames},
+	} {
+		if rule.set {
+			rules = append(rules, rule.name)
+		}
+	}
+	if len(rules) == 0 {
+		return "none"
+	}
+	return strings.Join(rules, ",")
+}
+
+// IsZero reports whether a redaction removes nothing
+func (r Redaction) IsZero() bool {
+	return r == Redaction{Secret: r.Secret}
+}
+
+// Number returns a phone number as it should appear once redacted
+func (r Redaction) Number(phonenumber string) string {
+	if !r.MaskNumbers {
+		return phonenumber
+	}
+	return MaskNumber(phonenumber)
+}
+
+// NumberKey returns what a phone number is kept as where it has to be told apart from other
+// numbers, like checkpoints: the number itself, or a hash of it in E.164 form when numbers are
+// masked
+func (r Redaction) NumberKey(phonenumber string) string {
+	if !r.MaskNumbers {
+		return phonenumber
+	}
+	if n, err := Normalize(phonenumber); err == nil {
+		phonenumber = n
+	}
+	return r.hash(phonenumber)
+}
+
+// Text returns text, like a command line, with the phone numbers in it masked as they should be
+func (r Redaction) Text(s string) string {
+	if !r.MaskNumbers {
+		return s
+	}
+	return numberPattern.ReplaceAllStringFunc(s, MaskNumber)
+}
+
+// Apply returns a redacted copy of a result, leaving the result itself untouched. Raw is encoded
+// again from the redacted result, and fields this package doesn't know about are dropped, since
+// there's no telling what they hold.
+func (r Redaction) Apply(result *Result) *Result {
+	if result == nil || r.IsZero() {
+		return result
+	}
+
+	ret := *result
+	ret.Extra = nil
+	ret.Data.Extra = nil
+	ret.Missed = append([]string(nil), result.Missed...)
+	ret.Warnings = append([]Warning(nil), result.Warnings...)
+	d := &ret.Data
+
+	if r.MaskNumbers {
+		ret.Number = MaskNumber(ret.Number)
+		if lp := d.LineProvider; lp != nil {
+			d.LineProvider = &LineProvider{
+				ID:       lp.ID,
+				MmsEmail: numberPattern.ReplaceAllStringFunc(lp.MmsEmail, MaskNumber),
+				Name:     lp.Name,
+				SmsEmail: numberPattern.ReplaceAllStringFunc(lp.SmsEmail, MaskNumber),
+			}
+		}
+	}
+
+	if r.DropImage {
+		d.Image = nil
+	}
+
+	if r.TruncateAddress && d.Address != nil {
+		d.Address = nil
+		if city, state := d.City(), d.State(); city != "" || state != "" {
+			d.Address = cityState(city, state)
+		}
+	}
+
+	if r.HashNames {
+		d.Name = r.hashName(d.Name)
+		d.Cnam = r.hashName(d.Cnam)
+		if n := d.ExpandedName; n != nil {
+			d.ExpandedName = &ExpandedName{First: *r.hashName(&n.First), Last: *r.hashName(&n.Last)}
+		}
+		if p := d.Profile; p != nil && p.Relationship != "" {
+			d.Profile = &Profile{Edu: p.Edu, Job: p.Job, Relationship: *r.hashName(&p.Relationship)}
+		}
+	}
+
+	ret.Raw = nil
+	if raw, err := json.Marshal(&ret); err == nil {
+		ret.Raw = raw
+	}
+	return &ret
+}
+
+// cityState returns a city and state as "City, ST", leaving out whichever is empty
+func cityState(city, state string) *string {
+	s := strings.Trim(city+", "+state, ", ")
+	return &s
+}
+
+// hashPrefix starts every hash
+const hashPrefix = "hmac-sha256:"
+
+// processSecret keys the hashes made by redactions without a secret of their own
+var processSecret = sync.OnceValue(func() []byte {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return b
+})
+
+// hash returns the first 32 hex digits of the HMAC-SHA256 of s, keyed with the redaction's secret
+func (r Redaction) hash(s string) string {
+	secret := []byte(r.Secret)
+	if len(secret) == 0 {
+		secret = processSecret()
+	}
+	mac := hmac.New(sha256.New, secret)
+	io.WriteString(mac, s)
+	return hashPrefix + hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// hashName replaces a name with its hash, ignoring case and surrounding whitespace so different
+// spellings of the same name, like a CNAM in capitals, hash the same. Empty names, and names that
+// were already hashed, are left alone, so redacting a result twice changes nothing.
+func (r Redaction) hashName(name *string) *string {
+	if name == nil || *name == "" || strings.HasPrefix(*name, hashPrefix) {
+		return name
+	}
+	hashed := r.hash(strings.ToLower(strings.TrimSpace(*name)))
+	return &hashed
+}
@@ -0,0 +1,225 @@
+package whatphone_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	. "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func TestParseRedaction(t *testing.T) {
+	tests := []struct {
+		policy   string
+		expected Redaction
+	}{
+		{"", Redaction{}},
+		{"none", Redaction{}},
+		{"all", RedactAll},
+		{"mask-numbers", Redaction{MaskNumbers: true}},
+		{"Drop-Image, hash-names", Redaction{DropImage: true, HashNames: true}},
+		{"truncate-address,all", RedactAll},
+	}
+	for _, test := range tests {
+		got, err := ParseRedaction(test.policy)
+		if err != nil {
+			t.Errorf("Error: ParseRedaction(%q) returned error: %v", test.policy, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("Error: Unexpected redaction for %q. Got: %+v, Want: %+v", test.policy, got, test.expected)
+		}
+	}
+
+	if _, err := ParseRedaction("mask-numbers,blur"); err == nil || err.Error() != "unknown redaction rule: blur" {
+		t.Errorf("Error: Unexpected error for an unknown rule: %v", err)
+	}
+}
+
+func TestRedactionString(t *testing.T) {
+	for _, policy := range []string{"none", "mask-numbers", "drop-image,hash-names", "mask-numbers,drop-image,truncate-address,hash-names"} {
+		r, err := ParseRedaction(policy)
+		if err != nil {
+			t.Fatalf("Error: ParseRedaction(%q) returned error: %v", policy, err)
+		}
+		if r.String() != policy {
+			t.Errorf("Error: Unexpected string. Got: %s, Want: %s", r.String(), policy)
+		}
+	}
+}
+
+// redactionSecret keys the hashes made by redactions in tests
+const redactionSecret = "s3cret"
+
+// hashOf returns the hash names are replaced with, for a name already folded to lower case
+func hashOf(name string) string {
+	mac := hmac.New(sha256.New, []byte(redactionSecret))
+	mac.Write([]byte(name))
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// sampleResult returns a result holding every data point of the sample number
+func sampleResult(t *testing.T) *Result {
+	t.Helper()
+	srv := apitest.NewServer(apitest.WithExtraData(apitest.SampleNumber, "email", "michael@example.com"))
+	defer srv.Close()
+	result, err := srv.API().Lookup(apitest.SampleNumber)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	return result
+}
+
+func TestRedactionApply(t *testing.T) {
+	result := sampleResult(t)
+	original, _ := json.Marshal(result)
+
+	all := RedactAll
+	all.Secret = redactionSecret
+	redacted := all.Apply(result)
+	d := redacted.Data
+	checks := []struct {
+		field, got, want string
+	}{
+		{"number", redacted.Number, "+*******4567"},
+		{"sms email", d.LineProvider.SmsEmail, "******4567@sms.mysticvoice.com"},
+		{"address", d.GetAddress(), "Long Island, NY"},
+		{"name", d.GetName(), hashOf("michael seaver")},
+		{"cnam", d.GetCNAM(), hashOf("michael seaver")},
+		{"first name", d.FirstName(), hashOf("michael")},
+		{"relationship", d.Profile.Relationship, hashOf("april lerman")},
+		{"job", d.Profile.Job, "Custodian"},
+		{"carrier", d.CarrierName(), "Growing Wireless Inc."},
+		{"city", d.City(), "Long Island"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("Error: Unexpected redacted %s. Got: %s, Want: %s", c.field, c.got, c.want)
+		}
+	}
+	if d.Image != nil || d.Extra != nil {
+		t.Errorf("Error: Image and unknown data points should be dropped: %v %v", d.Image, d.Extra)
+	}
+
+	for _, leak := range []string{"5551234567", "Seaver", "SEAVER", "Robin Hood", "teloimg", "michael@example.com"} {
+		if strings.Contains(string(redacted.Raw), leak) {
+			t.Errorf("Error: %q leaked into the redacted raw response: %s", leak, redacted.Raw)
+		}
+	}
+
+	// the result itself is untouched
+	if after, _ := json.Marshal(result); !bytes.Equal(after, original) || result.Data.Extra == nil {
+		t.Errorf("Error: Apply modified the result it was given:\n%s", after)
+	}
+	if twice := all.Apply(redacted); !bytes.Equal(twice.Raw, redacted.Raw) {
+		t.Errorf("Error: Redacting twice changed the result.\nOnce: %s\nTwice: %s", redacted.Raw, twice.Raw)
+	}
+	if (Redaction{Secret: redactionSecret}).Apply(result) != result {
+		t.Errorf("Error: An empty redaction should return the result as is")
+	}
+}
+
+func TestRedactionSecret(t *testing.T) {
+	name := "Michael Seaver"
+	hashed := func(r Redaction) string {
+		return r.Apply(&Result{Data: Data{Name: &name}}).Data.GetName()
+	}
+
+	a := hashed(Redaction{HashNames: true, Secret: "a"})
+	if a != hashed(Redaction{HashNames: true, Secret: "a"}) {
+		t.Errorf("Error: The same secret should hash a name the same")
+	}
+	if a == hashed(Redaction{HashNames: true, Secret: "b"}) {
+		t.Errorf("Error: Different secrets should hash a name differently")
+	}
+
+	// without a secret, names are hashed with a random one that can't be guessed
+	random := hashed(Redaction{HashNames: true})
+	if random != hashed(Redaction{HashNames: true}) {
+		t.Errorf("Error: Hashes made without a secret should match within a process")
+	}
+	unkeyed := hmac.New(sha256.New, nil)
+	unkeyed.Write([]byte("michael seaver"))
+	if !strings.HasPrefix(random, "hmac-sha256:") || strings.Contains(random, hex.EncodeToString(unkeyed.Sum(nil))[:32]) {
+		t.Errorf("Error: Unexpected hash without a secret: %s", random)
+	}
+}
+
+func TestRedactionNumberKey(t *testing.T) {
+	r := Redaction{MaskNumbers: true, Secret: redactionSecret}
+	key := r.NumberKey("+15557654321")
+	if !strings.HasPrefix(key, "hmac-sha256:") || strings.Contains(key, "5557654321") {
+		t.Errorf("Error: Unexpected key: %s", key)
+	}
+	if r.NumberKey("(555) 765-4321") != key {
+		t.Errorf("Error: Numbers should be normalized before they're hashed")
+	}
+	if r.NumberKey("+15551234567") == key {
+		t.Errorf("Error: Different numbers should have different keys")
+	}
+	if got := (Redaction{}).NumberKey("5557654321"); got != "5557654321" {
+		t.Errorf("Error: Numbers should be kept as is when they aren't masked. Got: %s", got)
+	}
+
+	if got, want := r.Text("lookup -n 5557654321 --input numbers.txt"), "lookup -n ******4321 --input numbers.txt"; got != want {
+		t.Errorf("Error: Unexpected text. Got: %s, Want: %s", got, want)
+	}
+}
+
+func TestRedactionAddressWithoutLocation(t *testing.T) {
+	address := "15 Robin Hood Lane"
+	result := &Result{Data: Data{Address: &address}}
+	if got := (Redaction{TruncateAddress: true}).Apply(result); got.Data.Address != nil {
+		t.Errorf("Error: An address without a location should be removed. Got: %s", *got.Data.Address)
+	}
+}
+
+func TestLookupRedaction(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	api := srv.API()
+	api.Redaction = Redaction{MaskNumbers: true, HashNames: true, Secret: redactionSecret}
+	api.Cache = NewMemoryCache(time.Minute, 0)
+	api.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	for i := 0; i < 2; i++ {
+		result, err := api.Lookup(apitest.SampleNumber, WithName(), WithCNAM())
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if result.Number != "+*******4567" || result.Data.GetName() != hashOf("michael seaver") {
+			t.Errorf("Error: Lookup %d returned an unredacted result: %s", i+1, result.Raw)
+		}
+	}
+	if srv.Requests() != 1 {
+		t.Errorf("Error: The second lookup should be answered from the cache")
+	}
+
+	for _, leak := range []string{"5551234567", "Seaver", "SEAVER"} {
+		if strings.Contains(buf.String(), leak) {
+			t.Errorf("Error: %q leaked into logs:\n%s", leak, buf.String())
+		}
+	}
+	if !strings.Contains(buf.String(), "hmac-sha256:") {
+		t.Errorf("Error: The redacted response body wasn't logged:\n%s", buf.String())
+	}
+}
+
+func TestLookupRedactionNetworkError(t *testing.T) {
+	api := New("sid", "token")
+	api.BaseURL = "http://127.0.0.1:1/"
+	api.Redaction = Redaction{MaskNumbers: true}
+	_, err := api.Lookup(apitest.SampleNumber)
+	if err == nil || strings.Contains(err.Error(), "5551234567") {
+		t.Errorf("Error: The phone number leaked into the error: %v", err)
+	}
+}
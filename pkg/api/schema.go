@@ -0,0 +1,214 @@
+package whatphone // import "samhofi.us/x/whatphone/pkg/api"
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// WarningKind identifies the kind of problem a schema Warning describes
+type WarningKind int
+
+const (
+	// TypeMismatch means a field was returned with a different type than the one declared
+	TypeMismatch WarningKind = iota
+
+	// UnknownField means a field was returned that isn't declared at all
+	UnknownField
+)
+
+// String returns the name of a WarningKind
+func (k WarningKind) String() string {
+	switch k {
+	case TypeMismatch:
+		return "type mismatch"
+	case UnknownField:
+		return "unknown field"
+	default:
+		return "unknown"
+	}
+}
+
+// Warning describes a way a response didn't match the declared schema. Warnings don't stop a
+// response from being decoded.
+type Warning struct {
+	// Path is the dotted path of the offending field, e.g. "pricing.breakdown.cnam"
+	Path string
+
+	Kind WarningKind
+
+	// Expected is the declared JSON type of the field, and is empty for unknown fields
+	Expected string
+
+	// Got is the JSON type that was actually returned
+	Got string
+}
+
+// String returns a human readable description of a Warning
+func (w Warning) String() string {
+	if w.Kind == TypeMismatch {
+		return fmt.Sprintf("%s: %s: expected %s, got %s", w.Path, w.Kind, w.Expected, w.Got)
+	}
+	return fmt.Sprintf("%s: %s (%s)", w.Path, w.Kind, w.Got)
+}
+
+var resultType = reflect.TypeOf(Result{})
+
+// Validate checks a lookup response body against the schema declared by Result, returning a
+// Warning for each field of an unexpected type and each field that isn't declared. An error is
+// only returned if the body isn't valid JSON.
+func Validate(body []byte) ([]Warning, error) {
+	_, warnings, err := check(body)
+	return warnings, err
+}
+
+// check validates a lookup response body like Validate, also returning the decoded body with
+// every field of an unexpected type removed
+func check(body []byte) (any, []Warning, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, nil, err
+	}
+
+	var warnings []Warning
+	validate("", v, resultType, &warnings)
+	return v, warnings, nil
+}
+
+// validate compares the decoded JSON value v against the Go type t, appending any problems to
+// warnings and removing the mismatched fields from v. It reports whether v itself matches t.
+// Nulls are accepted anywhere, since they leave the field at its zero value.
+func validate(path string, v any, t reflect.Type, warnings *[]Warning) bool {
+	if v == nil {
+		return true
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	mismatch := func() bool {
+		*warnings = append(*warnings, Warning{Path: path, Kind: TypeMismatch, Expected: schemaType(t), Got: jsonType(v)})
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return mismatch()
+		}
+
+		fields := make(map[string]reflect.Type)
+		for i := 0; i < t.NumField(); i++ {
+			if name := jsonName(t.Field(i)); name != "" {
+				fields[name] = t.Field(i).Type
+			}
+		}
+
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			ft, ok := fields[k]
+			if !ok {
+				*warnings = append(*warnings, Warning{Path: join(path, k), Kind: UnknownField, Got: jsonType(obj[k])})
+				continue
+			}
+			if !validate(join(path, k), obj[k], ft, warnings) {
+				delete(obj, k)
+			}
+		}
+
+	case reflect.Slice:
+		arr, ok := v.([]any)
+		if !ok {
+			return mismatch()
+		}
+		for i, e := range arr {
+			if !validate(fmt.Sprintf("%s[%d]", path, i), e, t.Elem(), warnings) {
+				arr[i] = nil
+			}
+		}
+
+	case reflect.String:
+		if _, ok := v.(string); !ok {
+			return mismatch()
+		}
+
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			return mismatch()
+		}
+
+	case reflect.Float64:
+		if _, ok := v.(json.Number); !ok {
+			return mismatch()
+		}
+
+	case reflect.Int:
+		n, ok := v.(json.Number)
+		if !ok {
+			return mismatch()
+		}
+		if f, err := n.Float64(); err != nil || f != math.Trunc(f) {
+			return mismatch()
+		}
+	}
+	return true
+}
+
+// schemaType returns the JSON type a Go type is declared to be decoded from
+func schemaType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.Slice:
+		return "array"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int:
+		return "integer"
+	case reflect.Float64:
+		return "number"
+	default:
+		return t.Kind().String()
+	}
+}
+
+// jsonType returns the JSON type of a value decoded with UseNumber
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	default:
+		return "null"
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,136 @@
+package whatphone_test
+
+import (
+	"bytes"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Warning
+	}{
+		{
+			name: "valid",
+			body: `{"data": {"name": "Jane Doe", "gender": null, "location": {"geo": {"latitude": "40.7"}}}, "missed": ["cnam"], "pricing": {"breakdown": {"cnam": 0.01, "expanded_name": 1}, "total": 0.01}, "status": true}`,
+		},
+		{
+			name: "type mismatches",
+			body: `{"data": {"name": 7, "location": {"geo": {"latitude": 40.7}}}, "missed": "cnam", "pricing": {"breakdown": {"cnam": "0.01", "expanded_name": 0.5}}}`,
+			want: []Warning{
+				{Path: "data.location.geo.latitude", Kind: TypeMismatch, Expected: "string", Got: "number"},
+				{Path: "data.name", Kind: TypeMismatch, Expected: "string", Got: "integer"},
+				{Path: "missed", Kind: TypeMismatch, Expected: "array", Got: "string"},
+				{Path: "pricing.breakdown.cnam", Kind: TypeMismatch, Expected: "number", Got: "string"},
+			},
+		},
+		{
+			name: "unknown fields",
+			body: `{"data": {"spam_score": {"score": 7}}, "request_id": "abc", "missed": [1]}`,
+			want: []Warning{
+				{Path: "data.spam_score", Kind: UnknownField, Got: "object"},
+				{Path: "missed[0]", Kind: TypeMismatch, Expected: "string", Got: "integer"},
+				{Path: "request_id", Kind: UnknownField, Got: "string"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		got, err := Validate([]byte(test.body))
+		if err != nil {
+			t.Errorf("Error: %s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Error: %s: Unexpected warnings.\nGot: %v\nWant: %v", test.name, got, test.want)
+		}
+	}
+
+	if _, err := Validate([]byte(`{"data": `)); err == nil {
+		t.Errorf("Error: invalid JSON should have returned an error")
+	}
+}
+
+func TestWarningString(t *testing.T) {
+	tests := []struct {
+		warning Warning
+		want    string
+	}{
+		{Warning{Path: "pricing.total", Kind: TypeMismatch, Expected: "number", Got: "string"}, "pricing.total: type mismatch: expected number, got string"},
+		{Warning{Path: "request_id", Kind: UnknownField, Got: "string"}, "request_id: unknown field (string)"},
+	}
+
+	for _, test := range tests {
+		if got := test.warning.String(); got != test.want {
+			t.Errorf("Error: Unexpected string. Got: %s, Want: %s", got, test.want)
+		}
+	}
+}
+
+func TestStrict(t *testing.T) {
+	srv := apitest.NewServer(apitest.WithExtraData(apitest.SampleNumber, "spam_score", 7))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	api := srv.API()
+	api.Logger = slog.New(slog.NewTextHandler(&buf, nil))
+
+	res, err := api.Lookup(apitest.SampleNumber)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if res.Warnings != nil {
+		t.Errorf("Error: warnings should only be reported in strict mode. Got: %v", res.Warnings)
+	}
+
+	api.Strict = true
+	res, err = api.Lookup(apitest.SampleNumber)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	want := []Warning{{Path: "data.spam_score", Kind: UnknownField, Got: "integer"}}
+	if !reflect.DeepEqual(res.Warnings, want) {
+		t.Errorf("Error: Unexpected warnings. Got: %v, Want: %v", res.Warnings, want)
+	}
+	if !strings.Contains(buf.String(), "path=data.spam_score") {
+		t.Errorf("Error: warning wasn't logged:\n%s", buf.String())
+	}
+}
+
+func TestStrictMistyped(t *testing.T) {
+	srv := apitest.NewServer(apitest.WithExtraData(apitest.SampleNumber, "name", 123))
+	defer srv.Close()
+
+	api := srv.API()
+	if _, err := api.Lookup(apitest.SampleNumber, WithName(), WithCarrier()); err == nil {
+		t.Errorf("Error: mistyped field should fail the lookup outside strict mode but didn't")
+	}
+
+	api.Strict = true
+	res, err := api.Lookup(apitest.SampleNumber, WithName(), WithCarrier())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	want := []Warning{{Path: "data.name", Kind: TypeMismatch, Expected: "string", Got: "integer"}}
+	if !reflect.DeepEqual(res.Warnings, want) {
+		t.Errorf("Error: Unexpected warnings. Got: %v, Want: %v", res.Warnings, want)
+	}
+	if res.Data.Name != nil {
+		t.Errorf("Error: mistyped name should have been dropped. Got: %q", *res.Data.Name)
+	}
+	if res.Data.Carrier == nil {
+		t.Errorf("Error: well typed fields should still be decoded. Got: %+v", res.Data)
+	}
+	if !strings.Contains(string(res.Raw), `"name":123`) {
+		t.Errorf("Error: Raw should hold the original body. Got: %s", res.Raw)
+	}
+}
@@ -0,0 +1,141 @@
+package whatphone // import "samhofi.us/x/whatphone/pkg/api"
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// UnmarshalJSON decodes a pricing breakdown, accepting prices encoded as either numbers or strings
+func (b *Breakdown) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(b).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonName(t.Field(i))
+		raw, ok := fields[name]
+		if !ok {
+			continue
+		}
+
+		price, err := looseFloat(raw)
+		if err != nil {
+			return fmt.Errorf("breakdown.%s: %v", name, err)
+		}
+
+		v.Field(i).SetFloat(price)
+	}
+
+	return nil
+}
+
+// UnmarshalJSON decodes pricing data, accepting a total encoded as either a number or a string
+func (p *Pricing) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Breakdown Breakdown       `json:"breakdown"`
+		Total     json.RawMessage `json:"total"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	total, err := looseFloat(raw.Total)
+	if err != nil {
+		return fmt.Errorf("pricing.total: %v", err)
+	}
+
+	p.Breakdown = raw.Breakdown
+	p.Total = total
+	return nil
+}
+
+// UnmarshalJSON decodes geographical data, accepting coordinates encoded as either strings or numbers
+func (g *Geo) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Latitude  json.RawMessage `json:"latitude"`
+		Longitude json.RawMessage `json:"longitude"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	lat, err := looseString(raw.Latitude)
+	if err != nil {
+		return fmt.Errorf("geo.latitude: %v", err)
+	}
+	long, err := looseString(raw.Longitude)
+	if err != nil {
+		return fmt.Errorf("geo.longitude: %v", err)
+	}
+
+	g.Latitude = lat
+	g.Longitude = long
+	return nil
+}
+
+// looseFloat decodes a JSON number, or a string holding one. Missing values, nulls and empty
+// strings decode to 0.
+func looseFloat(raw json.RawMessage) (float64, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, nil
+	}
+
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return 0, err
+		}
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return 0, nil
+		}
+		return strconv.ParseFloat(s, 64)
+	}
+
+	var f float64
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return 0, fmt.Errorf("expected a number, got %s", raw)
+	}
+	return f, nil
+}
+
+// looseString decodes a JSON string, or a number as it was written. Missing values and nulls
+// decode to an empty string.
+func looseString(raw json.RawMessage) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+
+	if raw[0] == '"' {
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return "", fmt.Errorf("expected a string or number, got %s", raw)
+	}
+	return n.String(), nil
+}
+
+// jsonName returns the name a struct field is encoded as, or an empty string if it's never encoded
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
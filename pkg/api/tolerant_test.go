@@ -0,0 +1,82 @@
+package whatphone_test
+
+import (
+	"testing"
+
+	. "samhofi.us/x/whatphone/pkg/api"
+)
+
+func TestTolerantDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		cnam     float64
+		expanded float64
+		total    float64
+		lat      string
+		long     string
+	}{
+		{
+			name:     "numbers",
+			body:     `{"data": {"location": {"geo": {"latitude": "40.7", "longitude": "-74.0"}}}, "pricing": {"breakdown": {"cnam": 0.01, "expanded_name": 1}, "total": 0.01}}`,
+			cnam:     .01,
+			expanded: 1,
+			total:    .01,
+			lat:      "40.7",
+			long:     "-74.0",
+		},
+		{
+			name:     "strings",
+			body:     `{"data": {"location": {"geo": {"latitude": 40.7, "longitude": -74.0}}}, "pricing": {"breakdown": {"cnam": "0.01", "expanded_name": "1"}, "total": "0.01"}}`,
+			cnam:     .01,
+			expanded: 1,
+			total:    .01,
+			lat:      "40.7",
+			long:     "-74.0",
+		},
+		{
+			name:     "fractional expanded name",
+			body:     `{"pricing": {"breakdown": {"expanded_name": 0.6}}}`,
+			expanded: .6,
+		},
+		{
+			name: "empty and null",
+			body: `{"data": {"location": {"geo": {"latitude": null}}}, "pricing": {"breakdown": {"cnam": "", "expanded_name": null}, "total": null}}`,
+		},
+	}
+
+	for _, test := range tests {
+		res, err := Decode([]byte(test.body))
+		if err != nil {
+			t.Errorf("Error: %s: %v", test.name, err)
+			continue
+		}
+
+		b := res.Pricing.Breakdown
+		if b.Cnam != test.cnam || b.ExpandedName != test.expanded || res.Pricing.Total != test.total {
+			t.Errorf("Error: %s: Unexpected prices. Got: %v/%v/%v, Want: %v/%v/%v", test.name, b.Cnam, b.ExpandedName, res.Pricing.Total, test.cnam, test.expanded, test.total)
+		}
+
+		var lat, long string
+		if res.Data.Location != nil {
+			lat, long = res.Data.Location.Geo.Latitude, res.Data.Location.Geo.Longitude
+		}
+		if lat != test.lat || long != test.long {
+			t.Errorf("Error: %s: Unexpected coordinates. Got: %s,%s, Want: %s,%s", test.name, lat, long, test.lat, test.long)
+		}
+	}
+}
+
+func TestTolerantDecodeErrors(t *testing.T) {
+	tests := []string{
+		`{"pricing": {"breakdown": {"cnam": "free"}}}`,
+		`{"pricing": {"total": true}}`,
+		`{"data": {"location": {"geo": {"latitude": [40.7]}}}}`,
+	}
+
+	for _, test := range tests {
+		if _, err := Decode([]byte(test)); err == nil {
+			t.Errorf("Error: %s should have returned an error", test)
+		}
+	}
+}
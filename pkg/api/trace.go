@@ -0,0 +1,60 @@
+package whatphone // import "samhofi.us/x/whatphone/pkg/api"
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	// Instrumentation scope of the tracer used for lookup spans
+	tracerName = "samhofi.us/x/whatphone/pkg/api"
+
+	// Name of the span created for each lookup
+	spanName = "whatphone.Lookup"
+)
+
+// Attributes set on lookup spans
+const (
+	AttrNumber     = attribute.Key("whatphone.number")
+	AttrDataPoints = attribute.Key("whatphone.data_points")
+	AttrStatusCode = attribute.Key("http.response.status_code")
+	AttrMissed     = attribute.Key("whatphone.missed")
+	AttrPriceTotal = attribute.Key("whatphone.price.total")
+	AttrCache      = attribute.Key("whatphone.cache")
+)
+
+// tracer returns the tracer used to create lookup spans
+func (a *API) tracer() trace.Tracer {
+	if a.TracerProvider == nil {
+		return noop.NewTracerProvider().Tracer(tracerName)
+	}
+	return a.TracerProvider.Tracer(tracerName)
+}
+
+// annotate records a lookup's measurements as attributes on its span
+func (a *API) annotate(span trace.Span, stats LookupStats) {
+	if !span.IsRecording() {
+		return
+	}
+
+	span.SetAttributes(
+		AttrNumber.String(a.logNumber(stats.Number)),
+		AttrDataPoints.StringSlice(stats.Data),
+		AttrCache.String(stats.Cache.String()),
+	)
+	if stats.Status != 0 {
+		span.SetAttributes(AttrStatusCode.Int(stats.Status))
+	}
+	if stats.Result != nil {
+		span.SetAttributes(
+			AttrMissed.StringSlice(stats.Result.Missed),
+			AttrPriceTotal.Float64(stats.Result.Pricing.Total),
+		)
+	}
+	if stats.Err != nil {
+		span.RecordError(stats.Err)
+		span.SetStatus(codes.Error, stats.Err.Error())
+	}
+}
@@ -0,0 +1,83 @@
+package whatphone_test
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	. "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+// spanAttrs returns a span's attributes keyed by name
+func spanAttrs(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracing(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	api := srv.API()
+	api.TracerProvider = tp
+	api.Cache = NewMemoryCache(time.Minute, 0)
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	for i := 0; i < 2; i++ {
+		if _, err := api.LookupContext(ctx, apitest.SampleNumber, WithName(), WithGender()); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	parent.End()
+
+	srv.FailNext(1, http.StatusServiceUnavailable)
+	api.Lookup(apitest.SampleNumber, WithCarrier())
+
+	spans := exporter.GetSpans()
+	if len(spans) != 4 {
+		t.Fatalf("Error: Unexpected number of spans. Got: %d, Want: 4", len(spans))
+	}
+	miss, hit, failed := spans[0], spans[1], spans[3]
+
+	if miss.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("Error: lookup span is not a child of the span in its context")
+	}
+
+	attrs := spanAttrs(miss)
+	if got := attrs[AttrDataPoints].AsStringSlice(); !reflect.DeepEqual(got, []string{"name", "gender"}) {
+		t.Errorf("Error: Unexpected data points. Got: %v", got)
+	}
+	if got := attrs[AttrStatusCode].AsInt64(); got != 200 {
+		t.Errorf("Error: Unexpected status code. Got: %d, Want: 200", got)
+	}
+	if want := -(apitest.Prices.Name + apitest.Prices.Gender); attrs[AttrPriceTotal].AsFloat64() != want {
+		t.Errorf("Error: Unexpected total price. Got: %v, Want: %v", attrs[AttrPriceTotal].AsFloat64(), want)
+	}
+	if got := attrs[AttrCache].AsString(); got != "miss" {
+		t.Errorf("Error: Unexpected cache attribute. Got: %s, Want: miss", got)
+	}
+	if got := attrs[AttrMissed].AsStringSlice(); len(got) != 0 {
+		t.Errorf("Error: Unexpected missed fields. Got: %v", got)
+	}
+
+	if got := spanAttrs(hit)[AttrCache].AsString(); got != "hit" {
+		t.Errorf("Error: Unexpected cache attribute. Got: %s, Want: hit", got)
+	}
+
+	if failed.Status.Code != codes.Error {
+		t.Errorf("Error: failed lookup span doesn't have an error status: %v", failed.Status)
+	}
+}
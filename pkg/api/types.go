@@ -1,93 +1,133 @@
 package whatphone // import "samhofi.us/x/whatphone/pkg/api"
 
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
 // API holds everyoneapi authentication information
 type API struct {
 	AccountSID string
 	AuthToken  string
+
+	// BaseURL overrides the EveryoneAPI phone endpoint, which is useful for
+	// pointing the client at a local stand-in. Leave empty to use EveryoneAPI.
+	BaseURL string `json:",omitempty"`
+
+	// Client is the HTTP client used to perform lookups. Set its Transport to record, replay, or
+	// otherwise intercept requests. Leave nil to use http.DefaultClient.
+	Client *http.Client `json:"-"`
+
+	// Cache, when set, is checked before performing a lookup and stores every successful result
+	Cache Cache `json:"-"`
+
+	// Metrics, when set, is given measurements of every lookup
+	Metrics Metrics `json:"-"`
+
+	// Logger, when set, logs every lookup request. Credentials are never logged. Requests and their
+	// timing are logged at the info level, and raw response bodies at the debug level.
+	Logger *slog.Logger `json:"-"`
+
+	// Redaction is applied to every result before it's cached or returned, and to the phone
+	// numbers and response bodies that are logged or traced
+	Redaction Redaction `json:"-"`
+
+	// Hooks are called as each lookup request progresses
+	Hooks Hooks `json:"-"`
+
+	// TracerProvider, when set, is used to create a span for every lookup. The span is a child of
+	// any span in the context passed to LookupContext.
+	TracerProvider trace.TracerProvider `json:"-"`
+
+	// Strict validates every response against the schema declared by Result. Fields of an
+	// unexpected type and undeclared fields are logged and reported in Result.Warnings, but never
+	// cause a lookup to fail; fields of an unexpected type are left at their zero value.
+	Strict bool `json:"-"`
 }
 
-// fields holds a list of fields to request from the API
-type fields []string
+// Hooks are called at each stage of a lookup request sent to EveryoneAPI. Any of them may be nil.
+// Hooks are passed the actual request, including its Authorization header, so take care not to
+// log it.
+type Hooks struct {
+	// BeforeRequest is called just before the request is sent
+	BeforeRequest func(req *http.Request)
+
+	// AfterResponse is called once the response's body has been read, with how long the request took
+	AfterResponse func(req *http.Request, resp *http.Response, body []byte, elapsed time.Duration)
+
+	// OnError is called when the request fails, whether it got no response, an unsuccessful
+	// status code, or a body that couldn't be decoded
+	OnError func(req *http.Request, err error)
+}
 
-// Option adds a field to the list of fields being requested from the API.
-// See "Data Points" section at https://www.everyoneapi.com/docs for more info
-type Option func(f *fields)
+// Option adds data points to the set being requested from the API. Adding a data point more than
+// once has no effect. See "Data Points" section at https://www.everyoneapi.com/docs for more info
+type Option func(s *DataPointSet)
+
+// WithDataPoints adds the given data points to the set being requested from the API
+func WithDataPoints(dps ...DataPoint) Option {
+	return func(s *DataPointSet) {
+		s.Add(dps...)
+	}
+}
 
 // WithName adds the "name" field to the list of fields being requested from the API
 func WithName() Option {
-	return func(f *fields) {
-		*f = append(*f, "name")
-	}
+	return WithDataPoints(DataPointName)
 }
 
 // WithProfile adds the "profile" field to the list of fields being requested from the API
 func WithProfile() Option {
-	return func(f *fields) {
-		*f = append(*f, "profile")
-	}
+	return WithDataPoints(DataPointProfile)
 }
 
 // WithCNAM adds the "cnam" field to the list of fields being requested from the API
 func WithCNAM() Option {
-	return func(f *fields) {
-		*f = append(*f, "cnam")
-	}
+	return WithDataPoints(DataPointCNAM)
 }
 
 // WithGender adds the "gender" field to the list of fields being requested from the API
 func WithGender() Option {
-	return func(f *fields) {
-		*f = append(*f, "gender")
-	}
+	return WithDataPoints(DataPointGender)
 }
 
 // WithImage adds the "image" field to the list of fields being requested from the API
 func WithImage() Option {
-	return func(f *fields) {
-		*f = append(*f, "image")
-	}
+	return WithDataPoints(DataPointImage)
 }
 
 // WithAddress adds the "address" field to the list of fields being requested from the API
 func WithAddress() Option {
-	return func(f *fields) {
-		*f = append(*f, "address")
-	}
+	return WithDataPoints(DataPointAddress)
 }
 
 // WithLocation adds the "location" field to the list of fields being requested from the API
 func WithLocation() Option {
-	return func(f *fields) {
-		*f = append(*f, "location")
-	}
+	return WithDataPoints(DataPointLocation)
 }
 
 // WithLineProvider adds the "line_provider" field to the list of fields being requested from the API
 func WithLineProvider() Option {
-	return func(f *fields) {
-		*f = append(*f, "line_provider")
-	}
+	return WithDataPoints(DataPointLineProvider)
 }
 
 // WithCarrier adds the "carrier" field to the list of fields being requested from the API
 func WithCarrier() Option {
-	return func(f *fields) {
-		*f = append(*f, "carrier")
-	}
+	return WithDataPoints(DataPointCarrier)
 }
 
 // WithOriginalcarrier adds the "carrier_o" field to the list of fields being requested from the API
 func WithOriginalCarrier() Option {
-	return func(f *fields) {
-		*f = append(*f, "carrier_o")
-	}
+	return WithDataPoints(DataPointOriginalCarrier)
 }
 
-// WithLineType adds the "line_type" field to the list of fields being requested from the API
+// WithLineType adds the "linetype" field to the list of fields being requested from the API
 func WithLineType() Option {
-	return func(f *fields) {
-		*f = append(*f, "line_type")
-	}
+	return WithDataPoints(DataPointLineType)
 }
 
 // Result holds the results of a phone number lookup
@@ -99,6 +139,16 @@ type Result struct {
 	Pricing Pricing  `json:"pricing"`
 	Status  bool     `json:"status"`
 	Type    string   `json:"type"`
+
+	// Raw holds the untouched response body the Result was decoded from
+	Raw json.RawMessage `json:"-"`
+
+	// Extra holds any top level response fields this package doesn't know about yet
+	Extra map[string]json.RawMessage `json:"-"`
+
+	// Warnings holds any ways the response didn't match the declared schema. It's only populated
+	// when API.Strict is set.
+	Warnings []Warning `json:"-"`
 }
 
 // Carrier holds data about the carrier that is currently providing line service
@@ -170,6 +220,10 @@ type Data struct {
 	Location     *Location     `json:"location"`
 	Name         *string       `json:"name"`
 	Profile      *Profile      `json:"profile"`
+
+	// Extra holds any data points this package doesn't know about yet, such as ones added to
+	// EveryoneAPI after this package was released
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Breakdown holds the pricing breakdown of a phone number lookup
@@ -178,7 +232,7 @@ type Breakdown struct {
 	Carrier      float64 `json:"carrier"`
 	Carrier0     float64 `json:"carrier_0"`
 	Cnam         float64 `json:"cnam"`
-	ExpandedName int     `json:"expanded_name"`
+	ExpandedName float64 `json:"expanded_name"`
 	Gender       float64 `json:"gender"`
 	Image        float64 `json:"image"`
 	LineProvider float64 `json:"line_provider"`
@@ -188,6 +242,24 @@ type Breakdown struct {
 	Profile      float64 `json:"profile"`
 }
 
+// Prices returns the price of each data point, keyed by the name used to request it
+func (b Breakdown) Prices() map[string]float64 {
+	return map[string]float64{
+		"address":       b.Address,
+		"carrier":       b.Carrier,
+		"carrier_o":     b.Carrier0,
+		"cnam":          b.Cnam,
+		"expanded_name": b.ExpandedName,
+		"gender":        b.Gender,
+		"image":         b.Image,
+		"line_provider": b.LineProvider,
+		"linetype":      b.Linetype,
+		"location":      b.Location,
+		"name":          b.Name,
+		"profile":       b.Profile,
+	}
+}
+
 // Pricing holds the pricing data of a phone number lookup
 type Pricing struct {
 	Breakdown Breakdown `json:"breakdown"`
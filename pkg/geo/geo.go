@@ -0,0 +1,177 @@
+// Package geo answers questions about where a phone number is located, such as how far a lookup's
+// location is from an address a caller gave, entirely offline using an embedded dataset of US ZIP
+// code centroids.
+//
+//	near, err := geo.ParsePlace("10001")
+//	report := geo.Compare(geo.FromLocation(result.Data.Location), near)
+//	if report.StateMismatch || report.Miles() > 50 {
+//		// the phone number isn't from around here
+//	}
+package geo // import "samhofi.us/x/whatphone/pkg/geo"
+
+//go:generate go run ./internal/gen -o zips.csv.gz
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+)
+
+const (
+	// Mean radius of the Earth in kilometers
+	earthRadius = 6371.0088
+
+	// Kilometers in a mile
+	kmPerMile = 1.609344
+
+	// Farthest a point may be from the nearest ZIP code centroid for it to be considered part of
+	// that ZIP code's state
+	reverseRadius = 50.0
+)
+
+// Point is a position in decimal degrees
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// ParsePoint parses a position given as "lat,long" in decimal degrees
+func ParsePoint(s string) (Point, error) {
+	lat, lon, ok := strings.Cut(s, ",")
+	if !ok {
+		return Point{}, fmt.Errorf("invalid coordinates: %s", s)
+	}
+
+	var p Point
+	var err error
+	if p.Lat, err = strconv.ParseFloat(strings.TrimSpace(lat), 64); err != nil || p.Lat < -90 || p.Lat > 90 {
+		return Point{}, fmt.Errorf("invalid latitude: %s", strings.TrimSpace(lat))
+	}
+	if p.Lon, err = strconv.ParseFloat(strings.TrimSpace(lon), 64); err != nil || p.Lon < -180 || p.Lon > 180 {
+		return Point{}, fmt.Errorf("invalid longitude: %s", strings.TrimSpace(lon))
+	}
+	return p, nil
+}
+
+// Distance returns the great-circle distance between two points in kilometers
+func (p Point) Distance(q Point) float64 {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := rad(q.Lat - p.Lat)
+	dLon := rad(q.Lon - p.Lon)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(rad(p.Lat))*math.Cos(rad(q.Lat))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// String returns a point in the "lat,long" form accepted by ParsePoint
+func (p Point) String() string {
+	return fmt.Sprintf("%.6f,%.6f", p.Lat, p.Lon)
+}
+
+// Place is a location known by its ZIP code, its coordinates, or both. Whatever isn't given is
+// filled in from the ZIP code dataset where possible.
+type Place struct {
+	ZIP   string `json:"zip,omitempty"`
+	State string `json:"state,omitempty"`
+	Point *Point `json:"point,omitempty"`
+}
+
+// ParsePlace parses a place given as either a ZIP code, such as "10001" or "10001-1234", or
+// coordinates, such as "40.75,-73.99"
+func ParsePlace(s string) (Place, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, ",") {
+		p, err := ParsePoint(s)
+		if err != nil {
+			return Place{}, err
+		}
+		return fromPoint(p), nil
+	}
+
+	zip := normalizeZIP(s)
+	if zip == "" {
+		return Place{}, fmt.Errorf("invalid ZIP code: %s", s)
+	}
+	place := Place{ZIP: zip, State: StateOfZIP(zip)}
+	if place.State == "" {
+		return Place{}, fmt.Errorf("unknown ZIP code: %s", s)
+	}
+	if z, ok := LookupZIP(zip); ok {
+		p := z.Point
+		place.Point = &p
+	}
+	return place, nil
+}
+
+// FromLocation returns the place a lookup located a phone number at, or an empty Place if loc is
+// nil. Coordinates missing from the lookup are taken from the ZIP code's centroid.
+func FromLocation(loc *whatphone.Location) Place {
+	if loc == nil {
+		return Place{}
+	}
+
+	place := Place{ZIP: normalizeZIP(loc.Zip), State: strings.ToUpper(strings.TrimSpace(loc.State))}
+	if lat, lon, ok := loc.Geo.Coordinates(); ok {
+		place.Point = &Point{Lat: lat, Lon: lon}
+	} else if z, ok := LookupZIP(place.ZIP); ok {
+		p := z.Point
+		place.Point = &p
+	}
+
+	if place.State == "" {
+		if place.ZIP != "" {
+			place.State = StateOfZIP(place.ZIP)
+		} else if place.Point != nil {
+			place.State = fromPoint(*place.Point).State
+		}
+	}
+	return place
+}
+
+// fromPoint returns a place at p, reverse geocoding its state from the nearest ZIP code
+func fromPoint(p Point) Place {
+	place := Place{Point: &p}
+	if z, dist, ok := Nearest(p); ok && dist <= reverseRadius {
+		place.State = z.State
+	}
+	return place
+}
+
+// Report compares where a lookup located a phone number with where it was expected to be
+type Report struct {
+	// Location is where the lookup located the phone number
+	Location Place `json:"location"`
+
+	// Near is where the phone number was expected to be
+	Near Place `json:"near"`
+
+	// Distance is the distance between Location and Near in kilometers, or nil if either one has
+	// no known coordinates
+	Distance *float64 `json:"distance_km,omitempty"`
+
+	// StateMismatch is true when the states of both places are known and differ
+	StateMismatch bool `json:"state_mismatch"`
+}
+
+// Compare reports how far, and whether in a different state, a lookup's location is from near
+func Compare(location, near Place) Report {
+	r := Report{Location: location, Near: near}
+	if location.Point != nil && near.Point != nil {
+		d := location.Point.Distance(*near.Point)
+		r.Distance = &d
+	}
+	r.StateMismatch = location.State != "" && near.State != "" && location.State != near.State
+	return r
+}
+
+// Miles returns the distance between the places in miles, or -1 if it isn't known
+func (r Report) Miles() float64 {
+	if r.Distance == nil {
+		return -1
+	}
+	return *r.Distance / kmPerMile
+}
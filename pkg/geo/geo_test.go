@@ -0,0 +1,125 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b Point
+		km   float64
+	}{
+		{Point{40.7506, -73.9972}, Point{40.7506, -73.9972}, 0},
+		{Point{40.7506, -73.9972}, Point{34.0614, -118.2385}, 3935},
+		{Point{0, 0}, Point{0, 180}, math.Pi * earthRadius},
+		{Point{90, 0}, Point{-90, 0}, math.Pi * earthRadius},
+	}
+
+	for _, test := range tests {
+		if got := test.a.Distance(test.b); math.Abs(got-test.km) > 1 {
+			t.Errorf("Error: Unexpected distance from %v to %v. Got: %.1f, Want: %.1f", test.a, test.b, got, test.km)
+		}
+		if a, b := test.a.Distance(test.b), test.b.Distance(test.a); a != b {
+			t.Errorf("Error: distance should be symmetric. Got: %v and %v", a, b)
+		}
+	}
+}
+
+func TestParsePoint(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Point
+		err  error
+	}{
+		{"40.75,-73.99", Point{40.75, -73.99}, nil},
+		{" 40.75 , -73.99 ", Point{40.75, -73.99}, nil},
+		{"40.75", Point{}, errors.New("invalid coordinates: 40.75")},
+		{"north,-73.99", Point{}, errors.New("invalid latitude: north")},
+		{"91,0", Point{}, errors.New("invalid latitude: 91")},
+		{"0,-181", Point{}, errors.New("invalid longitude: -181")},
+	}
+
+	for _, test := range tests {
+		got, err := ParsePoint(test.in)
+		if !reflect.DeepEqual(err, test.err) || got != test.want {
+			t.Errorf("Error: ParsePoint(%q). Got: %v (%v), Want: %v (%v)", test.in, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestParsePlace(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Place
+		err  error
+	}{
+		{"10001", Place{ZIP: "10001", State: "NY", Point: &Point{40.7506, -73.9972}}, nil},
+		{"10001-1234", Place{ZIP: "10001", State: "NY", Point: &Point{40.7506, -73.9972}}, nil},
+		{"07030", Place{ZIP: "07030", State: "NJ"}, nil},
+		{"40.70,-73.99", Place{State: "NY", Point: &Point{40.70, -73.99}}, nil},
+		{"45.0,-100.0", Place{Point: &Point{45, -100}}, nil},
+		{"1000", Place{}, errors.New("invalid ZIP code: 1000")},
+		{"00100", Place{}, errors.New("unknown ZIP code: 00100")},
+		{"40.7,west", Place{}, errors.New("invalid longitude: west")},
+	}
+
+	for _, test := range tests {
+		got, err := ParsePlace(test.in)
+		if !reflect.DeepEqual(err, test.err) || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Error: ParsePlace(%q). Got: %+v (%v), Want: %+v (%v)", test.in, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestFromLocation(t *testing.T) {
+	tests := []struct {
+		loc  *whatphone.Location
+		want Place
+	}{
+		{nil, Place{}},
+		{apitest.Sample().Location, Place{ZIP: "10003", State: "NY", Point: &Point{40.799787, -73.971421}}},
+		{&whatphone.Location{Zip: "98101"}, Place{ZIP: "98101", State: "WA", Point: &Point{47.6105, -122.3348}}},
+		{&whatphone.Location{State: "tx", Zip: "75001"}, Place{ZIP: "75001", State: "TX"}},
+		{&whatphone.Location{Geo: whatphone.Geo{Latitude: "39.75", Longitude: "-105.0"}}, Place{State: "CO", Point: &Point{39.75, -105}}},
+	}
+
+	for _, test := range tests {
+		if got := FromLocation(test.loc); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Error: FromLocation(%+v). Got: %+v, Want: %+v", test.loc, got, test.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	location := FromLocation(apitest.Sample().Location)
+
+	near, _ := ParsePlace("10001")
+	r := Compare(location, near)
+	if r.Distance == nil || math.Abs(*r.Distance-5.8) > 0.2 || r.StateMismatch {
+		t.Errorf("Error: Unexpected report for a nearby place: %+v", r)
+	}
+	if math.Abs(r.Miles()-*r.Distance/kmPerMile) > 1e-9 {
+		t.Errorf("Error: Unexpected miles. Got: %v", r.Miles())
+	}
+
+	far, _ := ParsePlace("90012")
+	if r := Compare(location, far); r.Distance == nil || *r.Distance < 3900 || !r.StateMismatch {
+		t.Errorf("Error: Unexpected report for a faraway place: %+v", r)
+	}
+
+	unknown, _ := ParsePlace("07030")
+	r = Compare(location, unknown)
+	if r.Distance != nil || r.Miles() != -1 || !r.StateMismatch {
+		t.Errorf("Error: Unexpected report for a place without coordinates: %+v", r)
+	}
+
+	if r := Compare(Place{}, near); r.Distance != nil || r.StateMismatch {
+		t.Errorf("Error: Unexpected report for an unknown location: %+v", r)
+	}
+}
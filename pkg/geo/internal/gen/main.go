@@ -0,0 +1,141 @@
+// Command gen builds the ZIP code centroid dataset embedded by package geo from the Census Bureau's
+// ZCTA gazetteer, which lists the internal point of every ZIP Code Tabulation Area. The dataset is
+// gzipped, since the full gazetteer has over 33,000 ZIP codes.
+//
+//	go run ./internal/gen -o zips.csv.gz
+//	go run ./internal/gen -in 2023_Gaz_zcta_national.txt -o zips.csv.gz
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"samhofi.us/x/whatphone/pkg/geo"
+)
+
+const gazetteerURL = "https://www2.census.gov/geo/docs/maps-data/data/gazetteer/2023_Gazetteer/2023_Gaz_zcta_national.zip"
+
+const header = `# ZIP code centroids: zip,state,lat,long
+#
+# Generated by internal/gen from the Census Bureau's ZCTA gazetteer. DO NOT EDIT.
+`
+
+func main() {
+	in := flag.String("in", "", "Read the gazetteer from `FILE` instead of downloading it")
+	url := flag.String("url", gazetteerURL, "Download the zipped gazetteer from `URL`")
+	out := flag.String("o", "zips.csv.gz", "Write the gzipped dataset to `FILE`")
+	flag.Parse()
+
+	r, err := open(*in, *url)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer r.Close()
+
+	rows, err := convert(r)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	io.WriteString(zw, header)
+	w := csv.NewWriter(zw)
+	if err := w.WriteAll(rows); err != nil {
+		log.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("wrote %d ZIP codes to %s", len(rows), *out)
+}
+
+// open returns the gazetteer, read from a local file when in is set, or downloaded otherwise
+func open(in, url string) (io.ReadCloser, error) {
+	if in != "" {
+		return os.Open(in)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, ".txt") {
+			return f.Open()
+		}
+	}
+	return nil, fmt.Errorf("no gazetteer found in %s", url)
+}
+
+// convert turns the tab separated gazetteer into "zip,state,lat,long" rows sorted by ZIP code,
+// skipping ZIP codes that aren't assigned to a state
+func convert(r io.Reader) ([][]string, error) {
+	s := bufio.NewScanner(r)
+	if !s.Scan() {
+		return nil, fmt.Errorf("empty gazetteer")
+	}
+
+	cols := make(map[string]int)
+	for i, name := range strings.Split(s.Text(), "\t") {
+		cols[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"GEOID", "INTPTLAT", "INTPTLONG"} {
+		if _, ok := cols[name]; !ok {
+			return nil, fmt.Errorf("gazetteer is missing the %s column", name)
+		}
+	}
+
+	var rows [][]string
+	for s.Scan() {
+		fields := strings.Split(s.Text(), "\t")
+		if len(fields) < len(cols) {
+			continue
+		}
+
+		zip := strings.TrimSpace(fields[cols["GEOID"]])
+		state := geo.StateOfZIP(zip)
+		if state == "" {
+			continue
+		}
+		lat := strings.TrimSpace(fields[cols["INTPTLAT"]])
+		lon := strings.TrimSpace(fields[cols["INTPTLONG"]])
+		if _, err := geo.ParsePoint(lat + "," + lon); err != nil {
+			return nil, fmt.Errorf("ZIP code %s: %v", zip, err)
+		}
+		rows = append(rows, []string{zip, state, lat, lon})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(rows, func(i, j int) bool { return rows[i][0] < rows[j][0] })
+	return rows, nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	gazetteer := "GEOID\tALAND\tAWATER\tALAND_SQMI\tAWATER_SQMI\tINTPTLAT\tINTPTLONG                                                                                                               \n" +
+		"10003\t1\t0\t0.1\t0.0\t40.731829\t-73.989181              \n" +
+		"00601\t1\t0\t0.1\t0.0\t18.180555\t-66.749961              \n" +
+		"00100\t1\t0\t0.1\t0.0\t0.0\t0.0              \n"
+
+	rows, err := convert(strings.NewReader(gazetteer))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	want := [][]string{
+		{"00601", "PR", "18.180555", "-66.749961"},
+		{"10003", "NY", "40.731829", "-73.989181"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("Error: Unexpected rows.\nGot: %v\nWant: %v", rows, want)
+	}
+
+	if _, err := convert(strings.NewReader("GEOID\tALAND\n10003\t1\n")); err == nil {
+		t.Errorf("Error: missing columns should have returned an error")
+	}
+	if _, err := convert(strings.NewReader("")); err == nil {
+		t.Errorf("Error: empty gazetteer should have returned an error")
+	}
+}
@@ -0,0 +1,173 @@
+package geo // import "samhofi.us/x/whatphone/pkg/geo"
+
+import (
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// zipsCSV holds the centroid of each ZIP code as gzipped "zip,state,lat,long" rows. It's built
+// from the Census Bureau's ZCTA gazetteer by "go generate"; see internal/gen.
+//
+//go:embed zips.csv.gz
+var zipsCSV []byte
+
+// ZIP is a ZIP code along with the state it's in and its centroid
+type ZIP struct {
+	Code  string
+	State string
+	Point Point
+}
+
+var (
+	zipsOnce sync.Once
+	zips     map[string]ZIP
+	zipList  []ZIP
+)
+
+// loadZIPs parses the embedded dataset the first time it's needed
+func loadZIPs() {
+	zipsOnce.Do(func() {
+		zr, err := gzip.NewReader(bytes.NewReader(zipsCSV))
+		if err == nil {
+			zipList, err = parseZIPs(zr)
+		}
+		if err != nil {
+			panic(fmt.Sprintf("geo: invalid embedded ZIP code dataset: %v", err))
+		}
+		zips = make(map[string]ZIP, len(zipList))
+		for _, z := range zipList {
+			zips[z.Code] = z
+		}
+	})
+}
+
+// parseZIPs reads a ZIP code dataset, sorted by latitude so it can be searched by Nearest
+func parseZIPs(r io.Reader) ([]ZIP, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 4
+	cr.Comment = '#'
+
+	var list []ZIP
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		p, err := ParsePoint(rec[2] + "," + rec[3])
+		if err != nil {
+			return nil, fmt.Errorf("ZIP code %s: %v", rec[0], err)
+		}
+		list = append(list, ZIP{Code: rec[0], State: rec[1], Point: p})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Point.Lat < list[j].Point.Lat
+	})
+	return list, nil
+}
+
+// LookupZIP returns the centroid of a ZIP code. ZIP+4 codes are looked up by their first five digits.
+func LookupZIP(zip string) (ZIP, bool) {
+	loadZIPs()
+	z, ok := zips[normalizeZIP(zip)]
+	return z, ok
+}
+
+// Nearest returns the ZIP code whose centroid is closest to p, along with its distance from p in
+// kilometers. ok is false if the dataset is empty.
+func Nearest(p Point) (zip ZIP, dist float64, ok bool) {
+	loadZIPs()
+	if len(zipList) == 0 {
+		return ZIP{}, 0, false
+	}
+
+	// a degree of latitude is never less than 110km, so once the latitude alone puts a centroid
+	// farther away than the best found so far, every centroid past it is farther too
+	const kmPerDegree = 110.0
+	dist = math.Inf(1)
+	start := sort.Search(len(zipList), func(i int) bool { return zipList[i].Point.Lat >= p.Lat })
+	for i := start; i < len(zipList) && (zipList[i].Point.Lat-p.Lat)*kmPerDegree < dist; i++ {
+		if d := p.Distance(zipList[i].Point); d < dist {
+			zip, dist = zipList[i], d
+		}
+	}
+	for i := start - 1; i >= 0 && (p.Lat-zipList[i].Point.Lat)*kmPerDegree < dist; i-- {
+		if d := p.Distance(zipList[i].Point); d < dist {
+			zip, dist = zipList[i], d
+		}
+	}
+	return zip, dist, true
+}
+
+// normalizeZIP returns the five digit ZIP code of a ZIP or ZIP+4 code, or an empty string if zip
+// isn't one
+func normalizeZIP(zip string) string {
+	zip = strings.TrimSpace(zip)
+	if len(zip) == 10 && zip[5] == '-' {
+		zip = zip[:5]
+	}
+	if len(zip) != 5 {
+		return ""
+	}
+	for _, r := range zip {
+		if r < '0' || r > '9' {
+			return ""
+		}
+	}
+	return zip
+}
+
+// zipPrefixes maps ranges of three digit ZIP code prefixes to the state, territory or military
+// postal code they're assigned to
+var zipPrefixes = []struct {
+	first, last int
+	state       string
+}{
+	{5, 5, "NY"}, {6, 7, "PR"}, {8, 8, "VI"}, {9, 9, "PR"},
+	{10, 27, "MA"}, {28, 29, "RI"}, {30, 38, "NH"}, {39, 49, "ME"},
+	{50, 54, "VT"}, {55, 55, "MA"}, {56, 59, "VT"}, {60, 69, "CT"},
+	{70, 89, "NJ"}, {90, 98, "AE"}, {100, 149, "NY"}, {150, 196, "PA"},
+	{197, 199, "DE"}, {200, 200, "DC"}, {201, 201, "VA"}, {202, 205, "DC"},
+	{206, 219, "MD"}, {220, 246, "VA"}, {247, 268, "WV"}, {270, 289, "NC"},
+	{290, 299, "SC"}, {300, 319, "GA"}, {320, 339, "FL"}, {340, 340, "AA"},
+	{341, 349, "FL"}, {350, 369, "AL"}, {370, 385, "TN"}, {386, 397, "MS"},
+	{398, 399, "GA"}, {400, 427, "KY"}, {430, 459, "OH"}, {460, 479, "IN"},
+	{480, 499, "MI"}, {500, 528, "IA"}, {530, 549, "WI"}, {550, 567, "MN"},
+	{569, 569, "DC"}, {570, 577, "SD"}, {580, 588, "ND"}, {590, 599, "MT"},
+	{600, 629, "IL"}, {630, 658, "MO"}, {660, 679, "KS"}, {680, 693, "NE"},
+	{700, 715, "LA"}, {716, 729, "AR"}, {730, 732, "OK"}, {733, 733, "TX"},
+	{734, 749, "OK"}, {750, 799, "TX"}, {800, 816, "CO"}, {820, 831, "WY"},
+	{832, 838, "ID"}, {840, 847, "UT"}, {850, 865, "AZ"}, {870, 884, "NM"},
+	{885, 885, "TX"}, {889, 898, "NV"}, {900, 961, "CA"}, {962, 966, "AP"},
+	{967, 968, "HI"}, {969, 969, "GU"}, {970, 979, "OR"}, {980, 994, "WA"},
+	{995, 999, "AK"},
+}
+
+// StateOfZIP returns the postal code of the state a ZIP code is in, based on its first three
+// digits, or an empty string if the ZIP code isn't assigned to any state
+func StateOfZIP(zip string) string {
+	zip = normalizeZIP(zip)
+	if zip == "" {
+		return ""
+	}
+
+	prefix, _ := strconv.Atoi(zip[:3])
+	i := sort.Search(len(zipPrefixes), func(i int) bool { return zipPrefixes[i].last >= prefix })
+	if i < len(zipPrefixes) && zipPrefixes[i].first <= prefix {
+		return zipPrefixes[i].state
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+package geo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDataset(t *testing.T) {
+	loadZIPs()
+	if len(zipList) == 0 {
+		t.Fatalf("Error: embedded dataset is empty")
+	}
+
+	for _, z := range zipList {
+		if state := StateOfZIP(z.Code); state != z.State {
+			t.Errorf("Error: ZIP code %s is listed in %s, but its prefix is assigned to %s", z.Code, z.State, state)
+		}
+	}
+}
+
+func TestParseZIPs(t *testing.T) {
+	list, err := parseZIPs(strings.NewReader("# comment\n10001,NY,40.75,-74.0\n33130,FL,25.77,-80.2\n"))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(list) != 2 || list[0].Code != "33130" || list[1].Code != "10001" {
+		t.Errorf("Error: dataset should be sorted by latitude. Got: %+v", list)
+	}
+
+	if _, err := parseZIPs(strings.NewReader("10001,NY,40.75\n")); err == nil {
+		t.Errorf("Error: short row should have returned an error")
+	}
+	if _, err := parseZIPs(strings.NewReader("10001,NY,north,-74.0\n")); err == nil {
+		t.Errorf("Error: invalid coordinates should have returned an error")
+	}
+}
+
+func TestLookupZIP(t *testing.T) {
+	tests := []struct {
+		zip   string
+		state string
+		ok    bool
+	}{
+		{"10001", "NY", true},
+		{" 94103-1234 ", "CA", true},
+		{"07030", "", false},
+		{"abcde", "", false},
+	}
+
+	for _, test := range tests {
+		z, ok := LookupZIP(test.zip)
+		if ok != test.ok || z.State != test.state {
+			t.Errorf("Error: LookupZIP(%q). Got: %+v (%v), Want: %s (%v)", test.zip, z, ok, test.state, test.ok)
+		}
+	}
+}
+
+func TestLookupZIPOutsideMetros(t *testing.T) {
+	loadZIPs()
+	if len(zipList) < 30000 {
+		t.Skipf("the embedded dataset only holds %d seed ZIP codes; run go generate to embed the full gazetteer", len(zipList))
+	}
+
+	for _, zip := range []string{"05001", "59001", "69001", "83001", "99701"} {
+		z, ok := LookupZIP(zip)
+		if !ok || z.State != StateOfZIP(zip) {
+			t.Errorf("Error: LookupZIP(%q). Got: %+v (%v), Want: a ZIP code in %s", zip, z, ok, StateOfZIP(zip))
+			continue
+		}
+		if near, dist, _ := Nearest(z.Point); near.Code != zip || dist != 0 {
+			t.Errorf("Error: Nearest(%v). Got: %s (%v km), Want: %s", z.Point, near.Code, dist, zip)
+		}
+	}
+}
+
+func TestNearest(t *testing.T) {
+	tests := []struct {
+		p    Point
+		want string
+	}{
+		{Point{40.76, -73.99}, "10001"},
+		{Point{34.05, -118.25}, "90012"},
+		{Point{21.3, -157.9}, "96813"},
+		{Point{64.8, -147.7}, "99501"},
+		{Point{10, -60}, "33130"},
+	}
+
+	for _, test := range tests {
+		z, dist, ok := Nearest(test.p)
+		if !ok || z.Code != test.want {
+			t.Errorf("Error: Nearest(%v). Got: %s, Want: %s", test.p, z.Code, test.want)
+		}
+
+		// compare against checking every ZIP code
+		best := -1.0
+		for _, c := range zipList {
+			if d := test.p.Distance(c.Point); best < 0 || d < best {
+				best = d
+			}
+		}
+		if dist != best {
+			t.Errorf("Error: Nearest(%v) distance. Got: %v, Want: %v", test.p, dist, best)
+		}
+	}
+}
+
+func TestStateOfZIP(t *testing.T) {
+	tests := []struct {
+		zip   string
+		state string
+	}{
+		{"00501", "NY"},
+		{"00901", "PR"},
+		{"02108", "MA"},
+		{"05501", "MA"},
+		{"05601", "VT"},
+		{"07030", "NJ"},
+		{"09001", "AE"},
+		{"20001", "DC"},
+		{"20101", "VA"},
+		{"34001", "AA"},
+		{"73301", "TX"},
+		{"73401", "OK"},
+		{"88501", "TX"},
+		{"96201", "AP"},
+		{"96910", "GU"},
+		{"99950", "AK"},
+		{"00100", ""},
+		{"88801", ""},
+		{"1234", ""},
+	}
+
+	for _, test := range tests {
+		if got := StateOfZIP(test.zip); got != test.state {
+			t.Errorf("Error: StateOfZIP(%q). Got: %q, Want: %q", test.zip, got, test.state)
+		}
+	}
+}
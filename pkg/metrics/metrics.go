@@ -0,0 +1,112 @@
+// Package metrics exports lookup measurements from a whatphone.API to Prometheus.
+//
+//	reg := prometheus.NewRegistry()
+//	api.Metrics = metrics.NewPrometheus(reg)
+//	http.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+package metrics // import "samhofi.us/x/whatphone/pkg/metrics"
+
+import (
+	"strconv"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+)
+
+const namespace = "whatphone"
+
+// Prometheus implements whatphone.Metrics by updating Prometheus collectors
+type Prometheus struct {
+	requests *prometheus.CounterVec
+	latency  prometheus.Histogram
+	spend    *prometheus.CounterVec
+	missed   *prometheus.CounterVec
+	cache    *prometheus.CounterVec
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// NewPrometheus returns a Prometheus with its collectors registered with reg
+func NewPrometheus(reg prometheus.Registerer) *Prometheus {
+	p := &Prometheus{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Requests sent to EveryoneAPI, by HTTP status code, or \"error\" when no response was received.",
+		}, []string{"status"}),
+		latency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Time taken by requests sent to EveryoneAPI.",
+			Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10},
+		}),
+		spend: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "spend_total",
+			Help:      "Amount charged by EveryoneAPI, by data point.",
+		}, []string{"data_point"}),
+		missed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "missed_total",
+			Help:      "Requested data points EveryoneAPI had no data for, by data point.",
+		}, []string{"data_point"}),
+		cache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_lookups_total",
+			Help:      "Lookups checked against the cache, by result (hit or miss).",
+		}, []string{"result"}),
+	}
+
+	ratio := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cache_hit_ratio",
+		Help:      "Share of lookups checked against the cache that were answered from it.",
+	}, p.hitRatio)
+
+	reg.MustRegister(p.requests, p.latency, p.spend, p.missed, p.cache, ratio)
+	return p
+}
+
+// ObserveLookup records the measurements of a lookup
+func (p *Prometheus) ObserveLookup(stats whatphone.LookupStats) {
+	switch stats.Cache {
+	case whatphone.CacheHit:
+		p.hits.Add(1)
+		p.cache.WithLabelValues(stats.Cache.String()).Inc()
+		// nothing was sent to EveryoneAPI, so there's nothing else to record
+		return
+	case whatphone.CacheMiss:
+		p.misses.Add(1)
+		p.cache.WithLabelValues(stats.Cache.String()).Inc()
+	}
+
+	status := "error"
+	if stats.Status != 0 {
+		status = strconv.Itoa(stats.Status)
+	}
+	p.requests.WithLabelValues(status).Inc()
+	p.latency.Observe(stats.Duration.Seconds())
+
+	if stats.Result == nil {
+		return
+	}
+	for dataPoint, price := range stats.Result.Pricing.Breakdown.Prices() {
+		// sample lookups are priced negatively, and counters can't go down
+		if price > 0 {
+			p.spend.WithLabelValues(dataPoint).Add(price)
+		}
+	}
+	for _, dataPoint := range stats.Result.Missed {
+		p.missed.WithLabelValues(dataPoint).Inc()
+	}
+}
+
+// hitRatio returns the share of cache checks that were hits
+func (p *Prometheus) hitRatio() float64 {
+	hits, misses := p.hits.Load(), p.misses.Load()
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func TestPrometheus(t *testing.T) {
+	srv := apitest.NewServer(apitest.WithNumber("+15557654321", whatphone.Data{Carrier: &whatphone.Carrier{ID: "1", Name: "Carrier"}}))
+	defer srv.Close()
+
+	reg := prometheus.NewRegistry()
+	p := NewPrometheus(reg)
+
+	api := srv.API()
+	api.Metrics = p
+	api.Cache = whatphone.NewMemoryCache(time.Minute, 0)
+
+	// a miss, then a hit
+	for i := 0; i < 2; i++ {
+		if _, err := api.Lookup("+15557654321", whatphone.WithCarrier(), whatphone.WithGender()); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	srv.FailNext(1, http.StatusServiceUnavailable)
+	if _, err := api.Lookup("+15557654321", whatphone.WithName()); err == nil {
+		t.Fatalf("Error: injected failure should have failed the lookup")
+	}
+
+	expected := `
+# HELP whatphone_requests_total Requests sent to EveryoneAPI, by HTTP status code, or "error" when no response was received.
+# TYPE whatphone_requests_total counter
+whatphone_requests_total{status="200"} 1
+whatphone_requests_total{status="503"} 1
+# HELP whatphone_spend_total Amount charged by EveryoneAPI, by data point.
+# TYPE whatphone_spend_total counter
+whatphone_spend_total{data_point="carrier"} 0.003
+# HELP whatphone_missed_total Requested data points EveryoneAPI had no data for, by data point.
+# TYPE whatphone_missed_total counter
+whatphone_missed_total{data_point="gender"} 1
+# HELP whatphone_cache_lookups_total Lookups checked against the cache, by result (hit or miss).
+# TYPE whatphone_cache_lookups_total counter
+whatphone_cache_lookups_total{result="hit"} 1
+whatphone_cache_lookups_total{result="miss"} 2
+# HELP whatphone_cache_hit_ratio Share of lookups checked against the cache that were answered from it.
+# TYPE whatphone_cache_hit_ratio gauge
+whatphone_cache_hit_ratio 0.3333333333333333
+`
+	names := []string{
+		"whatphone_requests_total", "whatphone_spend_total", "whatphone_missed_total",
+		"whatphone_cache_lookups_total", "whatphone_cache_hit_ratio",
+	}
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), names...); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+
+	if n := testutil.CollectAndCount(p.latency); n != 1 {
+		t.Errorf("Error: Unexpected number of latency histograms. Got: %d, Want: 1", n)
+	}
+}
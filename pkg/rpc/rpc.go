@@ -0,0 +1,181 @@
+// Package rpc provides a gRPC lookup service backed by EveryoneAPI, along with the generated client
+// for calling it.
+//
+// Connect to a server started with "whatphone grpc" using the generated client, sending an API key
+// from the server's keys file as a bearer token:
+//
+//	conn, err := grpc.NewClient("localhost:9090", grpc.WithTransportCredentials(insecure.NewCredentials()))
+//	client := rpc.NewLookupServiceClient(conn)
+//	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+key)
+//	result, err := client.Lookup(ctx, &rpc.LookupRequest{Number: "+15551234567", Data: []string{"name"}})
+package rpc // import "samhofi.us/x/whatphone/pkg/rpc"
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative whatphone.proto
+
+import (
+	"encoding/json"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+)
+
+// FromAPI converts a lookup result into its protobuf representation
+func FromAPI(r *whatphone.Result) *Result {
+	if r == nil {
+		return nil
+	}
+
+	d := r.Data
+	data := &Data{
+		Address:  d.Address,
+		Cnam:     d.Cnam,
+		Gender:   d.Gender,
+		Linetype: d.Linetype,
+		Name:     d.Name,
+	}
+	if d.Carrier != nil {
+		data.Carrier = &Carrier{Id: d.Carrier.ID, Name: d.Carrier.Name}
+	}
+	if d.CarrierO != nil {
+		data.CarrierO = &Carrier{Id: d.CarrierO.ID, Name: d.CarrierO.Name}
+	}
+	if d.ExpandedName != nil {
+		data.ExpandedName = &ExpandedName{First: d.ExpandedName.First, Last: d.ExpandedName.Last}
+	}
+	if d.Image != nil {
+		data.Image = &Image{Cover: d.Image.Cover, Large: d.Image.Large, Med: d.Image.Med, Small: d.Image.Small}
+	}
+	if d.LineProvider != nil {
+		data.LineProvider = &LineProvider{
+			Id:       d.LineProvider.ID,
+			MmsEmail: d.LineProvider.MmsEmail,
+			Name:     d.LineProvider.Name,
+			SmsEmail: d.LineProvider.SmsEmail,
+		}
+	}
+	if d.Location != nil {
+		data.Location = &Location{
+			City:  d.Location.City,
+			Geo:   &Geo{Latitude: d.Location.Geo.Latitude, Longitude: d.Location.Geo.Longitude},
+			State: d.Location.State,
+			Zip:   d.Location.Zip,
+		}
+	}
+	if d.Profile != nil {
+		data.Profile = &Profile{Edu: d.Profile.Edu, Job: d.Profile.Job, Relationship: d.Profile.Relationship}
+	}
+
+	b := r.Pricing.Breakdown
+	return &Result{
+		Data:   data,
+		Missed: r.Missed,
+		Number: r.Number,
+		Note:   r.Note,
+		Pricing: &Pricing{
+			Breakdown: &Breakdown{
+				Address:      b.Address,
+				Carrier:      b.Carrier,
+				Carrier_0:    b.Carrier0,
+				Cnam:         b.Cnam,
+				ExpandedName: b.ExpandedName,
+				Gender:       b.Gender,
+				Image:        b.Image,
+				LineProvider: b.LineProvider,
+				Linetype:     b.Linetype,
+				Location:     b.Location,
+				Name:         b.Name,
+				Profile:      b.Profile,
+			},
+			Total: r.Pricing.Total,
+		},
+		Status: r.Status,
+		Type:   r.Type,
+		Raw:    r.Raw,
+	}
+}
+
+// ToAPI converts the protobuf representation of a lookup result back into a whatphone.Result
+func (x *Result) ToAPI() *whatphone.Result {
+	if x == nil {
+		return nil
+	}
+
+	var data whatphone.Data
+	if d := x.GetData(); d != nil {
+		data = whatphone.Data{
+			Address:  d.Address,
+			Cnam:     d.Cnam,
+			Gender:   d.Gender,
+			Linetype: d.Linetype,
+			Name:     d.Name,
+		}
+		if c := d.GetCarrier(); c != nil {
+			data.Carrier = &whatphone.Carrier{ID: c.GetId(), Name: c.GetName()}
+		}
+		if c := d.GetCarrierO(); c != nil {
+			data.CarrierO = &whatphone.CarrierO{ID: c.GetId(), Name: c.GetName()}
+		}
+		if n := d.GetExpandedName(); n != nil {
+			data.ExpandedName = &whatphone.ExpandedName{First: n.GetFirst(), Last: n.GetLast()}
+		}
+		if i := d.GetImage(); i != nil {
+			data.Image = &whatphone.Image{Cover: i.GetCover(), Large: i.GetLarge(), Med: i.GetMed(), Small: i.GetSmall()}
+		}
+		if lp := d.GetLineProvider(); lp != nil {
+			data.LineProvider = &whatphone.LineProvider{
+				ID:       lp.GetId(),
+				MmsEmail: lp.GetMmsEmail(),
+				Name:     lp.GetName(),
+				SmsEmail: lp.GetSmsEmail(),
+			}
+		}
+		if l := d.GetLocation(); l != nil {
+			data.Location = &whatphone.Location{
+				City:  l.GetCity(),
+				Geo:   whatphone.Geo{Latitude: l.GetGeo().GetLatitude(), Longitude: l.GetGeo().GetLongitude()},
+				State: l.GetState(),
+				Zip:   l.GetZip(),
+			}
+		}
+		if p := d.GetProfile(); p != nil {
+			data.Profile = &whatphone.Profile{Edu: p.GetEdu(), Job: p.GetJob(), Relationship: p.GetRelationship()}
+		}
+	}
+
+	// fields unknown to this package only survive in the raw response
+	var extra, dataExtra map[string]json.RawMessage
+	if len(x.GetRaw()) > 0 {
+		if r, err := whatphone.Decode(x.GetRaw()); err == nil {
+			extra, dataExtra = r.Extra, r.Data.Extra
+		}
+	}
+	data.Extra = dataExtra
+
+	b := x.GetPricing().GetBreakdown()
+	return &whatphone.Result{
+		Data:   data,
+		Missed: x.GetMissed(),
+		Number: x.GetNumber(),
+		Note:   x.GetNote(),
+		Pricing: whatphone.Pricing{
+			Breakdown: whatphone.Breakdown{
+				Address:      b.GetAddress(),
+				Carrier:      b.GetCarrier(),
+				Carrier0:     b.GetCarrier_0(),
+				Cnam:         b.GetCnam(),
+				ExpandedName: b.GetExpandedName(),
+				Gender:       b.GetGender(),
+				Image:        b.GetImage(),
+				LineProvider: b.GetLineProvider(),
+				Linetype:     b.GetLinetype(),
+				Location:     b.GetLocation(),
+				Name:         b.GetName(),
+				Profile:      b.GetProfile(),
+			},
+			Total: x.GetPricing().GetTotal(),
+		},
+		Status: x.GetStatus(),
+		Type:   x.GetType(),
+		Raw:    x.GetRaw(),
+		Extra:  extra,
+	}
+}
@@ -0,0 +1,153 @@
+package rpc // import "samhofi.us/x/whatphone/pkg/rpc"
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+)
+
+// Server implements LookupServiceServer by performing lookups with a whatphone.API
+type Server struct {
+	UnimplementedLookupServiceServer
+
+	api         *whatphone.API
+	defaults    []string
+	concurrency int
+}
+
+// ServerOption configures a Server
+type ServerOption func(s *Server)
+
+// WithDefaultData sets the data points requested by lookups that don't select any
+func WithDefaultData(names ...string) ServerOption {
+	return func(s *Server) {
+		s.defaults = names
+	}
+}
+
+// WithConcurrency sets the maximum number of lookups a single LookupMany stream performs at once
+func WithConcurrency(n int) ServerOption {
+	return func(s *Server) {
+		if n > 0 {
+			s.concurrency = n
+		}
+	}
+}
+
+// NewServer returns a Server performing lookups with api
+func NewServer(api *whatphone.API, opts ...ServerOption) *Server {
+	s := &Server{
+		api:         api,
+		concurrency: 4,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Lookup performs a single phone number lookup
+func (s *Server) Lookup(ctx context.Context, req *LookupRequest) (*Result, error) {
+	number, err := whatphone.Normalize(req.GetNumber())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	opts, err := s.options(req)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.api.LookupContext(ctx, number, opts...)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		return nil, status.Errorf(codes.Unavailable, "lookup failed: %v", err)
+	}
+
+	return FromAPI(result), nil
+}
+
+// LookupMany performs a lookup for every request received on the stream, sending each response as
+// soon as its lookup finishes. Failed lookups are reported in their response rather than ending the
+// stream.
+func (s *Server) LookupMany(stream LookupService_LookupManyServer) error {
+	var (
+		wg      sync.WaitGroup
+		sendMu  sync.Mutex
+		sendErr error
+	)
+	sem := make(chan struct{}, s.concurrency)
+
+	send := func(resp *LookupManyResponse) {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		if sendErr == nil {
+			sendErr = stream.Send(resp)
+		}
+	}
+
+	var recvErr error
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			if err != io.EOF {
+				recvErr = err
+			}
+			break
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			resp := &LookupManyResponse{Id: req.GetId(), Number: req.GetNumber()}
+			result, err := s.Lookup(stream.Context(), req)
+			if err != nil {
+				resp.Error = status.Convert(err).Message()
+			} else {
+				resp.Result = result
+			}
+			send(resp)
+		}()
+	}
+	wg.Wait()
+
+	if recvErr != nil {
+		return recvErr
+	}
+	return sendErr
+}
+
+// options returns the lookup options for the data points selected by a request
+func (s *Server) options(req *LookupRequest) ([]whatphone.Option, error) {
+	if req.GetAll() {
+		return nil, nil
+	}
+
+	names := req.GetData()
+	if len(names) == 0 {
+		names = s.defaults
+	}
+	if len(names) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no data points selected; set all to request all data points")
+	}
+
+	var set whatphone.DataPointSet
+	for _, name := range names {
+		dp, err := whatphone.ParseDataPoint(name)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		set.Add(dp)
+	}
+	return []whatphone.Option{whatphone.WithDataPoints(set.DataPoints()...)}, nil
+}
@@ -0,0 +1,149 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+// testClient starts a Server on an in-memory listener and returns a client connected to it
+func testClient(t *testing.T, s *Server) LookupServiceClient {
+	l := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	RegisterLookupServiceServer(srv, s)
+	go srv.Serve(l)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return l.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return NewLookupServiceClient(conn)
+}
+
+func TestLookup(t *testing.T) {
+	api := apitest.NewServer()
+	defer api.Close()
+
+	client := testClient(t, NewServer(api.API(), WithDefaultData("carrier")))
+	ctx := context.Background()
+
+	res, err := client.Lookup(ctx, &LookupRequest{Number: apitest.SampleNumber, Data: []string{"name"}})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if res.GetData().GetName() != "Michael Seaver" || res.GetData().Carrier != nil {
+		t.Errorf("Error: Unexpected data: %v", res.GetData())
+	}
+
+	res, err = client.Lookup(ctx, &LookupRequest{Number: apitest.SampleNumber})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if res.GetData().GetCarrier().GetName() != "Growing Wireless Inc." {
+		t.Errorf("Error: default data points were not requested: %v", res.GetData())
+	}
+
+	_, err = client.Lookup(ctx, &LookupRequest{Number: apitest.SampleNumber, Data: []string{"shoe_size"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Error: Unexpected status for unknown data point. Got: %v, Want: %v", status.Code(err), codes.InvalidArgument)
+	}
+
+	for _, number := range []string{"123", "../account", "5551234567?data=image"} {
+		_, err = client.Lookup(ctx, &LookupRequest{Number: number, All: true})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Error: Unexpected status for invalid number %q. Got: %v, Want: %v", number, status.Code(err), codes.InvalidArgument)
+		}
+	}
+	if api.Requests() != 2 {
+		t.Errorf("Error: Invalid numbers reached EveryoneAPI. Got: %d requests, Want: 2", api.Requests())
+	}
+
+	api.FailNext(1, http.StatusServiceUnavailable)
+	_, err = client.Lookup(ctx, &LookupRequest{Number: apitest.SampleNumber, All: true})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Error: Unexpected status for failed lookup. Got: %v, Want: %v", status.Code(err), codes.Unavailable)
+	}
+}
+
+func TestLookupMany(t *testing.T) {
+	api := apitest.NewServer()
+	defer api.Close()
+
+	client := testClient(t, NewServer(api.API(), WithConcurrency(2)))
+	stream, err := client.LookupMany(context.Background())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	requests := map[string]*LookupRequest{
+		"a": {Id: "a", Number: apitest.SampleNumber, Data: []string{"name"}},
+		"b": {Id: "b", Number: "5551234567", Data: []string{"cnam"}},
+		"c": {Id: "c", Number: "123", Data: []string{"name"}},
+	}
+	for _, req := range requests {
+		if err := stream.Send(req); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	stream.CloseSend()
+
+	responses := make(map[string]*LookupManyResponse)
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		responses[resp.GetId()] = resp
+	}
+
+	if len(responses) != len(requests) {
+		t.Fatalf("Error: Unexpected number of responses. Got: %d, Want: %d", len(responses), len(requests))
+	}
+	if responses["a"].GetResult().GetData().GetName() != "Michael Seaver" {
+		t.Errorf("Error: Unexpected response: %v", responses["a"])
+	}
+	if responses["b"].GetResult().GetData().GetCnam() != "MICHAEL SEAVER" {
+		t.Errorf("Error: Unexpected response: %v", responses["b"])
+	}
+	if responses["c"].GetResult() != nil || responses["c"].GetError() == "" {
+		t.Errorf("Error: failed lookup should be reported as an error: %v", responses["c"])
+	}
+}
+
+func TestConvert(t *testing.T) {
+	api := apitest.NewServer()
+	defer api.Close()
+
+	want, err := api.API().Lookup(apitest.SampleNumber)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	got := FromAPI(want).ToAPI()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Error: result changed after conversion.\nGot: %+v\nWant: %+v", got, want)
+	}
+
+	if FromAPI(nil) != nil || (*Result)(nil).ToAPI() != nil {
+		t.Errorf("Error: nil results should convert to nil")
+	}
+}
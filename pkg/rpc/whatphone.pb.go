@@ -0,0 +1,1189 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: whatphone.proto
+
+package rpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// LookupRequest selects the phone number and data points to look up
+type LookupRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Phone number to look up
+	Number string `protobuf:"bytes,1,opt,name=number,proto3" json:"number,omitempty"`
+	// Data points to request, e.g. "name" or "carrier". The server's default data points are
+	// requested when empty.
+	Data []string `protobuf:"bytes,2,rep,name=data,proto3" json:"data,omitempty"`
+	// Request all data points, ignoring data
+	All bool `protobuf:"varint,3,opt,name=all,proto3" json:"all,omitempty"`
+	// Optional ID echoed back in the LookupManyResponse, for matching responses to requests
+	Id            string `protobuf:"bytes,4,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupRequest) Reset() {
+	*x = LookupRequest{}
+	mi := &file_whatphone_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupRequest) ProtoMessage() {}
+
+func (x *LookupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_whatphone_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupRequest.ProtoReflect.Descriptor instead.
+func (*LookupRequest) Descriptor() ([]byte, []int) {
+	return file_whatphone_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *LookupRequest) GetNumber() string {
+	if x != nil {
+		return x.Number
+	}
+	return ""
+}
+
+func (x *LookupRequest) GetData() []string {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *LookupRequest) GetAll() bool {
+	if x != nil {
+		return x.All
+	}
+	return false
+}
+
+func (x *LookupRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// LookupManyResponse holds the result of a single lookup performed by LookupMany
+type LookupManyResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// ID of the request this responds to
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Phone number that was looked up
+	Number string `protobuf:"bytes,2,opt,name=number,proto3" json:"number,omitempty"`
+	// Result of the lookup, unset if the lookup failed
+	Result *Result `protobuf:"bytes,3,opt,name=result,proto3" json:"result,omitempty"`
+	// Error message if the lookup failed
+	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupManyResponse) Reset() {
+	*x = LookupManyResponse{}
+	mi := &file_whatphone_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupManyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupManyResponse) ProtoMessage() {}
+
+func (x *LookupManyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_whatphone_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupManyResponse.ProtoReflect.Descriptor instead.
+func (*LookupManyResponse) Descriptor() ([]byte, []int) {
+	return file_whatphone_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *LookupManyResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *LookupManyResponse) GetNumber() string {
+	if x != nil {
+		return x.Number
+	}
+	return ""
+}
+
+func (x *LookupManyResponse) GetResult() *Result {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *LookupManyResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// Result holds the results of a phone number lookup
+type Result struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Data    *Data                  `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	Missed  []string               `protobuf:"bytes,2,rep,name=missed,proto3" json:"missed,omitempty"`
+	Number  string                 `protobuf:"bytes,3,opt,name=number,proto3" json:"number,omitempty"`
+	Note    string                 `protobuf:"bytes,4,opt,name=note,proto3" json:"note,omitempty"`
+	Pricing *Pricing               `protobuf:"bytes,5,opt,name=pricing,proto3" json:"pricing,omitempty"`
+	Status  bool                   `protobuf:"varint,6,opt,name=status,proto3" json:"status,omitempty"`
+	Type    string                 `protobuf:"bytes,7,opt,name=type,proto3" json:"type,omitempty"`
+	// Untouched EveryoneAPI response body, for reading fields this service doesn't know about yet
+	Raw           []byte `protobuf:"bytes,8,opt,name=raw,proto3" json:"raw,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Result) Reset() {
+	*x = Result{}
+	mi := &file_whatphone_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Result) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Result) ProtoMessage() {}
+
+func (x *Result) ProtoReflect() protoreflect.Message {
+	mi := &file_whatphone_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Result.ProtoReflect.Descriptor instead.
+func (*Result) Descriptor() ([]byte, []int) {
+	return file_whatphone_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Result) GetData() *Data {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Result) GetMissed() []string {
+	if x != nil {
+		return x.Missed
+	}
+	return nil
+}
+
+func (x *Result) GetNumber() string {
+	if x != nil {
+		return x.Number
+	}
+	return ""
+}
+
+func (x *Result) GetNote() string {
+	if x != nil {
+		return x.Note
+	}
+	return ""
+}
+
+func (x *Result) GetPricing() *Pricing {
+	if x != nil {
+		return x.Pricing
+	}
+	return nil
+}
+
+func (x *Result) GetStatus() bool {
+	if x != nil {
+		return x.Status
+	}
+	return false
+}
+
+func (x *Result) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Result) GetRaw() []byte {
+	if x != nil {
+		return x.Raw
+	}
+	return nil
+}
+
+// Carrier holds data about a carrier providing, or originally assigned, the phone number
+type Carrier struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Carrier) Reset() {
+	*x = Carrier{}
+	mi := &file_whatphone_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Carrier) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Carrier) ProtoMessage() {}
+
+func (x *Carrier) ProtoReflect() protoreflect.Message {
+	mi := &file_whatphone_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Carrier.ProtoReflect.Descriptor instead.
+func (*Carrier) Descriptor() ([]byte, []int) {
+	return file_whatphone_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Carrier) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Carrier) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// ExpandedName holds the full name returned by a phone number lookup
+type ExpandedName struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	First         string                 `protobuf:"bytes,1,opt,name=first,proto3" json:"first,omitempty"`
+	Last          string                 `protobuf:"bytes,2,opt,name=last,proto3" json:"last,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExpandedName) Reset() {
+	*x = ExpandedName{}
+	mi := &file_whatphone_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExpandedName) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExpandedName) ProtoMessage() {}
+
+func (x *ExpandedName) ProtoReflect() protoreflect.Message {
+	mi := &file_whatphone_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExpandedName.ProtoReflect.Descriptor instead.
+func (*ExpandedName) Descriptor() ([]byte, []int) {
+	return file_whatphone_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ExpandedName) GetFirst() string {
+	if x != nil {
+		return x.First
+	}
+	return ""
+}
+
+func (x *ExpandedName) GetLast() string {
+	if x != nil {
+		return x.Last
+	}
+	return ""
+}
+
+// Image holds the image URLs returned by a phone number lookup. These links expire after 30 days.
+type Image struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cover         string                 `protobuf:"bytes,1,opt,name=cover,proto3" json:"cover,omitempty"`
+	Large         string                 `protobuf:"bytes,2,opt,name=large,proto3" json:"large,omitempty"`
+	Med           string                 `protobuf:"bytes,3,opt,name=med,proto3" json:"med,omitempty"`
+	Small         string                 `protobuf:"bytes,4,opt,name=small,proto3" json:"small,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Image) Reset() {
+	*x = Image{}
+	mi := &file_whatphone_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Image) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Image) ProtoMessage() {}
+
+func (x *Image) ProtoReflect() protoreflect.Message {
+	mi := &file_whatphone_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Image.ProtoReflect.Descriptor instead.
+func (*Image) Descriptor() ([]byte, []int) {
+	return file_whatphone_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Image) GetCover() string {
+	if x != nil {
+		return x.Cover
+	}
+	return ""
+}
+
+func (x *Image) GetLarge() string {
+	if x != nil {
+		return x.Large
+	}
+	return ""
+}
+
+func (x *Image) GetMed() string {
+	if x != nil {
+		return x.Med
+	}
+	return ""
+}
+
+func (x *Image) GetSmall() string {
+	if x != nil {
+		return x.Small
+	}
+	return ""
+}
+
+// LineProvider holds the consumer facing line provider (e.g. Google Voice, or MagicJack)
+type LineProvider struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	MmsEmail      string                 `protobuf:"bytes,2,opt,name=mms_email,json=mmsEmail,proto3" json:"mms_email,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	SmsEmail      string                 `protobuf:"bytes,4,opt,name=sms_email,json=smsEmail,proto3" json:"sms_email,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LineProvider) Reset() {
+	*x = LineProvider{}
+	mi := &file_whatphone_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LineProvider) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LineProvider) ProtoMessage() {}
+
+func (x *LineProvider) ProtoReflect() protoreflect.Message {
+	mi := &file_whatphone_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LineProvider.ProtoReflect.Descriptor instead.
+func (*LineProvider) Descriptor() ([]byte, []int) {
+	return file_whatphone_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *LineProvider) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *LineProvider) GetMmsEmail() string {
+	if x != nil {
+		return x.MmsEmail
+	}
+	return ""
+}
+
+func (x *LineProvider) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *LineProvider) GetSmsEmail() string {
+	if x != nil {
+		return x.SmsEmail
+	}
+	return ""
+}
+
+// Geo holds the geographical data returned by a phone number lookup
+type Geo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Latitude      string                 `protobuf:"bytes,1,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude     string                 `protobuf:"bytes,2,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Geo) Reset() {
+	*x = Geo{}
+	mi := &file_whatphone_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Geo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Geo) ProtoMessage() {}
+
+func (x *Geo) ProtoReflect() protoreflect.Message {
+	mi := &file_whatphone_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Geo.ProtoReflect.Descriptor instead.
+func (*Geo) Descriptor() ([]byte, []int) {
+	return file_whatphone_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Geo) GetLatitude() string {
+	if x != nil {
+		return x.Latitude
+	}
+	return ""
+}
+
+func (x *Geo) GetLongitude() string {
+	if x != nil {
+		return x.Longitude
+	}
+	return ""
+}
+
+// Location holds the location data returned by a phone number lookup
+type Location struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	City          string                 `protobuf:"bytes,1,opt,name=city,proto3" json:"city,omitempty"`
+	Geo           *Geo                   `protobuf:"bytes,2,opt,name=geo,proto3" json:"geo,omitempty"`
+	State         string                 `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`
+	Zip           string                 `protobuf:"bytes,4,opt,name=zip,proto3" json:"zip,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Location) Reset() {
+	*x = Location{}
+	mi := &file_whatphone_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Location) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Location) ProtoMessage() {}
+
+func (x *Location) ProtoReflect() protoreflect.Message {
+	mi := &file_whatphone_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Location.ProtoReflect.Descriptor instead.
+func (*Location) Descriptor() ([]byte, []int) {
+	return file_whatphone_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Location) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *Location) GetGeo() *Geo {
+	if x != nil {
+		return x.Geo
+	}
+	return nil
+}
+
+func (x *Location) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *Location) GetZip() string {
+	if x != nil {
+		return x.Zip
+	}
+	return ""
+}
+
+// Profile holds the profile data returned by a phone number lookup
+type Profile struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Edu           string                 `protobuf:"bytes,1,opt,name=edu,proto3" json:"edu,omitempty"`
+	Job           string                 `protobuf:"bytes,2,opt,name=job,proto3" json:"job,omitempty"`
+	Relationship  string                 `protobuf:"bytes,3,opt,name=relationship,proto3" json:"relationship,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Profile) Reset() {
+	*x = Profile{}
+	mi := &file_whatphone_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Profile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Profile) ProtoMessage() {}
+
+func (x *Profile) ProtoReflect() protoreflect.Message {
+	mi := &file_whatphone_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Profile.ProtoReflect.Descriptor instead.
+func (*Profile) Descriptor() ([]byte, []int) {
+	return file_whatphone_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Profile) GetEdu() string {
+	if x != nil {
+		return x.Edu
+	}
+	return ""
+}
+
+func (x *Profile) GetJob() string {
+	if x != nil {
+		return x.Job
+	}
+	return ""
+}
+
+func (x *Profile) GetRelationship() string {
+	if x != nil {
+		return x.Relationship
+	}
+	return ""
+}
+
+// Data holds the personal info fields returned by a phone number lookup. Fields that weren't
+// requested or returned are unset.
+type Data struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       *string                `protobuf:"bytes,1,opt,name=address,proto3,oneof" json:"address,omitempty"`
+	Carrier       *Carrier               `protobuf:"bytes,2,opt,name=carrier,proto3" json:"carrier,omitempty"`
+	CarrierO      *Carrier               `protobuf:"bytes,3,opt,name=carrier_o,json=carrierO,proto3" json:"carrier_o,omitempty"`
+	Cnam          *string                `protobuf:"bytes,4,opt,name=cnam,proto3,oneof" json:"cnam,omitempty"`
+	ExpandedName  *ExpandedName          `protobuf:"bytes,5,opt,name=expanded_name,json=expandedName,proto3" json:"expanded_name,omitempty"`
+	Gender        *string                `protobuf:"bytes,6,opt,name=gender,proto3,oneof" json:"gender,omitempty"`
+	Image         *Image                 `protobuf:"bytes,7,opt,name=image,proto3" json:"image,omitempty"`
+	LineProvider  *LineProvider          `protobuf:"bytes,8,opt,name=line_provider,json=lineProvider,proto3" json:"line_provider,omitempty"`
+	Linetype      *string                `protobuf:"bytes,9,opt,name=linetype,proto3,oneof" json:"linetype,omitempty"`
+	Location      *Location              `protobuf:"bytes,10,opt,name=location,proto3" json:"location,omitempty"`
+	Name          *string                `protobuf:"bytes,11,opt,name=name,proto3,oneof" json:"name,omitempty"`
+	Profile       *Profile               `protobuf:"bytes,12,opt,name=profile,proto3" json:"profile,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Data) Reset() {
+	*x = Data{}
+	mi := &file_whatphone_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data) ProtoMessage() {}
+
+func (x *Data) ProtoReflect() protoreflect.Message {
+	mi := &file_whatphone_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data.ProtoReflect.Descriptor instead.
+func (*Data) Descriptor() ([]byte, []int) {
+	return file_whatphone_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Data) GetAddress() string {
+	if x != nil && x.Address != nil {
+		return *x.Address
+	}
+	return ""
+}
+
+func (x *Data) GetCarrier() *Carrier {
+	if x != nil {
+		return x.Carrier
+	}
+	return nil
+}
+
+func (x *Data) GetCarrierO() *Carrier {
+	if x != nil {
+		return x.CarrierO
+	}
+	return nil
+}
+
+func (x *Data) GetCnam() string {
+	if x != nil && x.Cnam != nil {
+		return *x.Cnam
+	}
+	return ""
+}
+
+func (x *Data) GetExpandedName() *ExpandedName {
+	if x != nil {
+		return x.ExpandedName
+	}
+	return nil
+}
+
+func (x *Data) GetGender() string {
+	if x != nil && x.Gender != nil {
+		return *x.Gender
+	}
+	return ""
+}
+
+func (x *Data) GetImage() *Image {
+	if x != nil {
+		return x.Image
+	}
+	return nil
+}
+
+func (x *Data) GetLineProvider() *LineProvider {
+	if x != nil {
+		return x.LineProvider
+	}
+	return nil
+}
+
+func (x *Data) GetLinetype() string {
+	if x != nil && x.Linetype != nil {
+		return *x.Linetype
+	}
+	return ""
+}
+
+func (x *Data) GetLocation() *Location {
+	if x != nil {
+		return x.Location
+	}
+	return nil
+}
+
+func (x *Data) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+func (x *Data) GetProfile() *Profile {
+	if x != nil {
+		return x.Profile
+	}
+	return nil
+}
+
+// Breakdown holds the pricing breakdown of a phone number lookup
+type Breakdown struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       float64                `protobuf:"fixed64,1,opt,name=address,proto3" json:"address,omitempty"`
+	Carrier       float64                `protobuf:"fixed64,2,opt,name=carrier,proto3" json:"carrier,omitempty"`
+	Carrier_0     float64                `protobuf:"fixed64,3,opt,name=carrier_0,json=carrier0,proto3" json:"carrier_0,omitempty"`
+	Cnam          float64                `protobuf:"fixed64,4,opt,name=cnam,proto3" json:"cnam,omitempty"`
+	ExpandedName  float64                `protobuf:"fixed64,5,opt,name=expanded_name,json=expandedName,proto3" json:"expanded_name,omitempty"`
+	Gender        float64                `protobuf:"fixed64,6,opt,name=gender,proto3" json:"gender,omitempty"`
+	Image         float64                `protobuf:"fixed64,7,opt,name=image,proto3" json:"image,omitempty"`
+	LineProvider  float64                `protobuf:"fixed64,8,opt,name=line_provider,json=lineProvider,proto3" json:"line_provider,omitempty"`
+	Linetype      float64                `protobuf:"fixed64,9,opt,name=linetype,proto3" json:"linetype,omitempty"`
+	Location      float64                `protobuf:"fixed64,10,opt,name=location,proto3" json:"location,omitempty"`
+	Name          float64                `protobuf:"fixed64,11,opt,name=name,proto3" json:"name,omitempty"`
+	Profile       float64                `protobuf:"fixed64,12,opt,name=profile,proto3" json:"profile,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Breakdown) Reset() {
+	*x = Breakdown{}
+	mi := &file_whatphone_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Breakdown) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Breakdown) ProtoMessage() {}
+
+func (x *Breakdown) ProtoReflect() protoreflect.Message {
+	mi := &file_whatphone_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Breakdown.ProtoReflect.Descriptor instead.
+func (*Breakdown) Descriptor() ([]byte, []int) {
+	return file_whatphone_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Breakdown) GetAddress() float64 {
+	if x != nil {
+		return x.Address
+	}
+	return 0
+}
+
+func (x *Breakdown) GetCarrier() float64 {
+	if x != nil {
+		return x.Carrier
+	}
+	return 0
+}
+
+func (x *Breakdown) GetCarrier_0() float64 {
+	if x != nil {
+		return x.Carrier_0
+	}
+	return 0
+}
+
+func (x *Breakdown) GetCnam() float64 {
+	if x != nil {
+		return x.Cnam
+	}
+	return 0
+}
+
+func (x *Breakdown) GetExpandedName() float64 {
+	if x != nil {
+		return x.ExpandedName
+	}
+	return 0
+}
+
+func (x *Breakdown) GetGender() float64 {
+	if x != nil {
+		return x.Gender
+	}
+	return 0
+}
+
+func (x *Breakdown) GetImage() float64 {
+	if x != nil {
+		return x.Image
+	}
+	return 0
+}
+
+func (x *Breakdown) GetLineProvider() float64 {
+	if x != nil {
+		return x.LineProvider
+	}
+	return 0
+}
+
+func (x *Breakdown) GetLinetype() float64 {
+	if x != nil {
+		return x.Linetype
+	}
+	return 0
+}
+
+func (x *Breakdown) GetLocation() float64 {
+	if x != nil {
+		return x.Location
+	}
+	return 0
+}
+
+func (x *Breakdown) GetName() float64 {
+	if x != nil {
+		return x.Name
+	}
+	return 0
+}
+
+func (x *Breakdown) GetProfile() float64 {
+	if x != nil {
+		return x.Profile
+	}
+	return 0
+}
+
+// Pricing holds the pricing data of a phone number lookup
+type Pricing struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Breakdown     *Breakdown             `protobuf:"bytes,1,opt,name=breakdown,proto3" json:"breakdown,omitempty"`
+	Total         float64                `protobuf:"fixed64,2,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Pricing) Reset() {
+	*x = Pricing{}
+	mi := &file_whatphone_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Pricing) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pricing) ProtoMessage() {}
+
+func (x *Pricing) ProtoReflect() protoreflect.Message {
+	mi := &file_whatphone_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pricing.ProtoReflect.Descriptor instead.
+func (*Pricing) Descriptor() ([]byte, []int) {
+	return file_whatphone_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Pricing) GetBreakdown() *Breakdown {
+	if x != nil {
+		return x.Breakdown
+	}
+	return nil
+}
+
+func (x *Pricing) GetTotal() float64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+var File_whatphone_proto protoreflect.FileDescriptor
+
+const file_whatphone_proto_rawDesc = "" +
+	"\n" +
+	"\x0fwhatphone.proto\x12\fwhatphone.v1\"]\n" +
+	"\rLookupRequest\x12\x16\n" +
+	"\x06number\x18\x01 \x01(\tR\x06number\x12\x12\n" +
+	"\x04data\x18\x02 \x03(\tR\x04data\x12\x10\n" +
+	"\x03all\x18\x03 \x01(\bR\x03all\x12\x0e\n" +
+	"\x02id\x18\x04 \x01(\tR\x02id\"\x80\x01\n" +
+	"\x12LookupManyResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06number\x18\x02 \x01(\tR\x06number\x12,\n" +
+	"\x06result\x18\x03 \x01(\v2\x14.whatphone.v1.ResultR\x06result\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"\xe3\x01\n" +
+	"\x06Result\x12&\n" +
+	"\x04data\x18\x01 \x01(\v2\x12.whatphone.v1.DataR\x04data\x12\x16\n" +
+	"\x06missed\x18\x02 \x03(\tR\x06missed\x12\x16\n" +
+	"\x06number\x18\x03 \x01(\tR\x06number\x12\x12\n" +
+	"\x04note\x18\x04 \x01(\tR\x04note\x12/\n" +
+	"\apricing\x18\x05 \x01(\v2\x15.whatphone.v1.PricingR\apricing\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\bR\x06status\x12\x12\n" +
+	"\x04type\x18\a \x01(\tR\x04type\x12\x10\n" +
+	"\x03raw\x18\b \x01(\fR\x03raw\"-\n" +
+	"\aCarrier\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"8\n" +
+	"\fExpandedName\x12\x14\n" +
+	"\x05first\x18\x01 \x01(\tR\x05first\x12\x12\n" +
+	"\x04last\x18\x02 \x01(\tR\x04last\"[\n" +
+	"\x05Image\x12\x14\n" +
+	"\x05cover\x18\x01 \x01(\tR\x05cover\x12\x14\n" +
+	"\x05large\x18\x02 \x01(\tR\x05large\x12\x10\n" +
+	"\x03med\x18\x03 \x01(\tR\x03med\x12\x14\n" +
+	"\x05small\x18\x04 \x01(\tR\x05small\"l\n" +
+	"\fLineProvider\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1b\n" +
+	"\tmms_email\x18\x02 \x01(\tR\bmmsEmail\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x1b\n" +
+	"\tsms_email\x18\x04 \x01(\tR\bsmsEmail\"?\n" +
+	"\x03Geo\x12\x1a\n" +
+	"\blatitude\x18\x01 \x01(\tR\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\x02 \x01(\tR\tlongitude\"k\n" +
+	"\bLocation\x12\x12\n" +
+	"\x04city\x18\x01 \x01(\tR\x04city\x12#\n" +
+	"\x03geo\x18\x02 \x01(\v2\x11.whatphone.v1.GeoR\x03geo\x12\x14\n" +
+	"\x05state\x18\x03 \x01(\tR\x05state\x12\x10\n" +
+	"\x03zip\x18\x04 \x01(\tR\x03zip\"Q\n" +
+	"\aProfile\x12\x10\n" +
+	"\x03edu\x18\x01 \x01(\tR\x03edu\x12\x10\n" +
+	"\x03job\x18\x02 \x01(\tR\x03job\x12\"\n" +
+	"\frelationship\x18\x03 \x01(\tR\frelationship\"\xc2\x04\n" +
+	"\x04Data\x12\x1d\n" +
+	"\aaddress\x18\x01 \x01(\tH\x00R\aaddress\x88\x01\x01\x12/\n" +
+	"\acarrier\x18\x02 \x01(\v2\x15.whatphone.v1.CarrierR\acarrier\x122\n" +
+	"\tcarrier_o\x18\x03 \x01(\v2\x15.whatphone.v1.CarrierR\bcarrierO\x12\x17\n" +
+	"\x04cnam\x18\x04 \x01(\tH\x01R\x04cnam\x88\x01\x01\x12?\n" +
+	"\rexpanded_name\x18\x05 \x01(\v2\x1a.whatphone.v1.ExpandedNameR\fexpandedName\x12\x1b\n" +
+	"\x06gender\x18\x06 \x01(\tH\x02R\x06gender\x88\x01\x01\x12)\n" +
+	"\x05image\x18\a \x01(\v2\x13.whatphone.v1.ImageR\x05image\x12?\n" +
+	"\rline_provider\x18\b \x01(\v2\x1a.whatphone.v1.LineProviderR\flineProvider\x12\x1f\n" +
+	"\blinetype\x18\t \x01(\tH\x03R\blinetype\x88\x01\x01\x122\n" +
+	"\blocation\x18\n" +
+	" \x01(\v2\x16.whatphone.v1.LocationR\blocation\x12\x17\n" +
+	"\x04name\x18\v \x01(\tH\x04R\x04name\x88\x01\x01\x12/\n" +
+	"\aprofile\x18\f \x01(\v2\x15.whatphone.v1.ProfileR\aprofileB\n" +
+	"\n" +
+	"\b_addressB\a\n" +
+	"\x05_cnamB\t\n" +
+	"\a_genderB\v\n" +
+	"\t_linetypeB\a\n" +
+	"\x05_name\"\xce\x02\n" +
+	"\tBreakdown\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\x01R\aaddress\x12\x18\n" +
+	"\acarrier\x18\x02 \x01(\x01R\acarrier\x12\x1b\n" +
+	"\tcarrier_0\x18\x03 \x01(\x01R\bcarrier0\x12\x12\n" +
+	"\x04cnam\x18\x04 \x01(\x01R\x04cnam\x12#\n" +
+	"\rexpanded_name\x18\x05 \x01(\x01R\fexpandedName\x12\x16\n" +
+	"\x06gender\x18\x06 \x01(\x01R\x06gender\x12\x14\n" +
+	"\x05image\x18\a \x01(\x01R\x05image\x12#\n" +
+	"\rline_provider\x18\b \x01(\x01R\flineProvider\x12\x1a\n" +
+	"\blinetype\x18\t \x01(\x01R\blinetype\x12\x1a\n" +
+	"\blocation\x18\n" +
+	" \x01(\x01R\blocation\x12\x12\n" +
+	"\x04name\x18\v \x01(\x01R\x04name\x12\x18\n" +
+	"\aprofile\x18\f \x01(\x01R\aprofile\"V\n" +
+	"\aPricing\x125\n" +
+	"\tbreakdown\x18\x01 \x01(\v2\x17.whatphone.v1.BreakdownR\tbreakdown\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x01R\x05total2\x9d\x01\n" +
+	"\rLookupService\x12;\n" +
+	"\x06Lookup\x12\x1b.whatphone.v1.LookupRequest\x1a\x14.whatphone.v1.Result\x12O\n" +
+	"\n" +
+	"LookupMany\x12\x1b.whatphone.v1.LookupRequest\x1a .whatphone.v1.LookupManyResponse(\x010\x01B$Z\"samhofi.us/x/whatphone/pkg/rpc;rpcb\x06proto3"
+
+var (
+	file_whatphone_proto_rawDescOnce sync.Once
+	file_whatphone_proto_rawDescData []byte
+)
+
+func file_whatphone_proto_rawDescGZIP() []byte {
+	file_whatphone_proto_rawDescOnce.Do(func() {
+		file_whatphone_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_whatphone_proto_rawDesc), len(file_whatphone_proto_rawDesc)))
+	})
+	return file_whatphone_proto_rawDescData
+}
+
+var file_whatphone_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_whatphone_proto_goTypes = []any{
+	(*LookupRequest)(nil),      // 0: whatphone.v1.LookupRequest
+	(*LookupManyResponse)(nil), // 1: whatphone.v1.LookupManyResponse
+	(*Result)(nil),             // 2: whatphone.v1.Result
+	(*Carrier)(nil),            // 3: whatphone.v1.Carrier
+	(*ExpandedName)(nil),       // 4: whatphone.v1.ExpandedName
+	(*Image)(nil),              // 5: whatphone.v1.Image
+	(*LineProvider)(nil),       // 6: whatphone.v1.LineProvider
+	(*Geo)(nil),                // 7: whatphone.v1.Geo
+	(*Location)(nil),           // 8: whatphone.v1.Location
+	(*Profile)(nil),            // 9: whatphone.v1.Profile
+	(*Data)(nil),               // 10: whatphone.v1.Data
+	(*Breakdown)(nil),          // 11: whatphone.v1.Breakdown
+	(*Pricing)(nil),            // 12: whatphone.v1.Pricing
+}
+var file_whatphone_proto_depIdxs = []int32{
+	2,  // 0: whatphone.v1.LookupManyResponse.result:type_name -> whatphone.v1.Result
+	10, // 1: whatphone.v1.Result.data:type_name -> whatphone.v1.Data
+	12, // 2: whatphone.v1.Result.pricing:type_name -> whatphone.v1.Pricing
+	7,  // 3: whatphone.v1.Location.geo:type_name -> whatphone.v1.Geo
+	3,  // 4: whatphone.v1.Data.carrier:type_name -> whatphone.v1.Carrier
+	3,  // 5: whatphone.v1.Data.carrier_o:type_name -> whatphone.v1.Carrier
+	4,  // 6: whatphone.v1.Data.expanded_name:type_name -> whatphone.v1.ExpandedName
+	5,  // 7: whatphone.v1.Data.image:type_name -> whatphone.v1.Image
+	6,  // 8: whatphone.v1.Data.line_provider:type_name -> whatphone.v1.LineProvider
+	8,  // 9: whatphone.v1.Data.location:type_name -> whatphone.v1.Location
+	9,  // 10: whatphone.v1.Data.profile:type_name -> whatphone.v1.Profile
+	11, // 11: whatphone.v1.Pricing.breakdown:type_name -> whatphone.v1.Breakdown
+	0,  // 12: whatphone.v1.LookupService.Lookup:input_type -> whatphone.v1.LookupRequest
+	0,  // 13: whatphone.v1.LookupService.LookupMany:input_type -> whatphone.v1.LookupRequest
+	2,  // 14: whatphone.v1.LookupService.Lookup:output_type -> whatphone.v1.Result
+	1,  // 15: whatphone.v1.LookupService.LookupMany:output_type -> whatphone.v1.LookupManyResponse
+	14, // [14:16] is the sub-list for method output_type
+	12, // [12:14] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
+}
+
+func init() { file_whatphone_proto_init() }
+func file_whatphone_proto_init() {
+	if File_whatphone_proto != nil {
+		return
+	}
+	file_whatphone_proto_msgTypes[10].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_whatphone_proto_rawDesc), len(file_whatphone_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_whatphone_proto_goTypes,
+		DependencyIndexes: file_whatphone_proto_depIdxs,
+		MessageInfos:      file_whatphone_proto_msgTypes,
+	}.Build()
+	File_whatphone_proto = out.File
+	file_whatphone_proto_goTypes = nil
+	file_whatphone_proto_depIdxs = nil
+}
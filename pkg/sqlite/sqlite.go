@@ -0,0 +1,382 @@
+// Package sqlite stores phone number lookup results in a SQLite database, so they can be queried
+// with SQL. It uses a pure Go SQLite driver, so no cgo is required.
+//
+//	sink, err := sqlite.Open("lookups.db")
+//	...
+//	defer sink.Close()
+//	err = sink.Write(ctx, result)
+//
+// Results are stored in a normalized schema: a row in lookups per phone number, referencing the
+// carriers and line_providers tables, along with its rows in locations, pricing and missed.
+// Writing a number again updates its rows, keeping data points the new result doesn't include,
+// and replaces its pricing with what the new lookup was charged.
+package sqlite // import "samhofi.us/x/whatphone/pkg/sqlite"
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+
+	// registers the pure Go "sqlite" database/sql driver
+	_ "modernc.org/sqlite"
+)
+
+// schemaVersion is the version of the schema created by Open, stored as the database's
+// user_version
+const schemaVersion = 1
+
+// schema creates the tables results are stored in
+const schema = `
+CREATE TABLE IF NOT EXISTS carriers (
+	id   TEXT PRIMARY KEY,
+	name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS line_providers (
+	id   TEXT PRIMARY KEY,
+	name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS lookups (
+	number              TEXT PRIMARY KEY,
+	type                TEXT NOT NULL,
+	note                TEXT NOT NULL,
+	name                TEXT,
+	first_name          TEXT,
+	last_name           TEXT,
+	cnam                TEXT,
+	gender              TEXT,
+	address             TEXT,
+	linetype            TEXT,
+	carrier_id          TEXT REFERENCES carriers(id),
+	original_carrier_id TEXT REFERENCES carriers(id),
+	line_provider_id    TEXT REFERENCES line_providers(id),
+	sms_email           TEXT,
+	mms_email           TEXT,
+	job                 TEXT,
+	education           TEXT,
+	relationship        TEXT,
+	image_small         TEXT,
+	image_medium        TEXT,
+	image_large         TEXT,
+	image_cover         TEXT,
+	total               REAL NOT NULL,
+	looked_up_at        TEXT NOT NULL,
+	raw                 TEXT
+);
+
+CREATE TABLE IF NOT EXISTS locations (
+	number    TEXT PRIMARY KEY REFERENCES lookups(number) ON DELETE CASCADE,
+	city      TEXT NOT NULL,
+	state     TEXT NOT NULL,
+	zip       TEXT NOT NULL,
+	latitude  REAL,
+	longitude REAL
+);
+
+CREATE TABLE IF NOT EXISTS pricing (
+	number     TEXT NOT NULL REFERENCES lookups(number) ON DELETE CASCADE,
+	data_point TEXT NOT NULL,
+	price      REAL NOT NULL,
+	PRIMARY KEY (number, data_point)
+);
+
+CREATE TABLE IF NOT EXISTS missed (
+	number     TEXT NOT NULL REFERENCES lookups(number) ON DELETE CASCADE,
+	data_point TEXT NOT NULL,
+	PRIMARY KEY (number, data_point)
+);
+`
+
+// upsertLookup inserts or updates a row in lookups. Columns the new result has no value for keep
+// their old value.
+const upsertLookup = `
+INSERT INTO lookups (
+	number, type, note, name, first_name, last_name, cnam, gender, address, linetype,
+	carrier_id, original_carrier_id, line_provider_id, sms_email, mms_email,
+	job, education, relationship, image_small, image_medium, image_large, image_cover,
+	total, looked_up_at, raw
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (number) DO UPDATE SET
+	type = excluded.type,
+	note = excluded.note,
+	name = coalesce(excluded.name, name),
+	first_name = coalesce(excluded.first_name, first_name),
+	last_name = coalesce(excluded.last_name, last_name),
+	cnam = coalesce(excluded.cnam, cnam),
+	gender = coalesce(excluded.gender, gender),
+	address = coalesce(excluded.address, address),
+	linetype = coalesce(excluded.linetype, linetype),
+	carrier_id = coalesce(excluded.carrier_id, carrier_id),
+	original_carrier_id = coalesce(excluded.original_carrier_id, original_carrier_id),
+	line_provider_id = coalesce(excluded.line_provider_id, line_provider_id),
+	sms_email = coalesce(excluded.sms_email, sms_email),
+	mms_email = coalesce(excluded.mms_email, mms_email),
+	job = coalesce(excluded.job, job),
+	education = coalesce(excluded.education, education),
+	relationship = coalesce(excluded.relationship, relationship),
+	image_small = coalesce(excluded.image_small, image_small),
+	image_medium = coalesce(excluded.image_medium, image_medium),
+	image_large = coalesce(excluded.image_large, image_large),
+	image_cover = coalesce(excluded.image_cover, image_cover),
+	total = excluded.total,
+	looked_up_at = excluded.looked_up_at,
+	raw = excluded.raw
+`
+
+// Sink writes lookup results to a SQLite database. It's safe for concurrent use.
+type Sink struct {
+	db *sql.DB
+
+	// now returns the time results are recorded as looked up at
+	now func() time.Time
+}
+
+// Open opens the SQLite database at path, creating it and its tables if needed. The path may also
+// be a "file:" URI.
+func Open(path string) (*Sink, error) {
+	dsn, err := dataSourceName(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Sink{db: db, now: time.Now}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return s, nil
+}
+
+// dataSourceName returns the URI opening the database at path with the pragmas the sink relies
+// on. File names are escaped, so they may hold characters like '?' and '#'.
+func dataSourceName(path string) (string, error) {
+	// an opaque URI keeps relative paths relative
+	u := &url.URL{Scheme: "file", Opaque: (&url.URL{Path: path}).EscapedPath()}
+	if strings.HasPrefix(path, "file:") {
+		var err error
+		if u, err = url.Parse(path); err != nil {
+			return "", err
+		}
+	}
+
+	q := u.Query()
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Add("_pragma", "busy_timeout(5000)")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// migrate creates the schema, refusing databases created by a newer version of this package
+func (s *Sink) migrate() error {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > schemaVersion {
+		return fmt.Errorf("schema version %d is newer than the supported version %d", version, schemaVersion)
+	}
+
+	if _, err := s.db.Exec(schema); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion))
+	return err
+}
+
+// DB returns the underlying database, for querying stored results
+func (s *Sink) DB() *sql.DB {
+	return s.db
+}
+
+// Close closes the database
+func (s *Sink) Close() error {
+	return s.db.Close()
+}
+
+// Write inserts a lookup result into the database, or updates the stored result of its number
+func (s *Sink) Write(ctx context.Context, result *whatphone.Result) error {
+	return s.WriteNumber(ctx, result.Number, result)
+}
+
+// WriteNumber is like Write, but stores the result under number rather than the result's own
+// number. Results with masked numbers, which many numbers can share, are kept apart this way.
+func (s *Sink) WriteNumber(ctx context.Context, number string, result *whatphone.Result) error {
+	if number == "" {
+		return fmt.Errorf("result has no phone number")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := write(ctx, tx, number, result, s.now().UTC()); err != nil {
+		return fmt.Errorf("writing %s: %v", result.Number, err)
+	}
+	return tx.Commit()
+}
+
+// write writes a result stored under number within a transaction
+func write(ctx context.Context, tx *sql.Tx, number string, result *whatphone.Result, now time.Time) error {
+	d := &result.Data
+
+	var carrierID, originalCarrierID, lineProviderID, smsEmail, mmsEmail *string
+	if d.Carrier != nil {
+		if err := upsertCarrier(ctx, tx, d.Carrier.ID, d.Carrier.Name); err != nil {
+			return err
+		}
+		carrierID = nullable(d.Carrier.ID)
+	}
+	if d.CarrierO != nil {
+		if err := upsertCarrier(ctx, tx, d.CarrierO.ID, d.CarrierO.Name); err != nil {
+			return err
+		}
+		originalCarrierID = nullable(d.CarrierO.ID)
+	}
+	if p := d.LineProvider; p != nil {
+		if p.ID != "" {
+			_, err := tx.ExecContext(ctx, `INSERT INTO line_providers (id, name) VALUES (?, ?)
+				ON CONFLICT (id) DO UPDATE SET name = excluded.name`, p.ID, p.Name)
+			if err != nil {
+				return err
+			}
+		}
+		lineProviderID, smsEmail, mmsEmail = nullable(p.ID), nullable(p.SmsEmail), nullable(p.MmsEmail)
+	}
+
+	var firstName, lastName *string
+	if n := d.ExpandedName; n != nil {
+		firstName, lastName = nullable(n.First), nullable(n.Last)
+	}
+	var job, education, relationship *string
+	if p := d.Profile; p != nil {
+		job, education, relationship = nullable(p.Job), nullable(p.Edu), nullable(p.Relationship)
+	}
+	var small, medium, large, cover *string
+	if i := d.Image; i != nil {
+		small, medium, large, cover = nullable(i.Small), nullable(i.Med), nullable(i.Large), nullable(i.Cover)
+	}
+	var raw *string
+	if len(result.Raw) > 0 {
+		raw = nullable(string(result.Raw))
+	}
+
+	_, err := tx.ExecContext(ctx, upsertLookup,
+		number, result.Type, result.Note, d.Name, firstName, lastName, d.Cnam, d.Gender, d.Address, d.Linetype,
+		carrierID, originalCarrierID, lineProviderID, smsEmail, mmsEmail,
+		job, education, relationship, small, medium, large, cover,
+		result.Pricing.Total, now.Format(time.RFC3339), raw,
+	)
+	if err != nil {
+		return err
+	}
+
+	if l := d.Location; l != nil {
+		var lat, lon *float64
+		if la, lo, ok := d.Coordinates(); ok {
+			lat, lon = &la, &lo
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO locations (number, city, state, zip, latitude, longitude)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (number) DO UPDATE SET city = excluded.city, state = excluded.state,
+				zip = excluded.zip, latitude = excluded.latitude, longitude = excluded.longitude`,
+			number, l.City, l.State, l.Zip, lat, lon)
+		if err != nil {
+			return err
+		}
+	}
+
+	// pricing is what the latest lookup was charged, along with its total
+	if _, err := tx.ExecContext(ctx, `DELETE FROM pricing WHERE number = ?`, number); err != nil {
+		return err
+	}
+	prices := result.Pricing.Breakdown.Prices()
+	for _, dp := range sortedKeys(prices) {
+		if prices[dp] == 0 {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO pricing (number, data_point, price) VALUES (?, ?, ?)`, number, dp, prices[dp])
+		if err != nil {
+			return err
+		}
+	}
+
+	// data points this result has aren't missed anymore
+	for _, dp := range present(d) {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM missed WHERE number = ? AND data_point = ?`, number, dp); err != nil {
+			return err
+		}
+	}
+	for _, dp := range result.Missed {
+		_, err := tx.ExecContext(ctx, `INSERT INTO missed (number, data_point) VALUES (?, ?)
+			ON CONFLICT (number, data_point) DO NOTHING`, number, dp)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upsertCarrier inserts or renames a carrier
+func upsertCarrier(ctx context.Context, tx *sql.Tx, id, name string) error {
+	if id == "" {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO carriers (id, name) VALUES (?, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name`, id, name)
+	return err
+}
+
+// present returns the names of the data points included in data
+func present(d *whatphone.Data) []string {
+	var dps []string
+	for dp, ok := range map[whatphone.DataPoint]bool{
+		whatphone.DataPointName:            d.Name != nil,
+		whatphone.DataPointProfile:         d.Profile != nil,
+		whatphone.DataPointCNAM:            d.Cnam != nil,
+		whatphone.DataPointGender:          d.Gender != nil,
+		whatphone.DataPointImage:           d.Image != nil,
+		whatphone.DataPointAddress:         d.Address != nil,
+		whatphone.DataPointLocation:        d.Location != nil,
+		whatphone.DataPointLineProvider:    d.LineProvider != nil,
+		whatphone.DataPointCarrier:         d.Carrier != nil,
+		whatphone.DataPointOriginalCarrier: d.CarrierO != nil,
+		whatphone.DataPointLineType:        d.Linetype != nil,
+	} {
+		if ok {
+			dps = append(dps, dp.String())
+		}
+	}
+	sort.Strings(dps)
+	return dps
+}
+
+// nullable returns a pointer to s, or nil when it's empty so it's stored as NULL
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,221 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func openTest(t *testing.T, path string) *Sink {
+	t.Helper()
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Error: Open returned error: %v", err)
+	}
+	s.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+	return s
+}
+
+func sampleResult() *whatphone.Result {
+	return &whatphone.Result{
+		Data:    apitest.Sample(),
+		Missed:  []string{},
+		Number:  apitest.SampleNumber,
+		Note:    apitest.SampleNote,
+		Pricing: whatphone.Pricing{Breakdown: whatphone.Breakdown{Name: 0.01, Carrier: 0.003}, Total: 0.013},
+		Status:  true,
+		Type:    "person",
+		Raw:     []byte(`{"number":"+15551234567"}`),
+	}
+}
+
+func TestWrite(t *testing.T) {
+	s := openTest(t, filepath.Join(t.TempDir(), "lookups.db"))
+	defer s.Close()
+	ctx := context.Background()
+
+	if err := s.Write(ctx, sampleResult()); err != nil {
+		t.Fatalf("Error: Write returned error: %v", err)
+	}
+
+	var name, firstName, carrier, originalCarrier, lineProvider, city, lookedUpAt, raw string
+	var lat, total float64
+	err := s.DB().QueryRow(`
+		SELECT l.name, l.first_name, c.name, o.name, p.name, loc.city, loc.latitude, l.total, l.looked_up_at, l.raw
+		FROM lookups l
+		JOIN carriers c ON c.id = l.carrier_id
+		JOIN carriers o ON o.id = l.original_carrier_id
+		JOIN line_providers p ON p.id = l.line_provider_id
+		JOIN locations loc ON loc.number = l.number
+		WHERE l.number = ?`, apitest.SampleNumber).
+		Scan(&name, &firstName, &carrier, &originalCarrier, &lineProvider, &city, &lat, &total, &lookedUpAt, &raw)
+	if err != nil {
+		t.Fatalf("Error: querying the stored lookup returned error: %v", err)
+	}
+
+	got := []interface{}{name, firstName, carrier, originalCarrier, lineProvider, city, lat, total, lookedUpAt, raw}
+	want := []interface{}{
+		"Michael Seaver", "Michael", "Growing Wireless Inc.", "Paine Mobile Inc.", "MysticVoice", "Long Island",
+		40.799787, 0.013, "2026-01-02T03:04:05Z", `{"number":"+15551234567"}`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Error: Unexpected stored lookup.\nGot: %v\nWant: %v", got, want)
+	}
+
+	prices := queryStrings(t, s.DB(), `SELECT data_point || '=' || price FROM pricing ORDER BY data_point`)
+	if want := []string{"carrier=0.003", "name=0.01"}; !reflect.DeepEqual(prices, want) {
+		t.Errorf("Error: Unexpected pricing breakdown. Got: %v, Want: %v", prices, want)
+	}
+}
+
+func TestWriteUpsert(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lookups.db")
+	s := openTest(t, path)
+	ctx := context.Background()
+
+	first := sampleResult()
+	first.Data.Name = nil
+	first.Missed = []string{"name"}
+	if err := s.Write(ctx, first); err != nil {
+		t.Fatalf("Error: Write returned error: %v", err)
+	}
+	if missed := queryStrings(t, s.DB(), `SELECT data_point FROM missed`); !reflect.DeepEqual(missed, []string{"name"}) {
+		t.Errorf("Error: Unexpected missed data points. Got: %v, Want: [name]", missed)
+	}
+	s.Close()
+
+	// a later lookup of fewer data points keeps the ones it didn't include
+	s = openTest(t, path)
+	defer s.Close()
+	name := "Jane Doe"
+	second := &whatphone.Result{
+		Data:   whatphone.Data{Name: &name, Carrier: &whatphone.Carrier{ID: "214", Name: "Renamed Wireless"}},
+		Missed: []string{"cnam"},
+		Number: apitest.SampleNumber,
+		Type:   "person",
+	}
+	if err := s.Write(ctx, second); err != nil {
+		t.Fatalf("Error: Write returned error: %v", err)
+	}
+
+	var storedName, cnam, carrier string
+	err := s.DB().QueryRow(`SELECT l.name, l.cnam, c.name FROM lookups l JOIN carriers c ON c.id = l.carrier_id`).
+		Scan(&storedName, &cnam, &carrier)
+	if err != nil {
+		t.Fatalf("Error: querying the stored lookup returned error: %v", err)
+	}
+	if storedName != "Jane Doe" || cnam != "MICHAEL SEAVER" || carrier != "Renamed Wireless" {
+		t.Errorf("Error: Unexpected upserted lookup. Got: %s, %s, %s, Want: Jane Doe, MICHAEL SEAVER, Renamed Wireless", storedName, cnam, carrier)
+	}
+
+	if missed := queryStrings(t, s.DB(), `SELECT data_point FROM missed`); !reflect.DeepEqual(missed, []string{"cnam"}) {
+		t.Errorf("Error: Unexpected missed data points. Got: %v, Want: [cnam]", missed)
+	}
+	if n := queryStrings(t, s.DB(), `SELECT count(*) FROM lookups`); !reflect.DeepEqual(n, []string{"1"}) {
+		t.Errorf("Error: Unexpected number of lookups. Got: %v, Want: 1", n)
+	}
+
+	// the second lookup wasn't charged, so the first lookup's pricing is gone
+	if pricing := queryStrings(t, s.DB(), `SELECT data_point FROM pricing`); len(pricing) != 0 {
+		t.Errorf("Error: Unexpected pricing. Got: %v, Want: []", pricing)
+	}
+}
+
+func TestOpenPaths(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	paths := []string{
+		"relative.db",
+		filepath.Join(dir, "what?.db"),
+		filepath.Join(dir, "100% #1.db"),
+		"file:" + filepath.Join(dir, "uri.db") + "?cache=private",
+	}
+
+	for _, path := range paths {
+		s := openTest(t, path)
+		if err := s.Write(context.Background(), sampleResult()); err != nil {
+			t.Errorf("Error: Write to %s returned error: %v", path, err)
+		}
+		var fk int
+		if err := s.DB().QueryRow("PRAGMA foreign_keys").Scan(&fk); err != nil || fk != 1 {
+			t.Errorf("Error: foreign keys aren't enforced in %s. Got: %d (%v)", path, fk, err)
+		}
+		s.Close()
+	}
+
+	for _, name := range []string{"relative.db", "what?.db", "100% #1.db", "uri.db"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Error: database wasn't created at its path: %v", err)
+		}
+	}
+}
+
+func TestWriteNumber(t *testing.T) {
+	s := openTest(t, filepath.Join(t.TempDir(), "lookups.db"))
+	defer s.Close()
+
+	result := sampleResult()
+	result.Number = "+*******4567"
+	for _, key := range []string{"first", "second", "first"} {
+		if err := s.WriteNumber(context.Background(), key, result); err != nil {
+			t.Fatalf("Error: WriteNumber returned error: %v", err)
+		}
+	}
+
+	if numbers := queryStrings(t, s.DB(), `SELECT number FROM lookups ORDER BY number`); !reflect.DeepEqual(numbers, []string{"first", "second"}) {
+		t.Errorf("Error: Unexpected stored numbers. Got: %v, Want: [first second]", numbers)
+	}
+	if numbers := queryStrings(t, s.DB(), `SELECT DISTINCT number FROM pricing ORDER BY number`); !reflect.DeepEqual(numbers, []string{"first", "second"}) {
+		t.Errorf("Error: Unexpected pricing numbers. Got: %v, Want: [first second]", numbers)
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	s := openTest(t, filepath.Join(t.TempDir(), "lookups.db"))
+	defer s.Close()
+
+	if err := s.Write(context.Background(), &whatphone.Result{}); err == nil {
+		t.Errorf("Error: Write should have refused a result without a number")
+	}
+}
+
+func TestOpenNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lookups.db")
+	s := openTest(t, path)
+	if _, err := s.DB().Exec("PRAGMA user_version = 99"); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	if s, err := Open(path); err == nil {
+		s.Close()
+		t.Errorf("Error: Open should have refused a database with a newer schema")
+	}
+}
+
+// queryStrings returns the first column of every row returned by a query
+func queryStrings(t *testing.T, db *sql.DB, query string) []string {
+	t.Helper()
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatalf("Error: %s returned error: %v", query, err)
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, v)
+	}
+	return values
+}
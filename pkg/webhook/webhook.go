@@ -0,0 +1,426 @@
+// Package webhook delivers phone number lookup results to an HTTP endpoint as they're made.
+//
+//	sink := webhook.New("https://example.com/hooks/whatphone",
+//		webhook.WithSecret(secret),
+//		webhook.WithDeadLetter("undelivered.jsonl"),
+//	)
+//	err := sink.Write(ctx, result)
+//
+// Each result is POSTed as JSON. When a secret is set, requests are signed with HMAC-SHA256 over
+// the timestamp and body, so receivers can check them with Verify. Deliveries failing with a
+// network error, 408, 429 or 5xx status are retried with exponential backoff. Deliveries that
+// still fail are appended to the dead-letter file, if one is set, to be replayed later. Once
+// several events in a row have failed, later events are attempted only once until one gets
+// through, so a receiver that's down doesn't cost the full backoff for every event.
+//
+// A Queue delivers events from a background goroutine, so lookups don't wait on the receiver:
+//
+//	queue := webhook.NewQueue(sink, 100)
+//	queue.Write(result)
+//	errs := queue.Close()
+package webhook // import "samhofi.us/x/whatphone/pkg/webhook"
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+)
+
+// Headers sent with every delivery
+const (
+	// SignatureHeader holds "sha256=" followed by the hex encoded HMAC-SHA256 of the timestamp, a
+	// dot, and the body. It's only sent when a secret is set.
+	SignatureHeader = "X-Whatphone-Signature"
+
+	// TimestampHeader holds the Unix time the delivery was signed at
+	TimestampHeader = "X-Whatphone-Timestamp"
+
+	// EventHeader holds the kind of event delivered
+	EventHeader = "X-Whatphone-Event"
+
+	// DeliveryHeader holds an ID unique to each delivery, which stays the same across retries so
+	// receivers can ignore duplicates
+	DeliveryHeader = "X-Whatphone-Delivery"
+)
+
+// Events delivered
+const (
+	// EventLookup delivers a lookup result
+	EventLookup = "lookup"
+
+	// EventTest delivers a sample result, to check a receiver is set up correctly
+	EventTest = "test"
+
+	// EventChange delivers an alert that data about a watched number changed
+	EventChange = "change"
+)
+
+// Defaults used unless overridden by options
+const (
+	DefaultAttempts    = 5
+	DefaultBackoff     = time.Second
+	DefaultMaxBackoff  = 30 * time.Second
+	DefaultTimeout     = 10 * time.Second
+	DefaultMaxFailures = 3
+)
+
+// Sink delivers lookup results to a webhook URL. It's safe for concurrent use.
+type Sink struct {
+	url         string
+	secret      []byte
+	client      *http.Client
+	attempts    int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	deadLetter  string
+	maxFailures int
+
+	// mu serializes writes to the dead-letter file, and guards failures
+	mu       sync.Mutex
+	failures int
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Option configures a Sink
+type Option func(s *Sink)
+
+// WithSecret signs every delivery with HMAC-SHA256 using secret
+func WithSecret(secret string) Option {
+	return func(s *Sink) {
+		s.secret = []byte(secret)
+	}
+}
+
+// WithClient sets the HTTP client deliveries are made with
+func WithClient(client *http.Client) Option {
+	return func(s *Sink) {
+		s.client = client
+	}
+}
+
+// WithRetries sets the number of attempts made to deliver each event, and the backoff before the
+// first retry, which doubles with each retry after it up to max
+func WithRetries(attempts int, backoff, max time.Duration) Option {
+	return func(s *Sink) {
+		s.attempts = attempts
+		s.backoff = backoff
+		s.maxBackoff = max
+	}
+}
+
+// WithDeadLetter appends deliveries that fail for good to the file at path, as JSON lines
+func WithDeadLetter(path string) Option {
+	return func(s *Sink) {
+		s.deadLetter = path
+	}
+}
+
+// WithMaxFailures sets how many events in a row may fail for good before later events are
+// attempted only once, without retrying, until one is delivered. 0 always retries.
+func WithMaxFailures(n int) Option {
+	return func(s *Sink) {
+		s.maxFailures = n
+	}
+}
+
+// New returns a Sink delivering to url
+func New(url string, opts ...Option) *Sink {
+	s := &Sink{
+		url:         url,
+		client:      &http.Client{Timeout: DefaultTimeout},
+		attempts:    DefaultAttempts,
+		backoff:     DefaultBackoff,
+		maxBackoff:  DefaultMaxBackoff,
+		maxFailures: DefaultMaxFailures,
+		now:         time.Now,
+		sleep:       sleep,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.attempts < 1 {
+		s.attempts = 1
+	}
+	return s
+}
+
+// DeadLetter is a delivery that failed for good, as written to the dead-letter file
+type DeadLetter struct {
+	Time     time.Time       `json:"time"`
+	URL      string          `json:"url"`
+	Event    string          `json:"event"`
+	Delivery string          `json:"delivery"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// Write delivers a lookup result, retrying on failure. If it can't be delivered, it's written to
+// the dead-letter file, and an error is returned either way.
+func (s *Sink) Write(ctx context.Context, result *whatphone.Result) error {
+	return s.Send(ctx, EventLookup, result)
+}
+
+// Send delivers an event with v as its JSON encoded payload, retrying on failure. If it can't be
+// delivered, it's written to the dead-letter file, and an error is returned either way.
+func (s *Sink) Send(ctx context.Context, event string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	delivery := newDeliveryID()
+
+	attempts := s.attempts
+	s.mu.Lock()
+	if s.maxFailures > 0 && s.failures >= s.maxFailures {
+		attempts = 1
+	}
+	s.mu.Unlock()
+
+	var attempt int
+	for attempt = 1; ; attempt++ {
+		var retryAfter time.Duration
+		retryAfter, err = s.deliver(ctx, event, delivery, body)
+		if err == nil {
+			s.mu.Lock()
+			s.failures = 0
+			s.mu.Unlock()
+			return nil
+		}
+		if attempt >= attempts || retryAfter < 0 || ctx.Err() != nil {
+			break
+		}
+
+		wait := s.backoff << (attempt - 1)
+		if wait > s.maxBackoff || wait <= 0 {
+			wait = s.maxBackoff
+		}
+		if retryAfter > wait {
+			wait = min(retryAfter, s.maxBackoff)
+		}
+		if serr := s.sleep(ctx, wait); serr != nil {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	s.failures++
+	s.mu.Unlock()
+
+	err = fmt.Errorf("delivering %s event to %s failed after %d attempts: %v", event, s.url, attempt, err)
+	if s.deadLetter == "" {
+		return err
+	}
+	if derr := s.writeDeadLetter(DeadLetter{
+		Time:     s.now().UTC(),
+		URL:      s.url,
+		Event:    event,
+		Delivery: delivery,
+		Attempts: attempt,
+		Error:    err.Error(),
+		Payload:  body,
+	}); derr != nil {
+		return fmt.Errorf("%v; writing dead letter: %v", err, derr)
+	}
+	return fmt.Errorf("%v; written to %s", err, s.deadLetter)
+}
+
+// Queue delivers events to a Sink from a background goroutine, in the order they were queued
+type Queue struct {
+	sink   *Sink
+	events chan queued
+	done   chan struct{}
+	once   sync.Once
+	errs   []error
+}
+
+// queued is an event waiting to be delivered
+type queued struct {
+	event string
+	v     interface{}
+}
+
+// NewQueue returns a Queue delivering to s, holding up to size events waiting for delivery
+func NewQueue(s *Sink, size int) *Queue {
+	q := &Queue{
+		sink:   s,
+		events: make(chan queued, size),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(q.done)
+		for e := range q.events {
+			if err := q.sink.Send(context.Background(), e.event, e.v); err != nil {
+				q.errs = append(q.errs, err)
+			}
+		}
+	}()
+	return q
+}
+
+// Write queues a lookup result for delivery
+func (q *Queue) Write(result *whatphone.Result) {
+	q.Send(EventLookup, result)
+}
+
+// Send queues an event with v as its JSON encoded payload, blocking only while the queue is full.
+// v must not be changed until the queue is closed.
+func (q *Queue) Send(event string, v interface{}) {
+	q.events <- queued{event: event, v: v}
+}
+
+// Close waits for every queued event to be delivered or written to the dead-letter file, and
+// returns the errors of the ones that failed. Nothing may be queued after Close is called, but
+// calling it again is safe.
+func (q *Queue) Close() []error {
+	q.once.Do(func() { close(q.events) })
+	<-q.done
+	return q.errs
+}
+
+// Test delivers a test event with a sample result once, without retrying or writing a dead letter
+func (s *Sink) Test(ctx context.Context, result *whatphone.Result) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	_, err = s.deliver(ctx, EventTest, newDeliveryID(), body)
+	return err
+}
+
+// deliver makes a single delivery attempt. When it fails, it also returns how long to wait before
+// retrying, which is 0 if the receiver didn't say, or -1 when the failure isn't worth retrying.
+func (s *Sink) deliver(ctx context.Context, event, delivery string, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return -1, err
+	}
+
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "whatphone-webhook")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, delivery)
+	req.Header.Set(TimestampHeader, timestamp)
+	if len(s.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(s.secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, nil
+	}
+	err = fmt.Errorf("%s", resp.Status)
+	switch {
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return retryAfter(resp.Header.Get("Retry-After"), s.now()), err
+	}
+	return -1, err
+}
+
+// writeDeadLetter appends a failed delivery to the dead-letter file
+func (s *Sink) writeDeadLetter(dl DeadLetter) error {
+	b, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.deadLetter, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Sign returns the signature of a delivery made at timestamp with body, as sent in
+// SignatureHeader
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	io.WriteString(mac, timestamp+".")
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a delivery, given the values of its SignatureHeader and
+// TimestampHeader headers. Deliveries signed more than tolerance ago, or in the future, are
+// rejected to prevent replays, unless tolerance is 0.
+func Verify(secret []byte, signature, timestamp string, body []byte, tolerance time.Duration) error {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return fmt.Errorf("missing signature")
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body))) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	if tolerance > 0 {
+		sec, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp: %q", timestamp)
+		}
+		if age := time.Since(time.Unix(sec, 0)); age > tolerance || age < -tolerance {
+			return fmt.Errorf("timestamp outside of tolerance")
+		}
+	}
+	return nil
+}
+
+// retryAfter parses a Retry-After header, given in seconds or as an HTTP date
+func retryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(header); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// newDeliveryID returns a random delivery ID
+func newDeliveryID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// sleep waits for d, or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
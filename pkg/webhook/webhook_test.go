@@ -0,0 +1,309 @@
+package webhook
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+)
+
+const testSecret = "s3cret"
+
+// receiver is a local webhook receiver answering each delivery with the next of its statuses,
+// and then with 200 OK
+type receiver struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	statuses   []int
+	retryAfter string
+	deliveries []*http.Request
+	bodies     [][]byte
+}
+
+func newReceiver(statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.deliveries = append(r.deliveries, req)
+		r.bodies = append(r.bodies, body)
+
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		if r.retryAfter != "" {
+			w.Header().Set("Retry-After", r.retryAfter)
+		}
+		w.WriteHeader(status)
+	}))
+	return r
+}
+
+// testSink returns a sink delivering to r, recording how long it sleeps between retries
+func testSink(r *receiver, opts ...Option) (*Sink, *[]time.Duration) {
+	var waits []time.Duration
+	s := New(r.URL, append([]Option{WithSecret(testSecret)}, opts...)...)
+	s.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return s, &waits
+}
+
+func testResult() *whatphone.Result {
+	name := "Jane Doe"
+	return &whatphone.Result{Number: "+15557654321", Data: whatphone.Data{Name: &name}, Status: true}
+}
+
+func TestWrite(t *testing.T) {
+	r := newReceiver()
+	defer r.Close()
+	s, waits := testSink(r)
+
+	if err := s.Write(context.Background(), testResult()); err != nil {
+		t.Fatalf("Error: Write returned error: %v", err)
+	}
+	if len(r.deliveries) != 1 || len(*waits) != 0 {
+		t.Fatalf("Error: Unexpected number of deliveries. Got: %d, Want: 1", len(r.deliveries))
+	}
+
+	req, body := r.deliveries[0], r.bodies[0]
+	if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Error: Unexpected request: %s %s", req.Method, req.Header.Get("Content-Type"))
+	}
+	if got := req.Header.Get(EventHeader); got != EventLookup {
+		t.Errorf("Error: Unexpected event. Got: %s, Want: %s", got, EventLookup)
+	}
+	if err := Verify([]byte(testSecret), req.Header.Get(SignatureHeader), req.Header.Get(TimestampHeader), body, time.Minute); err != nil {
+		t.Errorf("Error: Delivery has an invalid signature: %v", err)
+	}
+
+	var result whatphone.Result
+	if err := json.Unmarshal(body, &result); err != nil || result.Data.GetName() != "Jane Doe" {
+		t.Errorf("Error: Unexpected payload (%v): %s", err, body)
+	}
+}
+
+func TestWriteRetries(t *testing.T) {
+	r := newReceiver(http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusTooManyRequests)
+	defer r.Close()
+	s, waits := testSink(r, WithRetries(5, time.Second, 3*time.Second))
+
+	if err := s.Write(context.Background(), testResult()); err != nil {
+		t.Fatalf("Error: Write returned error: %v", err)
+	}
+	if len(r.deliveries) != 4 {
+		t.Errorf("Error: Unexpected number of deliveries. Got: %d, Want: %d", len(r.deliveries), 4)
+	}
+	if want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}; !reflect.DeepEqual(*waits, want) {
+		t.Errorf("Error: Unexpected backoff. Got: %v, Want: %v", *waits, want)
+	}
+
+	id := r.deliveries[0].Header.Get(DeliveryHeader)
+	for _, req := range r.deliveries {
+		if req.Header.Get(DeliveryHeader) != id {
+			t.Errorf("Error: Delivery ID changed between retries. Got: %s, Want: %s", req.Header.Get(DeliveryHeader), id)
+		}
+	}
+}
+
+func TestWriteRetryAfter(t *testing.T) {
+	r := newReceiver(http.StatusTooManyRequests)
+	r.retryAfter = "7"
+	defer r.Close()
+	s, waits := testSink(r)
+
+	if err := s.Write(context.Background(), testResult()); err != nil {
+		t.Fatalf("Error: Write returned error: %v", err)
+	}
+	if want := []time.Duration{7 * time.Second}; !reflect.DeepEqual(*waits, want) {
+		t.Errorf("Error: Retry-After wasn't honored. Got: %v, Want: %v", *waits, want)
+	}
+}
+
+func TestWriteDeadLetter(t *testing.T) {
+	r := newReceiver(500, 500, 500, http.StatusBadRequest)
+	defer r.Close()
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	s, _ := testSink(r, WithRetries(3, time.Second, time.Minute), WithDeadLetter(path))
+
+	// retries give up after 3 attempts
+	err := s.Write(context.Background(), testResult())
+	if err == nil || !strings.Contains(err.Error(), "failed after 3 attempts: 500 Internal Server Error; written to "+path) {
+		t.Errorf("Error: Write returned unexpected error: %v", err)
+	}
+
+	// client errors aren't retried
+	err = s.Write(context.Background(), testResult())
+	if err == nil || !strings.Contains(err.Error(), "failed after 1 attempts: 400 Bad Request") {
+		t.Errorf("Error: Write returned unexpected error: %v", err)
+	}
+	if len(r.deliveries) != 4 {
+		t.Errorf("Error: Unexpected number of deliveries. Got: %d, Want: %d", len(r.deliveries), 4)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Error: dead-letter file wasn't written: %v", err)
+	}
+	defer f.Close()
+
+	var letters []DeadLetter
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var dl DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &dl); err != nil {
+			t.Fatalf("Error: invalid dead letter: %v", err)
+		}
+		letters = append(letters, dl)
+	}
+	if len(letters) != 2 {
+		t.Fatalf("Error: Unexpected number of dead letters. Got: %d, Want: %d", len(letters), 2)
+	}
+	if dl := letters[0]; dl.Attempts != 3 || dl.Event != EventLookup || dl.URL != r.URL || !strings.Contains(string(dl.Payload), `"+15557654321"`) {
+		t.Errorf("Error: Unexpected dead letter: %+v", dl)
+	}
+}
+
+func TestWriteMaxFailures(t *testing.T) {
+	r := newReceiver(500, 500, 500, 500, 500, 500, 500)
+	defer r.Close()
+	s, _ := testSink(r, WithRetries(3, time.Second, time.Minute), WithMaxFailures(2))
+
+	// after 2 events fail for good, later events are attempted once until one is delivered
+	for i, attempts := range []int{3, 3, 1} {
+		err := s.Write(context.Background(), testResult())
+		if want := "failed after " + strconv.Itoa(attempts) + " attempts"; err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Error: Write %d returned unexpected error. Got: %v, Want: %s", i+1, err, want)
+		}
+	}
+	if err := s.Write(context.Background(), testResult()); err != nil {
+		t.Errorf("Error: Write returned error: %v", err)
+	}
+
+	// a delivery resets the count
+	r.mu.Lock()
+	r.statuses = []int{500, 500, 500}
+	r.mu.Unlock()
+	if err := s.Write(context.Background(), testResult()); err == nil || !strings.Contains(err.Error(), "failed after 3 attempts") {
+		t.Errorf("Error: Write returned unexpected error after a delivery: %v", err)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	r := newReceiver(http.StatusBadRequest)
+	defer r.Close()
+	s, _ := testSink(r)
+
+	q := NewQueue(s, 1)
+	numbers := []string{"+15557654321", "+15551234567", "+15550000000"}
+	for _, number := range numbers {
+		result := testResult()
+		result.Number = number
+		q.Write(result)
+	}
+	errs := q.Close()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "400 Bad Request") {
+		t.Errorf("Error: Close returned unexpected errors: %v", errs)
+	}
+	if len(q.Close()) != 1 {
+		t.Errorf("Error: Closing again should return the same errors")
+	}
+
+	if len(r.bodies) != len(numbers) {
+		t.Fatalf("Error: Unexpected number of deliveries. Got: %d, Want: %d", len(r.bodies), len(numbers))
+	}
+	for i, number := range numbers {
+		var result whatphone.Result
+		if err := json.Unmarshal(r.bodies[i], &result); err != nil || result.Number != number {
+			t.Errorf("Error: Delivery %d is out of order (%v): %s", i+1, err, r.bodies[i])
+		}
+	}
+}
+
+func TestTest(t *testing.T) {
+	r := newReceiver(http.StatusUnauthorized)
+	defer r.Close()
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	s, waits := testSink(r, WithDeadLetter(path))
+
+	err := s.Test(context.Background(), testResult())
+	if err == nil || err.Error() != "401 Unauthorized" {
+		t.Errorf("Error: Test returned unexpected error: %v", err)
+	}
+	if err := s.Test(context.Background(), testResult()); err != nil {
+		t.Errorf("Error: Test returned error: %v", err)
+	}
+	if got := r.deliveries[1].Header.Get(EventHeader); got != EventTest {
+		t.Errorf("Error: Unexpected event. Got: %s, Want: %s", got, EventTest)
+	}
+	if len(*waits) != 0 {
+		t.Errorf("Error: Test retried: %v", *waits)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Error: Test wrote a dead letter")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	secret := []byte(testSecret)
+	body := []byte(`{"number":"+15557654321"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		signature string
+		timestamp string
+		body      []byte
+		tolerance time.Duration
+		ok        bool
+	}{
+		{Sign(secret, now, body), now, body, time.Minute, true},
+		{Sign(secret, old, body), old, body, 0, true},
+		{Sign(secret, old, body), old, body, time.Minute, false},
+		{Sign(secret, now, body), now, []byte(`{"number":"+15551234567"}`), time.Minute, false},
+		{Sign([]byte("wrong"), now, body), now, body, time.Minute, false},
+		{Sign(secret, now, body), old, body, 0, false},
+		{"", now, body, 0, false},
+	}
+
+	for i, test := range tests {
+		err := Verify(secret, test.signature, test.timestamp, test.body, test.tolerance)
+		if (err == nil) != test.ok {
+			t.Errorf("Error: Verify test %d returned unexpected error: %v", i, err)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"12":                            12 * time.Second,
+		"-1":                            0,
+		"soon":                          0,
+		"Fri, 02 Jan 2026 03:05:05 GMT": time.Minute,
+		"Fri, 02 Jan 2026 03:03:05 GMT": 0,
+	}
+	for header, want := range tests {
+		if got := retryAfter(header, now); got != want {
+			t.Errorf("Error: retryAfter(%q). Got: %v, Want: %v", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+)
+
+// Progress reporting modes
+const (
+	progressAuto  = "auto"
+	progressBar   = "bar"
+	progressLines = "lines"
+	progressOff   = "off"
+)
+
+const (
+	// Width of the progress bar, in characters
+	progressBarWidth = 30
+
+	// Shortest time between redraws of the progress bar
+	progressBarInterval = 100 * time.Millisecond
+)
+
+// progress reports the progress of a run looking up many numbers to stderr, either as a progress
+// bar redrawn in place on a terminal, or as periodic status lines that are easy to log
+type progress struct {
+	w        io.Writer
+	log      *slog.Logger
+	interval time.Duration
+	now      func() time.Time
+
+	total  int
+	done   int
+	failed int
+	reused int
+	spent  float64
+
+	start   time.Time
+	last    time.Time
+	started bool
+}
+
+// newProgress returns a progress reporter for a run of total numbers, or of an unknown number of
+// them when total is 0, as selected by the --progress flag. Nil is returned when progress isn't
+// reported.
+func newProgress(c *cli.Context, total int) (*progress, error) {
+	mode := c.String("progress")
+	if mode == progressAuto {
+		mode = progressLines
+		if f, ok := c.App.ErrWriter.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+			mode = progressBar
+		}
+	}
+
+	p := &progress{w: c.App.ErrWriter, total: total, now: time.Now}
+	switch mode {
+	case progressBar:
+		p.interval = progressBarInterval
+	case progressLines:
+		p.interval = c.Duration("progress-interval")
+		if p.interval <= 0 {
+			return nil, fmt.Errorf("--progress-interval must be positive")
+		}
+		p.log = slog.New(slog.NewTextHandler(p.w, nil))
+	case progressOff:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown progress mode: %s", mode)
+	}
+	p.start = p.now()
+	p.last = p.start
+	return p, nil
+}
+
+// add counts a finished number, which failed when err is set, or was answered without a new
+// lookup, from a job's checkpoint or an earlier lookup of the same number, when reused is true
+func (p *progress) add(result *whatphone.Result, err error, reused bool) {
+	if p == nil {
+		return
+	}
+
+	switch {
+	case err != nil:
+		p.failed++
+	case reused:
+		p.reused++
+	default:
+		p.spent += result.Pricing.Total
+	}
+	p.done++
+
+	if now := p.now(); now.Sub(p.last) >= p.interval {
+		p.last = now
+		p.report(now, false)
+	}
+}
+
+// finish reports the final progress of the run. Status lines only end with a final report if
+// they were reported before, so runs quicker than the interval stay quiet.
+func (p *progress) finish() {
+	if p == nil || !p.started {
+		return
+	}
+	p.report(p.now(), true)
+}
+
+// clear erases the progress bar, so other messages can be written to stderr. It's redrawn by the
+// next report.
+func (p *progress) clear() {
+	if p == nil || p.log != nil || !p.started {
+		return
+	}
+	io.WriteString(p.w, "\r\x1b[K")
+}
+
+// rate returns the number of lookups made per second by now, not counting reused ones
+func (p *progress) rate(now time.Time) float64 {
+	elapsed := now.Sub(p.start).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(p.done-p.reused) / elapsed
+}
+
+// remaining returns the number of numbers left to look up, or -1 if the total isn't known
+func (p *progress) remaining() int {
+	if p.total == 0 {
+		return -1
+	}
+	return max(p.total-p.done, 0)
+}
+
+// eta returns the estimated time left in the run as of now, or -1 if it can't be estimated yet
+func (p *progress) eta(now time.Time) time.Duration {
+	remaining, rate := p.remaining(), p.rate(now)
+	if remaining < 0 || rate == 0 {
+		return -1
+	}
+	return time.Duration(float64(remaining) / rate * float64(time.Second)).Round(time.Second)
+}
+
+// report writes the progress as of now, ending the progress bar's line when final is true
+func (p *progress) report(now time.Time, final bool) {
+	p.started = true
+	if p.log != nil {
+		attrs := []any{
+			"completed", p.done - p.failed,
+			"failed", p.failed,
+		}
+		if remaining := p.remaining(); remaining >= 0 {
+			attrs = append(attrs, "remaining", remaining)
+		}
+		attrs = append(attrs, "rate", fmt.Sprintf("%.2f/s", p.rate(now)), "spent", fmt.Sprintf("%.4f", p.spent))
+		if eta := p.eta(now); eta >= 0 && !final {
+			attrs = append(attrs, "eta", eta)
+		}
+		msg := "progress"
+		if final {
+			msg = "finished"
+		}
+		p.log.Info(msg, attrs...)
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("\r")
+	if p.total > 0 {
+		filled := progressBarWidth * min(p.done, p.total) / p.total
+		b.WriteString("[" + strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled) + "] ")
+		fmt.Fprintf(&b, "%d/%d", p.done, p.total)
+	} else {
+		fmt.Fprintf(&b, "%d", p.done)
+	}
+	fmt.Fprintf(&b, "  %d failed  %.1f/s  spent %.4f", p.failed, p.rate(now), p.spent)
+	if eta := p.eta(now); eta >= 0 && !final {
+		fmt.Fprintf(&b, "  ETA %s", eta)
+	}
+	// clear whatever is left of a longer previous line
+	b.WriteString("\x1b[K")
+	if final {
+		b.WriteString("\n")
+	}
+	io.WriteString(p.w, b.String())
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+// testProgress returns a progress bar for total numbers, along with a function advancing its clock
+func testProgress(w io.Writer, total int) (*progress, func(d time.Duration)) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	p := &progress{w: w, total: total, interval: progressBarInterval, start: now, last: now}
+	p.now = func() time.Time { return now }
+	return p, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestProgressBar(t *testing.T) {
+	var b bytes.Buffer
+	p, advance := testProgress(&b, 4)
+	result := &whatphone.Result{Pricing: whatphone.Pricing{Total: 0.01}}
+
+	advance(2 * time.Second)
+	p.add(result, nil, false)
+	expected := "\r[=======                       ] 1/4  0 failed  0.5/s  spent 0.0100  ETA 6s\x1b[K"
+	if b.String() != expected {
+		t.Errorf("Error: Unexpected progress bar.\nExpected: %q\nGot: %q", expected, b.String())
+	}
+
+	// reports are limited to one per interval
+	b.Reset()
+	p.add(nil, errors.New("404 Not Found"), false)
+	p.add(result, nil, true)
+	if b.Len() != 0 {
+		t.Errorf("Error: progress bar redrawn within its interval: %q", b.String())
+	}
+
+	advance(2 * time.Second)
+	p.add(result, nil, false)
+	p.finish()
+	expected = "\r[==============================] 4/4  1 failed  0.8/s  spent 0.0200  ETA 0s\x1b[K" +
+		"\r[==============================] 4/4  1 failed  0.8/s  spent 0.0200\x1b[K\n"
+	if b.String() != expected {
+		t.Errorf("Error: Unexpected final progress bar.\nExpected: %q\nGot: %q", expected, b.String())
+	}
+	if p.reused != 1 || p.failed != 1 || p.done != 4 {
+		t.Errorf("Error: Unexpected counts. Got: %d done, %d failed, %d reused, Want: 4 done, 1 failed, 1 reused", p.done, p.failed, p.reused)
+	}
+}
+
+func TestProgressUnknownTotal(t *testing.T) {
+	var b bytes.Buffer
+	p, advance := testProgress(&b, 0)
+	advance(2 * time.Second)
+	p.add(&whatphone.Result{}, nil, false)
+	if expected := "\r1  0 failed  0.5/s  spent 0.0000\x1b[K"; b.String() != expected {
+		t.Errorf("Error: Unexpected progress bar.\nExpected: %q\nGot: %q", expected, b.String())
+	}
+	if eta := p.eta(p.now()); eta != -1 {
+		t.Errorf("Error: Unexpected ETA with an unknown total. Got: %v, Want: %v", eta, -1)
+	}
+}
+
+func TestProgressLines(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+	cr := newConfigReader(testServerConfig(srv))
+
+	var stderr bytes.Buffer
+	args := []string{"whatphone", "lookup", "-n", "--progress", "lines", "--progress-interval", "1ns", "15551234567", "5557654321"}
+	if err := run(args, strings.NewReader(""), io.Discard, &stderr, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("%v wrote unexpected status lines:\n%s", args, stderr.String())
+	}
+	for _, expected := range []string{"msg=progress completed=1 failed=0 remaining=1 rate=", " spent=-0.0100 eta="} {
+		if !strings.Contains(lines[0], expected) {
+			t.Errorf("%v wrote an unexpected status line.\nExpected to contain: %s\nGot: %s", args, expected, lines[0])
+		}
+	}
+	if expected := "msg=finished completed=2 failed=0 remaining=0 rate="; !strings.Contains(lines[2], expected) {
+		t.Errorf("%v wrote an unexpected final status line.\nExpected to contain: %s\nGot: %s", args, expected, lines[2])
+	}
+
+	// runs quicker than the interval stay quiet
+	stderr.Reset()
+	args = []string{"whatphone", "lookup", "-n", "--progress", "lines", "15551234567", "5557654321"}
+	if err := run(args, strings.NewReader(""), io.Discard, &stderr, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("%v wrote unexpected status lines:\n%s", args, stderr.String())
+	}
+
+	args = []string{"whatphone", "lookup", "-n", "--progress", "dots", "15551234567", "5557654321"}
+	err := run(args, strings.NewReader(""), io.Discard, io.Discard, cr)
+	if expected := "unknown progress mode: dots"; err == nil || err.Error() != expected {
+		t.Errorf("%v returned unexpected error.\nExpected: %v\nGot: %v\n", args, expected, err)
+	}
+}
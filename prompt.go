@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// prompter reads answers to questions, either interactively from a terminal or line by line
+// from piped input
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	// fd is the terminal's file descriptor, used to read secrets without echoing them
+	fd          int
+	interactive bool
+}
+
+// newPrompter returns a prompter reading from in, which is treated as interactive when it is a terminal
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	p := &prompter{
+		in:  bufio.NewReader(in),
+		out: out,
+		fd:  -1,
+	}
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		p.fd = int(f.Fd())
+		p.interactive = true
+	}
+	return p
+}
+
+// ask prints the label when running interactively and returns the next line of input. An empty
+// string is returned once the input is exhausted.
+func (p *prompter) ask(label string) (string, error) {
+	if p.interactive {
+		fmt.Fprintf(p.out, "%s: ", label)
+	}
+
+	line, err := p.in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	return strings.TrimSpace(line), nil
+}
+
+// askSecret works like ask, but doesn't echo the answer when running interactively
+func (p *prompter) askSecret(label string) (string, error) {
+	if !p.interactive {
+		return p.ask(label)
+	}
+
+	fmt.Fprintf(p.out, "%s: ", label)
+	secret, err := term.ReadPassword(p.fd)
+	fmt.Fprintln(p.out)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(secret)), nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// a Friday
+	from := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"@hourly", time.Date(2026, 1, 2, 4, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 6h", time.Date(2026, 1, 2, 9, 4, 5, 0, time.UTC)},
+		{"* * * * *", time.Date(2026, 1, 2, 3, 5, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 1, 2, 3, 15, 0, 0, time.UTC)},
+		{"30 9 * * mon-fri", time.Date(2026, 1, 2, 9, 30, 0, 0, time.UTC)},
+		{"30 9 * * sat,sun", time.Date(2026, 1, 3, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 6 15 * *", time.Date(2026, 1, 15, 6, 0, 0, 0, time.UTC)},
+		{"0 6 15 * mon", time.Date(2026, 1, 5, 6, 0, 0, 0, time.UTC)},
+		{"0 0 1 mar *", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12-18/3 * * *", time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2026, 1, 2, 3, 5, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 feb *", time.Time{}},
+	}
+
+	for _, test := range tests {
+		s, err := parseSchedule(test.spec)
+		if err != nil {
+			t.Errorf("Error: parseSchedule(%q) returned error: %v", test.spec, err)
+			continue
+		}
+		if got := s.next(from); !got.Equal(test.expected) {
+			t.Errorf("Error: Unexpected next run of %q. Got: %v, Want: %v", test.spec, got, test.expected)
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"* * * foo *",
+		"@every 30s",
+		"@every soon",
+		"@fortnightly",
+	} {
+		if _, err := parseSchedule(spec); err == nil {
+			t.Errorf("Error: parseSchedule(%q) should have returned an error", spec)
+		}
+	}
+}
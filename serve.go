@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+)
+
+// apiKey holds the API key and quota of a gateway client
+type apiKey struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+
+	// Quota is the number of lookups allowed per quota period, or 0 for no limit
+	Quota int `json:"quota"`
+}
+
+// gatewayClient tracks a client's usage of its quota
+type gatewayClient struct {
+	apiKey
+
+	mu    sync.Mutex
+	used  int
+	reset time.Time
+}
+
+// take uses one lookup from the client's quota, returning false and the time the quota resets if
+// the quota has been used up
+func (c *gatewayClient) take(period time.Duration) (bool, time.Time) {
+	if c.Quota <= 0 {
+		return true, time.Time{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.After(c.reset) {
+		c.used = 0
+		c.reset = now.Add(period)
+	}
+	if c.used >= c.Quota {
+		return false, c.reset
+	}
+
+	c.used++
+	return true, c.reset
+}
+
+// refund gives back a lookup taken from the client's quota, for lookups that failed
+func (c *gatewayClient) refund() {
+	if c.Quota <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.used > 0 {
+		c.used--
+	}
+}
+
+// gateway serves phone number lookups over HTTP, so clients can perform lookups without having
+// the EveryoneAPI credentials
+type gateway struct {
+	api         *whatphone.API
+	data        []string
+	clients     map[string]*gatewayClient
+	quotaPeriod time.Duration
+	ready       atomic.Bool
+
+	// metrics, when set, is served at /metrics
+	metrics http.Handler
+}
+
+// newGateway returns a gateway performing lookups with api for the clients holding keys. Lookups
+// that don't select any data points request the default data points.
+func newGateway(api *whatphone.API, data []string, keys []apiKey, quotaPeriod time.Duration) *gateway {
+	g := &gateway{
+		api:         api,
+		data:        data,
+		clients:     make(map[string]*gatewayClient),
+		quotaPeriod: quotaPeriod,
+	}
+	for _, k := range keys {
+		g.clients[k.Key] = &gatewayClient{apiKey: k}
+	}
+	g.ready.Store(true)
+	return g
+}
+
+// handler returns the gateway's HTTP handler
+func (g *gateway) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/lookup/{number}", g.handleLookup)
+	mux.HandleFunc("GET /healthz", g.handleHealth)
+	mux.HandleFunc("GET /readyz", g.handleReady)
+	if g.metrics != nil {
+		mux.Handle("GET /metrics", g.metrics)
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not_found", "no such endpoint")
+	})
+	return mux
+}
+
+// handleLookup answers GET /v1/lookup/{number}?data=name,carrier
+func (g *gateway) handleLookup(w http.ResponseWriter, r *http.Request) {
+	client, ok := g.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="whatphone"`)
+		writeError(w, http.StatusUnauthorized, "unauthorized", "missing or invalid API key")
+		return
+	}
+
+	number, err := whatphone.Normalize(r.PathValue("number"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_number", err.Error())
+		return
+	}
+
+	var opts []whatphone.Option
+	switch data := r.URL.Query().Get("data"); data {
+	case "all":
+	case "":
+		if len(g.data) == 0 {
+			writeError(w, http.StatusBadRequest, "no_data_points", "no data points selected; use data=all to request all data points")
+			return
+		}
+		opts = dataPointOptions(g.data)
+	default:
+		names, err := parseDataPoints(data)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_data_point", err.Error())
+			return
+		}
+		opts = dataPointOptions(names)
+	}
+
+	if ok, reset := client.take(g.quotaPeriod); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(reset).Seconds())+1))
+		writeError(w, http.StatusTooManyRequests, "quota_exceeded", fmt.Sprintf("quota of %d lookups exceeded", client.Quota))
+		return
+	}
+
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	result, err := g.api.LookupContext(ctx, number, opts...)
+	if err != nil {
+		// only successful lookups count against the quota
+		client.refund()
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		failureLogger(g.api).Error("lookup failed", "client", client.Name, "number", g.api.Redaction.Number(number), "error", err)
+		writeError(w, http.StatusBadGateway, "lookup_failed", "the lookup failed; try again later")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// failureLogger returns the API's logger, or the default logger if it has none, so servers log
+// failed lookups even without --verbose
+func failureLogger(api *whatphone.API) *slog.Logger {
+	if api.Logger != nil {
+		return api.Logger
+	}
+	return slog.Default()
+}
+
+// handleHealth reports that the gateway is running
+func (g *gateway) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReady reports whether the gateway is accepting lookups
+func (g *gateway) handleReady(w http.ResponseWriter, r *http.Request) {
+	if !g.ready.Load() {
+		writeError(w, http.StatusServiceUnavailable, "shutting_down", "server is shutting down")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// authenticate returns the client owning the bearer token sent with a request
+func (g *gateway) authenticate(r *http.Request) (*gatewayClient, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, false
+	}
+	client, ok := g.clients[token]
+	return client, ok
+}
+
+// apiError is the body of every gateway error response
+type apiError struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// writeError writes a structured JSON error response
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	var e apiError
+	e.Error.Code = code
+	e.Error.Message = message
+	writeJSON(w, status, e)
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// loadKeys reads a JSON list of gateway clients' API keys and quotas
+func loadKeys(r io.Reader) ([]apiKey, error) {
+	var keys []apiKey
+	if err := json.NewDecoder(r).Decode(&keys); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		if k.Key == "" {
+			return nil, fmt.Errorf("API key for %q is empty", k.Name)
+		}
+		if seen[k.Key] {
+			return nil, fmt.Errorf("API key for %q is used more than once", k.Name)
+		}
+		seen[k.Key] = true
+	}
+
+	return keys, nil
+}
+
+func cmdServe(c *cli.Context) error {
+	config, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(c.String("keys"))
+	if err != nil {
+		return err
+	}
+	keys, err := loadKeys(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("unable to read keys: %v", err)
+	}
+
+	if c.Int("cache-size") >= 0 {
+		config.Cache = whatphone.NewMemoryCache(c.Duration("cache-ttl"), c.Int("cache-size"))
+	}
+
+	g := newGateway(&config.API, config.Data, keys, c.Duration("quota-period"))
+	g.metrics = instrument(&config.API)
+	srv := &http.Server{
+		Addr:              c.String("listen"),
+		Handler:           g.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+	fmt.Fprintf(c.App.Writer, "Listening on %s\n", srv.Addr)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	g.ready.Store(false)
+	fmt.Fprintf(c.App.Writer, "Shutting down\n")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), c.Duration("shutdown-timeout"))
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
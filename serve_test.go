@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func TestGateway(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	api := srv.API()
+	api.Cache = whatphone.NewMemoryCache(time.Minute, 0)
+	keys := []apiKey{
+		{Name: "billing", Key: "billing-key", Quota: 2},
+		{Name: "crm", Key: "crm-key"},
+	}
+	g := newGateway(api, []string{"carrier"}, keys, time.Hour)
+	g.metrics = instrument(api)
+	gw := httptest.NewServer(g.handler())
+	defer gw.Close()
+
+	requests := []struct {
+		path   string
+		key    string
+		status int
+		code   string
+	}{
+		{"/v1/lookup/+15551234567?data=name", "", http.StatusUnauthorized, "unauthorized"},
+		{"/v1/lookup/+15551234567?data=name", "wrong-key", http.StatusUnauthorized, "unauthorized"},
+		{"/v1/lookup/+15551234567?data=shoe_size", "crm-key", http.StatusBadRequest, "invalid_data_point"},
+		{"/v1/lookup/+15551234567?data=name", "billing-key", http.StatusOK, ""},
+		{"/v1/lookup/+15551234567?data=name", "billing-key", http.StatusOK, ""},
+		{"/v1/lookup/+15551234567?data=name", "billing-key", http.StatusTooManyRequests, "quota_exceeded"},
+		{"/v1/lookup/+15551234567?data=name", "crm-key", http.StatusOK, ""},
+		{"/v1/lookup/+15551234567", "crm-key", http.StatusOK, ""},
+		{"/v1/lookup/123", "crm-key", http.StatusBadRequest, "invalid_number"},
+		{"/v1/lookup/%2E%2E%2Faccount%3Fx=", "billing-key", http.StatusBadRequest, "invalid_number"},
+		{"/v1/lookup/15551234567%3Fdata=image", "billing-key", http.StatusBadRequest, "invalid_number"},
+		{"/v1/nothing", "crm-key", http.StatusNotFound, "not_found"},
+	}
+
+	for _, r := range requests {
+		req, _ := http.NewRequest(http.MethodGet, gw.URL+r.path, nil)
+		if r.key != "" {
+			req.Header.Set("Authorization", "Bearer "+r.key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s returned error: %v", r.path, err)
+		}
+
+		if resp.StatusCode != r.status {
+			t.Errorf("%s with key %q returned unexpected status. Got: %d, Want: %d", r.path, r.key, resp.StatusCode, r.status)
+		}
+		if r.code != "" {
+			var e apiError
+			json.NewDecoder(resp.Body).Decode(&e)
+			if e.Error.Code != r.code {
+				t.Errorf("%s with key %q returned unexpected error code. Got: %q, Want: %q", r.path, r.key, e.Error.Code, r.code)
+			}
+		}
+		resp.Body.Close()
+	}
+
+	// every name lookup after the first was answered from the shared cache, the lookup
+	// without a data parameter used the default data points, and invalid numbers never
+	// reached EveryoneAPI
+	if srv.Requests() != 2 {
+		t.Errorf("Unexpected number of EveryoneAPI requests. Got: %d, Want: 2", srv.Requests())
+	}
+
+	resp, err := http.Get(gw.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("/metrics returned error: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), `whatphone_cache_lookups_total{result="hit"} 2`) {
+		t.Errorf("/metrics is missing the cache hits:\n%s", body)
+	}
+}
+
+func TestGatewayLookupFailure(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	api := srv.API()
+	api.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	g := newGateway(api, []string{"carrier"}, []apiKey{{Name: "billing", Key: "billing-key", Quota: 1}}, time.Hour)
+	gw := httptest.NewServer(g.handler())
+	defer gw.Close()
+
+	lookup := func() (int, apiError) {
+		req, _ := http.NewRequest(http.MethodGet, gw.URL+"/v1/lookup/+15551234567", nil)
+		req.Header.Set("Authorization", "Bearer billing-key")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("lookup returned error: %v", err)
+		}
+		defer resp.Body.Close()
+		var e apiError
+		json.NewDecoder(resp.Body).Decode(&e)
+		return resp.StatusCode, e
+	}
+
+	srv.FailNext(1, http.StatusServiceUnavailable)
+	status, e := lookup()
+	if status != http.StatusBadGateway || e.Error.Code != "lookup_failed" {
+		t.Errorf("Unexpected response to a failed lookup. Got: %d %q, Want: %d %q", status, e.Error.Code, http.StatusBadGateway, "lookup_failed")
+	}
+	if strings.Contains(e.Error.Message, "503") {
+		t.Errorf("Upstream error leaked to the client: %q", e.Error.Message)
+	}
+
+	// the failed lookup didn't use up the quota
+	if status, _ := lookup(); status != http.StatusOK {
+		t.Errorf("Unexpected status after a failed lookup. Got: %d, Want: %d", status, http.StatusOK)
+	}
+	if status, _ := lookup(); status != http.StatusTooManyRequests {
+		t.Errorf("Unexpected status once the quota is used. Got: %d, Want: %d", status, http.StatusTooManyRequests)
+	}
+}
+
+func TestGatewayHealth(t *testing.T) {
+	g := newGateway(whatphone.New("test", "test"), nil, nil, time.Hour)
+	gw := httptest.NewServer(g.handler())
+	defer gw.Close()
+
+	checks := []struct {
+		path   string
+		ready  bool
+		status int
+	}{
+		{"/healthz", true, http.StatusOK},
+		{"/readyz", true, http.StatusOK},
+		{"/healthz", false, http.StatusOK},
+		{"/readyz", false, http.StatusServiceUnavailable},
+	}
+
+	for _, c := range checks {
+		g.ready.Store(c.ready)
+		resp, err := http.Get(gw.URL + c.path)
+		if err != nil {
+			t.Fatalf("%s returned error: %v", c.path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Errorf("%s (ready: %v) returned unexpected status. Got: %d, Want: %d", c.path, c.ready, resp.StatusCode, c.status)
+		}
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	keys, err := loadKeys(strings.NewReader(`[{"name": "crm", "key": "abc", "quota": 10}]`))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != (apiKey{Name: "crm", Key: "abc", Quota: 10}) {
+		t.Errorf("Unexpected keys. Got: %+v", keys)
+	}
+
+	if _, err := loadKeys(strings.NewReader(`[{"name": "a", "key": "abc"}, {"name": "b", "key": "abc"}]`)); err == nil {
+		t.Errorf("duplicate keys should have returned an error but didn't")
+	}
+	if _, err := loadKeys(strings.NewReader(`[{"name": "a"}]`)); err == nil {
+		t.Errorf("empty key should have returned an error but didn't")
+	}
+}
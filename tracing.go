@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+)
+
+// setTracer makes the api export a span for every lookup over OTLP when requested by the global
+// flags. The exporter is flushed and shut down by shutdownTracer once the app is done.
+func setTracer(c *cli.Context, api *whatphone.API) error {
+	endpoint := c.String("otlp-endpoint")
+	if endpoint == "" {
+		return nil
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+	if c.Bool("otlp-insecure") {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(context.Background(), opts...)
+	if err != nil {
+		return err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", "whatphone"),
+			attribute.String("service.version", version),
+		)),
+	)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	api.TracerProvider = tp
+	c.App.Metadata["tracerProvider"] = tp
+	return nil
+}
+
+// shutdownTracer flushes any spans that haven't been exported yet
+func shutdownTracer(c *cli.Context) error {
+	tp, ok := c.App.Metadata["tracerProvider"].(*sdktrace.TracerProvider)
+	if !ok {
+		return nil
+	}
+	return tp.Shutdown(context.Background())
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+// testCollector is an OTLP trace collector that keeps the names of the spans it receives
+type testCollector struct {
+	coltracepb.UnimplementedTraceServiceServer
+
+	mu    sync.Mutex
+	spans []string
+}
+
+func (tc *testCollector) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	for _, rs := range req.GetResourceSpans() {
+		for _, ss := range rs.GetScopeSpans() {
+			for _, span := range ss.GetSpans() {
+				tc.spans = append(tc.spans, span.GetName())
+			}
+		}
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func TestTracing(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	collector := &testCollector{}
+	gs := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(gs, collector)
+	go gs.Serve(l)
+	defer gs.Stop()
+
+	var stdout bytes.Buffer
+	args := []string{"whatphone", "--otlp-endpoint", l.Addr().String(), "--otlp-insecure", "lookup", "-n", "+15551234567"}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, newConfigReader(testServerConfig(srv))); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+
+	// spans are flushed when the app exits
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if len(collector.spans) != 1 || collector.spans[0] != "whatphone.Lookup" {
+		t.Errorf("%v exported unexpected spans: %v", args, collector.spans)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+)
+
+// Longest a vCard content line may be before it's folded, in octets
+const vcardLineLength = 75
+
+// vcard returns a lookup result as a vCard 4.0 (RFC 6350) contact
+func vcard(result *whatphone.Result) string {
+	var b strings.Builder
+	line := func(name, value string) {
+		b.WriteString(foldVCard(name + ":" + value))
+		b.WriteString("\r\n")
+	}
+
+	data := &result.Data
+	line("BEGIN", "VCARD")
+	line("VERSION", "4.0")
+
+	fn := data.GetName()
+	if fn == "" {
+		fn = strings.TrimSpace(data.FirstName() + " " + data.LastName())
+	}
+	if fn == "" {
+		fn = data.GetCNAM()
+	}
+	if fn == "" {
+		fn = result.Number
+	}
+	line("FN", escapeVCard(fn))
+	if data.ExpandedName != nil {
+		line("N", vcardComponents(data.LastName(), data.FirstName(), "", "", ""))
+	}
+
+	telType := "voice"
+	switch data.LineType() {
+	case whatphone.LineTypeMobile:
+		telType = "cell"
+	case whatphone.LineTypePager:
+		telType = "pager"
+	}
+	line("TEL;VALUE=uri;TYPE="+telType, "tel:"+result.Number)
+
+	if data.Address != nil || data.Location != nil {
+		line("ADR", vcardComponents("", "", data.GetAddress(), data.City(), data.State(), data.Zip(), ""))
+	}
+	if lat, lon, ok := data.Coordinates(); ok {
+		line("GEO", fmt.Sprintf("geo:%g,%g", lat, lon))
+	}
+	if data.Profile != nil && data.Profile.Job != "" {
+		line("TITLE", escapeVCard(data.Profile.Job))
+	}
+	switch data.GetGender() {
+	case whatphone.GenderMale:
+		line("GENDER", "M")
+	case whatphone.GenderFemale:
+		line("GENDER", "F")
+	}
+	if data.Image != nil {
+		for _, url := range []string{data.Image.Large, data.Image.Med, data.Image.Small} {
+			if url != "" {
+				// EveryoneAPI returns protocol relative URLs, but vCard needs absolute ones
+				if strings.HasPrefix(url, "//") {
+					url = "https:" + url
+				}
+				line("PHOTO", url)
+				break
+			}
+		}
+	}
+
+	line("END", "VCARD")
+	return b.String()
+}
+
+// writeVCards writes lookup results to w as a single file of vCards
+func writeVCards(w io.Writer, outs []lookupOutput) error {
+	for _, out := range outs {
+		if _, err := io.WriteString(w, vcard(out.Result)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeVCardFiles writes each lookup result to its own .vcf file in dir, named after its number
+func writeVCardFiles(w io.Writer, dir string, outs []lookupOutput) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, out := range outs {
+		file := filepath.Join(dir, vcardFileName(out.Number))
+		if err := os.WriteFile(file, []byte(vcard(out.Result)), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Wrote %s\n", file)
+	}
+	return nil
+}
+
+// vcardFileName returns the name of the .vcf file for a phone number, keeping only the characters
+// that make up an E.164 number
+func vcardFileName(number string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '+' || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, number)
+	if name == "" {
+		name = "unknown"
+	}
+	return name + ".vcf"
+}
+
+// escapeVCard escapes a text value for use in a vCard
+func escapeVCard(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// vcardComponents escapes and joins the components of a structured vCard value, such as N or ADR
+func vcardComponents(components ...string) string {
+	for i, c := range components {
+		components[i] = escapeVCard(c)
+	}
+	return strings.Join(components, ";")
+}
+
+// foldVCard folds a content line longer than vcardLineLength octets onto continuation lines, each
+// starting with a space, without splitting any UTF-8 characters
+func foldVCard(line string) string {
+	if len(line) <= vcardLineLength {
+		return line
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		n := len(string(r))
+		if width+n > vcardLineLength {
+			b.WriteString("\r\n ")
+			// the leading space counts towards the continuation line's length
+			width = 1
+		}
+		b.WriteRune(r)
+		width += n
+	}
+	return b.String()
+}
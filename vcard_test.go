@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+)
+
+func TestVCard(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+
+	var stdout bytes.Buffer
+	args := []string{"whatphone", "lookup", "-f", "vcard", "--all", "15551234567"}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, newConfigReader(testServerConfig(srv))); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+
+	expected := strings.ReplaceAll(`BEGIN:VCARD
+VERSION:4.0
+FN:Michael Seaver
+N:Seaver;Michael;;;
+TEL;VALUE=uri;TYPE=cell:tel:+15551234567
+ADR:;;15 Robin Hood Lane;Long Island;NY;10003;
+GEO:geo:40.799787,-73.971421
+TITLE:Custodian
+GENDER:M
+PHOTO:https://teloimg-pub.com.s3.amazonaws.com/large.jpg
+END:VCARD
+`, "\n", "\r\n")
+	if stdout.String() != expected {
+		t.Errorf("%v returned unexpected output.\nExpected: %q\nGot: %q\n", args, expected, stdout.String())
+	}
+}
+
+func TestVCardMinimal(t *testing.T) {
+	tests := []struct {
+		result   whatphone.Result
+		expected string
+	}{
+		{
+			whatphone.Result{Number: "+15557654321"},
+			"BEGIN:VCARD\r\nVERSION:4.0\r\nFN:+15557654321\r\nTEL;VALUE=uri;TYPE=voice:tel:+15557654321\r\nEND:VCARD\r\n",
+		},
+		{
+			whatphone.Result{Number: "+15557654321", Data: whatphone.Data{Cnam: str("DOE, JANE; ESQ")}},
+			"BEGIN:VCARD\r\nVERSION:4.0\r\nFN:DOE\\, JANE\\; ESQ\r\nTEL;VALUE=uri;TYPE=voice:tel:+15557654321\r\nEND:VCARD\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		if got := vcard(&test.result); got != test.expected {
+			t.Errorf("Error: Unexpected vCard.\nExpected: %q\nGot: %q", test.expected, got)
+		}
+	}
+}
+
+func str(s string) *string {
+	return &s
+}
+
+func TestFoldVCard(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{"FN:short", "FN:short"},
+		{strings.Repeat("a", 75), strings.Repeat("a", 75)},
+		{strings.Repeat("a", 80), strings.Repeat("a", 75) + "\r\n " + strings.Repeat("a", 5)},
+		{strings.Repeat("a", 74) + "é", strings.Repeat("a", 74) + "\r\n é"},
+	}
+
+	for _, test := range tests {
+		got := foldVCard(test.line)
+		if got != test.expected {
+			t.Errorf("Error: Unexpected fold of %q.\nExpected: %q\nGot: %q", test.line, test.expected, got)
+		}
+		for _, l := range strings.Split(got, "\r\n") {
+			if len(l) > vcardLineLength {
+				t.Errorf("Error: folded line is %d octets long: %q", len(l), l)
+			}
+		}
+	}
+}
+
+func TestVCardFiles(t *testing.T) {
+	name := "Jane Doe"
+	srv := apitest.NewServer(apitest.WithNumber("+15557654321", whatphone.Data{Name: &name}))
+	defer srv.Close()
+	cr := newConfigReader(testServerConfig(srv))
+
+	dir := filepath.Join(t.TempDir(), "contacts")
+	var stdout bytes.Buffer
+	args := []string{"whatphone", "lookup", "-n", "--vcard-dir", dir, "15551234567", "5557654321"}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+
+	for _, number := range []string{"+15551234567", "+15557654321"} {
+		b, err := os.ReadFile(filepath.Join(dir, number+".vcf"))
+		if err != nil {
+			t.Errorf("%v didn't write a vCard for %s: %v", args, number, err)
+			continue
+		}
+		if !strings.Contains(string(b), "TEL;VALUE=uri;TYPE=voice:tel:"+number+"\r\n") {
+			t.Errorf("%v wrote an unexpected vCard for %s:\n%s", args, number, b)
+		}
+	}
+	if !strings.Contains(stdout.String(), "Wrote "+filepath.Join(dir, "+15557654321.vcf")) {
+		t.Errorf("%v didn't report the files it wrote:\n%s", args, stdout.String())
+	}
+
+	args = []string{"whatphone", "lookup", "-n", "-f", "json", "--vcard-dir", dir, "15551234567"}
+	err := run(args, strings.NewReader(""), io.Discard, io.Discard, cr)
+	if expected := errors.New("--vcard-dir can only be used with --format vcard"); err == nil || err.Error() != expected.Error() {
+		t.Errorf("%v returned unexpected error.\nExpected: %v\nGot: %v\n", args, expected, err)
+	}
+}
+
+func TestVCardFileName(t *testing.T) {
+	tests := map[string]string{
+		"+15551234567":   "+15551234567.vcf",
+		"../555-1234":    "5551234.vcf",
+		"":               "unknown.vcf",
+		"(555) 123-4567": "5551234567.vcf",
+	}
+	for number, expected := range tests {
+		if got := vcardFileName(number); got != expected {
+			t.Errorf("Error: vcardFileName(%q). Got: %s, Want: %s", number, got, expected)
+		}
+	}
+}
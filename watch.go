@@ -0,0 +1,392 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/webhook"
+)
+
+// watchDataPoints are the data points watched numbers are looked up with
+var watchDataPoints = whatphone.NewDataPointSet(
+	whatphone.DataPointCarrier,
+	whatphone.DataPointLineType,
+	whatphone.DataPointLineProvider,
+)
+
+// watchFields returns the watched fields of a result, leaving out the ones that were missed
+func watchFields(result *whatphone.Result) map[string]string {
+	fields := make(map[string]string)
+	if result.Data.Carrier != nil {
+		fields[whatphone.DataPointCarrier.String()] = result.Data.CarrierName()
+	}
+	if result.Data.Linetype != nil {
+		fields[whatphone.DataPointLineType.String()] = *result.Data.Linetype
+	}
+	if result.Data.LineProvider != nil {
+		fields[whatphone.DataPointLineProvider.String()] = result.Data.LineProviderName()
+	}
+	return fields
+}
+
+// watchState is what a watch remembers between cycles, and runs. Numbers are keyed by their
+// Redaction.NumberKey, so they're hashed when numbers are masked.
+type watchState struct {
+	Numbers map[string]*watchEntry
+}
+
+// watchEntry holds the last check of a watched number
+type watchEntry struct {
+	Checked time.Time
+	Fields  map[string]string
+}
+
+// watchChange is a watched field whose value changed
+type watchChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// watchAlert reports the changes found by a check of a watched number
+type watchAlert struct {
+	Number  string        `json:"number"`
+	Time    time.Time     `json:"time"`
+	Changes []watchChange `json:"changes"`
+}
+
+// String returns an alert as a single line of text
+func (a watchAlert) String() string {
+	changes := make([]string, len(a.Changes))
+	for i, ch := range a.Changes {
+		changes[i] = fmt.Sprintf("%s %q -> %q", ch.Field, ch.Old, ch.New)
+	}
+	return fmt.Sprintf("%s %s changed: %s", a.Time.Format(time.RFC3339), a.Number, strings.Join(changes, ", "))
+}
+
+// compare returns the changes between the previous and current fields of a number. Fields that
+// weren't returned this time aren't compared, since a miss doesn't mean the value went away.
+func compare(previous, current map[string]string) []watchChange {
+	var changes []watchChange
+	for _, field := range sortedKeys(current) {
+		if old, ok := previous[field]; ok && old != current[field] {
+			changes = append(changes, watchChange{Field: field, Old: old, New: current[field]})
+		}
+	}
+	return changes
+}
+
+// watcher checks the numbers of a watchlist for changes
+type watcher struct {
+	api       *whatphone.API
+	list      string
+	statePath string
+	state     *watchState
+	budget    float64
+	format    string
+	alertFile string
+	hook      *webhook.Sink
+	now       func() time.Time
+	stdout    io.Writer
+	stderr    io.Writer
+}
+
+// watchStatePath returns where the state of a watchlist is kept unless --state is given
+func watchStatePath(list string) (string, error) {
+	abs, err := filepath.Abs(list)
+	if err != nil {
+		return "", err
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(configDir, "whatphone", "watch")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])[:12]+".json"), nil
+}
+
+// loadWatchState reads the state of a watch, which is empty when it hasn't run before
+func loadWatchState(path string) (*watchState, error) {
+	state := &watchState{Numbers: make(map[string]*watchEntry)}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("invalid watch state %s: %v", path, err)
+	}
+	if state.Numbers == nil {
+		state.Numbers = make(map[string]*watchEntry)
+	}
+	return state, nil
+}
+
+// save writes the state of a watch, replacing the previous state in one go so an interrupted
+// write can't lose it
+func (s *watchState) save(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// numbers reads the watchlist, returning its distinct valid numbers with the least recently
+// checked first, and warning about the invalid ones
+func (w *watcher) numbers() ([]string, error) {
+	f, err := os.Open(w.list)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines, err := scanNumbers(f)
+	if err != nil {
+		return nil, err
+	}
+	normalized, errs, _ := dedupe(lines)
+
+	var numbers []string
+	seen := make(map[string]bool)
+	for i, number := range normalized {
+		if errs[i] != nil {
+			fmt.Fprintf(w.stderr, "warning: %s: %v\n", w.api.Redaction.Number(lines[i]), errs[i])
+			continue
+		}
+		if !seen[number] {
+			seen[number] = true
+			numbers = append(numbers, number)
+		}
+	}
+
+	sort.SliceStable(numbers, func(i, j int) bool {
+		return w.checked(numbers[i]).Before(w.checked(numbers[j]))
+	})
+	return numbers, nil
+}
+
+// checked returns when a number was last checked, or the zero time if it never was
+func (w *watcher) checked(number string) time.Time {
+	if entry, ok := w.state.Numbers[w.api.Redaction.NumberKey(number)]; ok {
+		return entry.Checked
+	}
+	return time.Time{}
+}
+
+// cycle checks every number of the watchlist once, or as many as the budget allows, alerting on
+// the ones that changed since they were last checked
+func (w *watcher) cycle(ctx context.Context) error {
+	numbers, err := w.numbers()
+	if err != nil {
+		return err
+	}
+
+	var checked, changed int
+	var spent float64
+	estimate := watchDataPoints.Price()
+	for i, number := range numbers {
+		if ctx.Err() != nil {
+			break
+		}
+		if w.budget > 0 && spent+estimate > w.budget {
+			fmt.Fprintf(w.stderr, "Budget of %.4f reached; %d numbers weren't checked this cycle\n", w.budget, len(numbers)-i)
+			break
+		}
+
+		result, err := w.api.LookupContext(ctx, number, whatphone.WithDataPoints(watchDataPoints.DataPoints()...))
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			fmt.Fprintf(w.stderr, "warning: %s: %v\n", w.api.Redaction.Number(number), err)
+			continue
+		}
+		checked++
+		spent += result.Pricing.Total
+
+		now := w.now()
+		current := watchFields(result)
+		key := w.api.Redaction.NumberKey(number)
+		entry, ok := w.state.Numbers[key]
+		if !ok {
+			// the first check of a number is the baseline later checks are compared against
+			entry = &watchEntry{Fields: make(map[string]string)}
+			w.state.Numbers[key] = entry
+		}
+		if changes := compare(entry.Fields, current); ok && len(changes) > 0 {
+			changed++
+			if err := w.alert(ctx, watchAlert{Number: w.api.Redaction.Number(number), Time: now.UTC(), Changes: changes}); err != nil {
+				fmt.Fprintf(w.stderr, "warning: %v\n", err)
+			}
+		}
+		for field, value := range current {
+			entry.Fields[field] = value
+		}
+		entry.Checked = now.UTC()
+	}
+
+	// forget numbers taken off the watchlist
+	listed := make(map[string]bool, len(numbers))
+	for _, number := range numbers {
+		listed[w.api.Redaction.NumberKey(number)] = true
+	}
+	for key := range w.state.Numbers {
+		if !listed[key] {
+			delete(w.state.Numbers, key)
+		}
+	}
+	if err := w.state.save(w.statePath); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w.stderr, "Checked %d of %d numbers, %d changed, costing %.4f\n", checked, len(numbers), changed, spent)
+	return nil
+}
+
+// alert reports changes to stdout, and to the alert file and webhook when they're set
+func (w *watcher) alert(ctx context.Context, a watchAlert) error {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	if w.format == formatJSON {
+		fmt.Fprintf(w.stdout, "%s\n", b)
+	} else {
+		fmt.Fprintln(w.stdout, a)
+	}
+
+	if w.alertFile != "" {
+		f, err := os.OpenFile(w.alertFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(f, "%s\n", b)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if w.hook != nil {
+		if err := w.hook.Send(ctx, webhook.EventChange, a); err != nil {
+			return fmt.Errorf("webhook: %v", err)
+		}
+	}
+	return nil
+}
+
+func cmdWatch(c *cli.Context) error {
+	config, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() < 1 {
+		return fmt.Errorf("missing watchlist file")
+	}
+	list := c.Args().Get(0)
+
+	sched, err := parseSchedule(c.String("schedule"))
+	if err != nil {
+		return err
+	}
+	format := c.String("format")
+	if format != formatText && format != formatJSON {
+		return fmt.Errorf("invalid format: %s (must be text or json)", format)
+	}
+	if c.Float64("budget") < 0 {
+		return fmt.Errorf("invalid budget: %v", c.Float64("budget"))
+	}
+
+	statePath := c.String("state")
+	if statePath == "" {
+		if statePath, err = watchStatePath(list); err != nil {
+			return err
+		}
+	}
+	state, err := loadWatchState(statePath)
+	if err != nil {
+		return err
+	}
+
+	hook, err := newWebhook(c, config)
+	if err != nil {
+		return err
+	}
+
+	w := &watcher{
+		api:       &config.API,
+		list:      list,
+		statePath: statePath,
+		state:     state,
+		budget:    c.Float64("budget"),
+		format:    format,
+		alertFile: c.String("alert-file"),
+		hook:      hook,
+		now:       time.Now,
+		stdout:    c.App.Writer,
+		stderr:    c.App.ErrWriter,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for {
+		if err := w.cycle(ctx); err != nil {
+			return err
+		}
+		if c.Bool("once") || ctx.Err() != nil {
+			return nil
+		}
+
+		next := sched.next(w.now())
+		if next.IsZero() {
+			return fmt.Errorf("schedule %q never runs again", c.String("schedule"))
+		}
+		fmt.Fprintf(w.stderr, "Next check at %s\n", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/api/apitest"
+	"samhofi.us/x/whatphone/pkg/webhook"
+)
+
+// watchData returns the data of a watched number
+func watchData(carrier, linetype string) whatphone.Data {
+	return whatphone.Data{
+		Carrier:  &whatphone.Carrier{ID: "1", Name: carrier},
+		Linetype: &linetype,
+	}
+}
+
+// writeWatchlist writes a watchlist holding numbers to a temporary file, returning its path
+func writeWatchlist(t *testing.T, numbers ...string) string {
+	t.Helper()
+	list := filepath.Join(t.TempDir(), "watchlist.txt")
+	if err := os.WriteFile(list, []byte("# watched\n"+strings.Join(numbers, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func TestWatch(t *testing.T) {
+	srv := apitest.NewServer(
+		apitest.WithNumber("+15557654321", watchData("Growing Wireless Inc.", "mobile")),
+		apitest.WithNumber("+15557654322", watchData("Paine Mobile Inc.", "landline")),
+	)
+	defer srv.Close()
+	receiver, deliveries := webhookReceiver(http.StatusOK)
+	defer receiver.Close()
+	cr := newConfigReader(testServerConfig(srv))
+
+	dir := t.TempDir()
+	list := writeWatchlist(t, "555-765-4321", "5557654322", "(555) 765-4321", "123")
+	state := filepath.Join(dir, "state.json")
+	alerts := filepath.Join(dir, "alerts.jsonl")
+	args := []string{"whatphone", "watch", "--once", "--state", state, "--alert-file", alerts, "--webhook", receiver.URL, list}
+
+	// the first cycle only records a baseline
+	var stdout, stderr bytes.Buffer
+	if err := run(args, strings.NewReader(""), &stdout, &stderr, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("%v alerted on the baseline:\n%s", args, stdout.String())
+	}
+	expected := "warning: 123: invalid phone number\nChecked 2 of 2 numbers, 0 changed, costing 0.0080\n"
+	if stderr.String() != expected {
+		t.Errorf("%v returned unexpected output.\nExpected: %s\nGot: %s\n", args, expected, stderr.String())
+	}
+	if srv.Requests() != 2 {
+		t.Errorf("Error: Unexpected number of lookups. Got: %d, Want: %d", srv.Requests(), 2)
+	}
+
+	// nothing changed
+	stdout.Reset()
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("%v alerted without a change:\n%s", args, stdout.String())
+	}
+
+	// the carrier was ported, and the line type wasn't returned, which isn't a change
+	srv.SetNumber("+15557654321", whatphone.Data{Carrier: &whatphone.Carrier{ID: "2", Name: "Paine Mobile Inc."}})
+	stdout.Reset()
+	stderr.Reset()
+	if err := run(args, strings.NewReader(""), &stdout, &stderr, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if !strings.HasSuffix(stdout.String(), ` +15557654321 changed: carrier "Growing Wireless Inc." -> "Paine Mobile Inc."`+"\n") || strings.Count(stdout.String(), "\n") != 1 {
+		t.Errorf("%v returned an unexpected alert: %s", args, stdout.String())
+	}
+	if !strings.HasSuffix(stderr.String(), "Checked 2 of 2 numbers, 1 changed, costing 0.0070\n") {
+		t.Errorf("%v returned an unexpected summary: %s", args, stderr.String())
+	}
+
+	want := []watchChange{{Field: "carrier", Old: "Growing Wireless Inc.", New: "Paine Mobile Inc."}}
+	b, err := os.ReadFile(alerts)
+	if err != nil {
+		t.Fatalf("%v didn't write the alert file: %v", args, err)
+	}
+	var a watchAlert
+	if err := json.Unmarshal(b, &a); err != nil || a.Number != "+15557654321" || !reflect.DeepEqual(a.Changes, want) {
+		t.Errorf("%v wrote an unexpected alert (%v): %s", args, err, b)
+	}
+
+	got := deliveries()
+	if len(got) != 1 {
+		t.Fatalf("%v made %d deliveries, Want: 1", args, len(got))
+	}
+	if got[0].header.Get(webhook.EventHeader) != webhook.EventChange || !strings.Contains(string(got[0].body), `"field":"carrier"`) {
+		t.Errorf("%v delivered an unexpected event: %s %s", args, got[0].header.Get(webhook.EventHeader), got[0].body)
+	}
+
+	// the line type is still remembered from before it was missed
+	s, err := loadWatchState(state)
+	if err != nil {
+		t.Fatalf("Error: loadWatchState returned error: %v", err)
+	}
+	if fields := s.Numbers["+15557654321"].Fields; fields["linetype"] != "mobile" || fields["carrier"] != "Paine Mobile Inc." {
+		t.Errorf("Error: Unexpected state: %v", fields)
+	}
+}
+
+func TestWatchRedacted(t *testing.T) {
+	srv := apitest.NewServer(apitest.WithNumber("+15557654321", watchData("Growing Wireless Inc.", "mobile")))
+	defer srv.Close()
+	cr := newConfigReader(testServerConfig(srv))
+
+	list := writeWatchlist(t, "555-765-4321")
+	state := filepath.Join(t.TempDir(), "state.json")
+	args := []string{"whatphone", "--redact", "mask-numbers", "watch", "--once", "--state", state, list}
+	if err := run(args, strings.NewReader(""), io.Discard, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	b, _ := os.ReadFile(state)
+	if strings.Contains(string(b), "5557654321") || !strings.Contains(string(b), "Growing Wireless Inc.") {
+		t.Errorf("%v wrote unexpected state:\n%s", args, b)
+	}
+
+	// the hashed numbers still match up across runs
+	srv.SetNumber("+15557654321", watchData("Paine Mobile Inc.", "mobile"))
+	var stdout bytes.Buffer
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if !strings.HasSuffix(stdout.String(), ` +*******4321 changed: carrier "Growing Wireless Inc." -> "Paine Mobile Inc."`+"\n") {
+		t.Errorf("%v returned an unexpected alert: %s", args, stdout.String())
+	}
+}
+
+func TestWatchBudget(t *testing.T) {
+	srv := apitest.NewServer(
+		apitest.WithNumber("+15557654321", watchData("Growing Wireless Inc.", "mobile")),
+		apitest.WithNumber("+15557654322", watchData("Paine Mobile Inc.", "landline")),
+	)
+	defer srv.Close()
+	cr := newConfigReader(testServerConfig(srv))
+
+	list := writeWatchlist(t, "5557654321", "5557654322")
+	state := filepath.Join(t.TempDir(), "state.json")
+	args := []string{"whatphone", "watch", "--once", "--budget", "0.019", "--state", state, list}
+
+	// each cycle can only afford one number, so the cycles take turns
+	for i, number := range []string{"+15557654321", "+15557654322"} {
+		var stderr bytes.Buffer
+		if err := run(args, strings.NewReader(""), io.Discard, &stderr, cr); err != nil {
+			t.Fatalf("%v returned error: %v", args, err)
+		}
+		expected := "Budget of 0.0190 reached; 1 numbers weren't checked this cycle\nChecked 1 of 2 numbers, 0 changed, costing 0.0040\n"
+		if stderr.String() != expected {
+			t.Errorf("%v returned unexpected output.\nExpected: %s\nGot: %s\n", args, expected, stderr.String())
+		}
+
+		s, err := loadWatchState(state)
+		if err != nil {
+			t.Fatalf("Error: loadWatchState returned error: %v", err)
+		}
+		if len(s.Numbers) != i+1 || s.Numbers[number] == nil {
+			t.Errorf("Error: Cycle %d didn't check %s: %v", i+1, number, s.Numbers)
+		}
+	}
+	if srv.Requests() != 2 {
+		t.Errorf("Error: Unexpected number of lookups. Got: %d, Want: %d", srv.Requests(), 2)
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	srv := apitest.NewServer()
+	defer srv.Close()
+	list := writeWatchlist(t, sampleNumber)
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"whatphone", "watch"}, "missing watchlist file"},
+		{[]string{"whatphone", "watch", "--schedule", "@fortnightly", list}, `invalid schedule "@fortnightly": expected 5 fields, got 1`},
+		{[]string{"whatphone", "watch", "--format", "csv", list}, "invalid format: csv (must be text or json)"},
+		{[]string{"whatphone", "watch", "--budget", "-1", list}, "invalid budget: -1"},
+		{[]string{"whatphone", "watch", "--webhook", "ftp://example.com", list}, "invalid webhook URL: ftp://example.com"},
+	}
+
+	for _, test := range tests {
+		err := run(test.args, strings.NewReader(""), io.Discard, io.Discard, newConfigReader(testServerConfig(srv)))
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%v returned unexpected error.\nExpected: %s\nGot: %v\n", test.args, test.expected, err)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	previous := map[string]string{"carrier": "A", "linetype": "mobile"}
+	tests := []struct {
+		current  map[string]string
+		expected []watchChange
+	}{
+		{map[string]string{"carrier": "A", "linetype": "mobile"}, nil},
+		{map[string]string{"carrier": "B"}, []watchChange{{"carrier", "A", "B"}}},
+		{map[string]string{"carrier": "B", "linetype": "landline"}, []watchChange{{"carrier", "A", "B"}, {"linetype", "mobile", "landline"}}},
+		{map[string]string{"line_provider": "MysticVoice"}, nil},
+	}
+	for _, test := range tests {
+		if got := compare(previous, test.current); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Error: Unexpected changes for %v. Got: %v, Want: %v", test.current, got, test.expected)
+		}
+	}
+}
+
+func TestWatchAlertString(t *testing.T) {
+	a := watchAlert{
+		Number:  "+15557654321",
+		Time:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Changes: []watchChange{{"carrier", "A", "B"}, {"linetype", "mobile", "voip"}},
+	}
+	expected := `2026-01-02T03:04:05Z +15557654321 changed: carrier "A" -> "B", linetype "mobile" -> "voip"`
+	if a.String() != expected {
+		t.Errorf("Error: Unexpected alert. Got: %s, Want: %s", a.String(), expected)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/webhook"
+)
+
+// webhookConfig holds the webhook settings stored in the config file
+type webhookConfig struct {
+	// URL is where lookup results are delivered
+	URL string
+
+	// Secret signs every delivery with HMAC-SHA256
+	Secret string `json:",omitempty"`
+
+	// DeadLetter is the file deliveries that fail for good are appended to. Leave empty to use
+	// webhook-dead-letter.jsonl in the config directory.
+	DeadLetter string `json:",omitempty"`
+}
+
+// webhookSettings returns the webhook settings given by the command line flags, falling back to
+// the config file
+func webhookSettings(c *cli.Context, cfg *config) webhookConfig {
+	var wc webhookConfig
+	if cfg != nil && cfg.Webhook != nil {
+		wc = *cfg.Webhook
+	}
+	if c.IsSet("webhook") {
+		wc.URL = c.String("webhook")
+	}
+	if c.IsSet("webhook-secret") {
+		wc.Secret = c.String("webhook-secret")
+	}
+	if c.IsSet("webhook-dead-letter") {
+		wc.DeadLetter = c.String("webhook-dead-letter")
+	}
+	return wc
+}
+
+// newWebhook returns a sink delivering lookup results to the configured webhook, or nil when no
+// webhook is configured
+func newWebhook(c *cli.Context, cfg *config) (*webhook.Sink, error) {
+	wc := webhookSettings(c, cfg)
+	if wc.URL == "" {
+		return nil, nil
+	}
+	if err := validWebhookURL(wc.URL); err != nil {
+		return nil, err
+	}
+
+	if wc.DeadLetter == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, err
+		}
+		dir := filepath.Join(configDir, "whatphone")
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, err
+		}
+		wc.DeadLetter = filepath.Join(dir, "webhook-dead-letter.jsonl")
+	}
+
+	opts := []webhook.Option{webhook.WithDeadLetter(wc.DeadLetter)}
+	if wc.Secret != "" {
+		opts = append(opts, webhook.WithSecret(wc.Secret))
+	}
+	return webhook.New(wc.URL, opts...), nil
+}
+
+// validWebhookURL checks a webhook URL is an absolute http or https URL
+func validWebhookURL(u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid webhook URL: %s", u)
+	}
+	return nil
+}
+
+// webhookTestResult returns the result delivered by webhook test, which looks like a lookup of the
+// sample number
+func webhookTestResult() *whatphone.Result {
+	name, linetype := "Michael Seaver", "mobile"
+	return &whatphone.Result{
+		Data: whatphone.Data{
+			Name:     &name,
+			Carrier:  &whatphone.Carrier{ID: "214", Name: "Growing Wireless Inc."},
+			Linetype: &linetype,
+		},
+		Missed: []string{},
+		Number: sampleNumber,
+		Note:   "THIS IS A TEST DELIVERY FROM WHATPHONE",
+		Status: true,
+		Type:   "person",
+	}
+}
+
+func cmdWebhookTest(c *cli.Context) error {
+	// the config is optional, since everything can be given on the command line
+	cr := c.App.Metadata["configReader"].(configReader)
+	cfg, _ := cr.reader()
+
+	wc := webhookSettings(c, cfg)
+	if c.NArg() > 0 {
+		wc.URL = c.Args().First()
+	}
+	if wc.URL == "" {
+		return fmt.Errorf("missing webhook URL")
+	}
+	if err := validWebhookURL(wc.URL); err != nil {
+		return err
+	}
+
+	// the test delivery is redacted like real ones, so receivers see what they'll get
+	redaction, err := parseRedaction(c)
+	if err != nil {
+		return err
+	}
+
+	var opts []webhook.Option
+	if wc.Secret != "" {
+		opts = append(opts, webhook.WithSecret(wc.Secret))
+	}
+	if err := webhook.New(wc.URL, opts...).Test(context.Background(), redaction.Apply(webhookTestResult())); err != nil {
+		return fmt.Errorf("webhook test failed: %v", err)
+	}
+
+	fmt.Fprintf(c.App.Writer, "Delivered a %s event to %s\n", webhook.EventTest, wc.URL)
+	if wc.Secret == "" {
+		fmt.Fprintf(c.App.Writer, "Warning: no secret is set, so deliveries aren't signed\n")
+	}
+	return nil
+}
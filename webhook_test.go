@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	whatphone "samhofi.us/x/whatphone/pkg/api"
+	"samhofi.us/x/whatphone/pkg/webhook"
+)
+
+// delivery is a webhook delivery received by a test receiver
+type delivery struct {
+	header http.Header
+	result whatphone.Result
+	body   []byte
+}
+
+// webhookReceiver starts a local webhook receiver answering with status, returning it along with
+// a function returning the deliveries it has received
+func webhookReceiver(status int) (*httptest.Server, func() []delivery) {
+	var mu sync.Mutex
+	var deliveries []delivery
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d := delivery{header: r.Header}
+		d.body, _ = io.ReadAll(r.Body)
+		json.Unmarshal(d.body, &d.result)
+
+		mu.Lock()
+		deliveries = append(deliveries, d)
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	return srv, func() []delivery {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]delivery(nil), deliveries...)
+	}
+}
+
+func TestWebhookTest(t *testing.T) {
+	receiver, deliveries := webhookReceiver(http.StatusNoContent)
+	defer receiver.Close()
+	cr := newConfigReader(testReadConfig)
+
+	var stdout bytes.Buffer
+	args := []string{"whatphone", "webhook", "test", "--webhook-secret", "s3cret", receiver.URL}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if expected := "Delivered a test event to " + receiver.URL + "\n"; stdout.String() != expected {
+		t.Errorf("%v returned unexpected output.\nExpected: %s\nGot: %s\n", args, expected, stdout.String())
+	}
+
+	got := deliveries()
+	if len(got) != 1 {
+		t.Fatalf("%v made %d deliveries, Want: 1", args, len(got))
+	}
+	d := got[0]
+	if d.header.Get(webhook.EventHeader) != webhook.EventTest || d.result.Number != sampleNumber {
+		t.Errorf("%v delivered an unexpected event: %s %s", args, d.header.Get(webhook.EventHeader), d.body)
+	}
+	if err := webhook.Verify([]byte("s3cret"), d.header.Get(webhook.SignatureHeader), d.header.Get(webhook.TimestampHeader), d.body, time.Minute); err != nil {
+		t.Errorf("%v delivered an invalid signature: %v", args, err)
+	}
+
+	stdout.Reset()
+	args = []string{"whatphone", "webhook", "test", receiver.URL}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if !strings.Contains(stdout.String(), "Warning: no secret is set") {
+		t.Errorf("%v didn't warn about the missing secret:\n%s", args, stdout.String())
+	}
+}
+
+func TestWebhookTestErrors(t *testing.T) {
+	receiver, _ := webhookReceiver(http.StatusUnauthorized)
+	defer receiver.Close()
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"whatphone", "webhook", "test", receiver.URL}, "webhook test failed: 401 Unauthorized"},
+		{[]string{"whatphone", "webhook", "test"}, "missing webhook URL"},
+		{[]string{"whatphone", "webhook", "test", "ftp://example.com"}, "invalid webhook URL: ftp://example.com"},
+	}
+
+	for _, test := range tests {
+		err := run(test.args, strings.NewReader(""), io.Discard, io.Discard, newConfigReader(testReadConfig))
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%v returned unexpected error.\nExpected: %s\nGot: %v\n", test.args, test.expected, err)
+		}
+	}
+}
+
+func TestLookupWebhook(t *testing.T) {
+	srv := multiServer()
+	defer srv.Close()
+	receiver, deliveries := webhookReceiver(http.StatusOK)
+	defer receiver.Close()
+
+	// the webhook is taken from the config
+	cr := newConfigReader(func() (*config, error) {
+		cfg, _ := testServerConfig(srv)()
+		cfg.Webhook = &webhookConfig{URL: receiver.URL, Secret: "s3cret"}
+		return cfg, nil
+	})
+
+	args := []string{"whatphone", "lookup", "-n", "15551234567", "555-765-4321", "5557654321"}
+	if err := run(args, strings.NewReader(""), io.Discard, io.Discard, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+
+	got := deliveries()
+	if len(got) != 2 {
+		t.Fatalf("%v made %d deliveries, Want: 2", args, len(got))
+	}
+	for i, number := range []string{"+15551234567", "+15557654321"} {
+		d := got[i]
+		if d.result.Number != number || d.header.Get(webhook.EventHeader) != webhook.EventLookup {
+			t.Errorf("%v delivered an unexpected event: %s %s", args, d.header.Get(webhook.EventHeader), d.body)
+		}
+		if err := webhook.Verify([]byte("s3cret"), d.header.Get(webhook.SignatureHeader), d.header.Get(webhook.TimestampHeader), d.body, time.Minute); err != nil {
+			t.Errorf("%v delivered an invalid signature: %v", args, err)
+		}
+	}
+}
+
+func TestLookupWebhookDeadLetter(t *testing.T) {
+	srv := multiServer()
+	defer srv.Close()
+	receiver, _ := webhookReceiver(http.StatusBadRequest)
+	defer receiver.Close()
+	cr := newConfigReader(testServerConfig(srv))
+
+	dead := filepath.Join(t.TempDir(), "dead.jsonl")
+	var stdout, stderr bytes.Buffer
+	args := []string{"whatphone", "lookup", "-n", "--webhook", receiver.URL, "--webhook-dead-letter", dead, "15551234567"}
+	if err := run(args, strings.NewReader(""), &stdout, &stderr, cr); err != nil {
+		t.Fatalf("%v returned error: %v", args, err)
+	}
+	if !strings.Contains(stdout.String(), "Name: Michael Seaver") {
+		t.Errorf("%v should still print the result:\n%s", args, stdout.String())
+	}
+	if !strings.HasPrefix(stderr.String(), "warning: webhook: delivering lookup event to "+receiver.URL+" failed after 1 attempts: 400 Bad Request") {
+		t.Errorf("%v reported unexpected errors: %s", args, stderr.String())
+	}
+
+	b, err := os.ReadFile(dead)
+	if err != nil {
+		t.Fatalf("%v didn't write a dead letter: %v", args, err)
+	}
+	var dl webhook.DeadLetter
+	if err := json.Unmarshal(b, &dl); err != nil || !strings.Contains(string(dl.Payload), `"number":"+15551234567"`) {
+		t.Errorf("%v wrote an unexpected dead letter (%v): %s", args, err, b)
+	}
+}